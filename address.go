@@ -1,6 +1,7 @@
 package panos
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -57,88 +58,76 @@ type xmlAddressGroup struct {
 	Tag           []string `xml:"tag>member,omitempty"`
 }
 
-// Addresses returns information about all of the address objects. You can (optionally) specify a device-group
-// when ran against a Panorama device. If no device-group is specified, then all objects are returned, including
-// shared objects if run against a Panorama device.
-func (p *PaloAlto) Addresses(devicegroup ...string) (*AddressObjects, error) {
+// Addresses returns information about all of the address objects. You can (optionally) specify a location, such as
+// a device-group when ran against a Panorama device. If no location is specified, then all objects are returned,
+// including shared objects if run against a Panorama device.
+func (p *PaloAlto) Addresses(location ...Location) (*AddressObjects, error) {
+	return p.AddressesContext(context.Background(), location...)
+}
+
+// AddressesContext is the same as Addresses, but uses ctx for all of its API requests.
+func (p *PaloAlto) AddressesContext(ctx context.Context, location ...Location) (*AddressObjects, error) {
 	var addrs AddressObjects
 	xpath := "/config//address"
 
-	if p.DeviceType == "panos" {
-		if p.Panorama == true {
-			xpath = "/config//address"
-		}
-
-		if p.Panorama == false {
-			xpath = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address"
-		}
-
-		if len(devicegroup) > 0 && len(devicegroup[0]) > 0 {
-			return nil, errors.New("you must be connected to a Panorama device when specifying a device-group")
-		}
+	if p.DeviceType == "panos" && p.Panorama == false {
+		xpath = p.vsysXpath() + "/address"
 	}
 
-	if p.DeviceType == "panorama" {
-		if len(devicegroup) > 0 && len(devicegroup[0]) > 0 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address", devicegroup[0])
+	if len(location) > 0 {
+		base, err := p.locationXpath(location...)
+		if err != nil {
+			return nil, err
 		}
-	}
 
-	_, addrData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+		xpath = base + "/address"
 	}
 
-	if err := xml.Unmarshal([]byte(addrData), &addrs); err != nil {
+	addrData, err := p.getConfig(ctx, xpath)
+	if err != nil {
 		return nil, err
 	}
 
-	if addrs.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", addrs.Code, errorCodes[addrs.Code])
+	if err := xml.Unmarshal([]byte(addrData), &addrs); err != nil {
+		return nil, err
 	}
 
 	return &addrs, nil
 }
 
-// AddressGroups returns information about all of the address groups. You can (optionally) specify a device-group
-// when ran against a Panorama device. If no device-group is specified, then all address groups are returned, including
-// shared objects if run against a Panorama device.
-func (p *PaloAlto) AddressGroups(devicegroup ...string) (*AddressGroups, error) {
+// AddressGroups returns information about all of the address groups. You can (optionally) specify a location, such
+// as a device-group when ran against a Panorama device. If no location is specified, then all address groups are
+// returned, including shared objects if run against a Panorama device.
+func (p *PaloAlto) AddressGroups(location ...Location) (*AddressGroups, error) {
+	return p.AddressGroupsContext(context.Background(), location...)
+}
+
+// AddressGroupsContext is the same as AddressGroups, but uses ctx for all of its API requests.
+func (p *PaloAlto) AddressGroupsContext(ctx context.Context, location ...Location) (*AddressGroups, error) {
 	var parsedGroups xmlAddressGroups
 	var groups AddressGroups
 	xpath := "/config//address-group"
 
-	if p.DeviceType == "panos" {
-		if p.Panorama == true {
-			xpath = "/config//address-group"
-		}
-
-		if p.Panorama == false {
-			xpath = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group"
-		}
-
-		if len(devicegroup) > 0 && len(devicegroup[0]) > 0 {
-			return nil, errors.New("you must be connected to a Panorama device when specifying a device-group")
-		}
+	if p.DeviceType == "panos" && p.Panorama == false {
+		xpath = p.vsysXpath() + "/address-group"
 	}
 
-	if p.DeviceType == "panorama" {
-		if len(devicegroup) > 0 && len(devicegroup[0]) > 0 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group", devicegroup[0])
+	if len(location) > 0 {
+		base, err := p.locationXpath(location...)
+		if err != nil {
+			return nil, err
 		}
-	}
 
-	_, groupData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+		xpath = base + "/address-group"
 	}
 
-	if err := xml.Unmarshal([]byte(groupData), &parsedGroups); err != nil {
+	groupData, err := p.getConfig(ctx, xpath)
+	if err != nil {
 		return nil, err
 	}
 
-	if parsedGroups.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", parsedGroups.Code, errorCodes[parsedGroups.Code])
+	if err := xml.Unmarshal([]byte(groupData), &parsedGroups); err != nil {
+		return nil, err
 	}
 
 	for _, g := range parsedGroups.Groups {
@@ -160,60 +149,39 @@ func (p *PaloAlto) AddressGroups(devicegroup ...string) (*AddressGroups, error)
 }
 
 // CreateAddress will add a new address object to the device. Addrtype should be one of ip, range, or fqdn. If creating an address
-// object on a Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) CreateAddress(name, addrtype, address, description string, devicegroup ...string) error {
+// object on a Panorama device, specify its location (e.g. DeviceGroupLocation("name") or SharedLocation()) as the last parameter.
+func (p *PaloAlto) CreateAddress(name, addrtype, address, description string, location ...Location) error {
+	return p.CreateAddressContext(context.Background(), name, addrtype, address, description, location...)
+}
+
+// CreateAddressContext is the same as CreateAddress, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateAddressContext(ctx context.Context, name, addrtype, address, description string, location ...Location) error {
 	var xmlBody string
-	var xpath string
-	var reqError requestError
 
 	switch addrtype {
 	case "ip":
-		xmlBody = fmt.Sprintf("<ip-netmask>%s</ip-netmask>", strings.TrimSpace(address))
+		xmlBody = fmt.Sprintf("<ip-netmask>%s</ip-netmask>", xmlEscape(strings.TrimSpace(address)))
 	case "range":
-		xmlBody = fmt.Sprintf("<ip-range>%s</ip-range>", strings.TrimSpace(address))
+		xmlBody = fmt.Sprintf("<ip-range>%s</ip-range>", xmlEscape(strings.TrimSpace(address)))
 	case "fqdn":
-		xmlBody = fmt.Sprintf("<fqdn>%s</fqdn>", strings.TrimSpace(address))
+		xmlBody = fmt.Sprintf("<fqdn>%s</fqdn>", xmlEscape(strings.TrimSpace(address)))
 	}
 
 	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
+		xmlBody += fmt.Sprintf("<description>%s</description>", xmlEscape(description))
 	}
 
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" {
-		if p.Shared == true {
-			xpath = fmt.Sprintf("/config/shared/address/entry[@name='%s']", name)
-		}
-
-		if len(devicegroup) > 0 && devicegroup[0] == "shared" {
-			xpath = fmt.Sprintf("/config/shared/address/entry[@name='%s']", name)
-		}
-
-		if p.Shared == false && len(devicegroup) > 0 && devicegroup[0] != "shared" {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address/entry[@name='%s']", devicegroup[0], name)
-		}
-
-		if p.Shared == false && len(devicegroup) <= 0 {
-			return errors.New("you must specify a device-group when creating address objects on a Panorama device")
-		}
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("%s/address/entry[@name=%s]", base, xpathQuote(name))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -228,11 +196,14 @@ func (p *PaloAlto) CreateAddress(name, addrtype, address, description string, de
 // match := "'web-servers' and 'dmz-servers'"
 //
 // If you do not want to include a description, just leave the parameter blank using double-quotes (""). If creating an address group on
-// a Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) CreateAddressGroup(name, grouptype string, members interface{}, description string, devicegroup ...string) error {
+// a Panorama device, specify its location (e.g. DeviceGroupLocation("name") or SharedLocation()) as the last parameter.
+func (p *PaloAlto) CreateAddressGroup(name, grouptype string, members interface{}, description string, location ...Location) error {
+	return p.CreateAddressGroupContext(context.Background(), name, grouptype, members, description, location...)
+}
+
+// CreateAddressGroupContext is the same as CreateAddressGroup, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateAddressGroupContext(ctx context.Context, name, grouptype string, members interface{}, description string, location ...Location) error {
 	var xmlBody string
-	var xpath string
-	var reqError requestError
 
 	switch grouptype {
 	case "static":
@@ -243,128 +214,72 @@ func (p *PaloAlto) CreateAddressGroup(name, grouptype string, members interface{
 
 		xmlBody = "<static>"
 		for _, member := range staticMembers {
-			xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(member))
+			xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(member)))
 		}
 		xmlBody += "</static>"
 	case "dynamic":
 		criteria := members.(string)
-		xmlBody = fmt.Sprintf("<dynamic><filter>%s</filter></dynamic>", criteria)
+		xmlBody = fmt.Sprintf("<dynamic><filter>%s</filter></dynamic>", xmlEscape(criteria))
 	}
 
 	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
+		xmlBody += fmt.Sprintf("<description>%s</description>", xmlEscape(description))
 	}
 
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" {
-		if p.Shared == true {
-			xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']", name)
-		}
-
-		if len(devicegroup) > 0 && devicegroup[0] == "shared" {
-			xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']", name)
-		}
-
-		if p.Shared == false && len(devicegroup) > 0 && devicegroup[0] != "shared" {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']", devicegroup[0], name)
-		}
-
-		if p.Shared == false && len(devicegroup) <= 0 {
-			return errors.New("you must specify a device-group when creating address groups on a Panorama device")
-		}
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("%s/address-group/entry[@name=%s]", base, xpathQuote(name))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // DeleteAddress will remove an address object from the device. If deleting an address object on a
-// Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) DeleteAddress(name string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == true {
-		xpath = fmt.Sprintf("/config/shared/address/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address/entry[@name='%s']", devicegroup[0], name)
-	}
+// Panorama device, specify its location (e.g. DeviceGroupLocation("name") or SharedLocation()) as the last parameter.
+func (p *PaloAlto) DeleteAddress(name string, location ...Location) error {
+	return p.DeleteAddressContext(context.Background(), name, location...)
+}
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when deleting address objects on a Panorama device")
+// DeleteAddressContext is the same as DeleteAddress, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteAddressContext(ctx context.Context, name string, location ...Location) error {
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("%s/address/entry[@name=%s]", base, xpathQuote(name))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // DeleteAddressGroup will remove an address group from the device. If deleting an address group on a
-// Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) DeleteAddressGroup(name string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == true {
-		xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']", devicegroup[0], name)
-	}
+// Panorama device, specify its location (e.g. DeviceGroupLocation("name") or SharedLocation()) as the last parameter.
+func (p *PaloAlto) DeleteAddressGroup(name string, location ...Location) error {
+	return p.DeleteAddressGroupContext(context.Background(), name, location...)
+}
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when deleting address groups on a Panorama device")
+// DeleteAddressGroupContext is the same as DeleteAddressGroup, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteAddressGroupContext(ctx context.Context, name string, location ...Location) error {
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("%s/address-group/entry[@name=%s]", base, xpathQuote(name))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
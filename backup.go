@@ -0,0 +1,279 @@
+package panos
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// backupPrefix and backupSuffix surround the timestamp in the name of each backup file.
+	backupPrefix = "running-config-"
+	backupSuffix = ".xml"
+
+	// backupTimeFormat is the timestamp in the name of each backup file. Names sort in the order they were taken, and
+	// the microseconds keep backups taken within the same second apart.
+	backupTimeFormat = "20060102-150405.000000"
+)
+
+// SavedConfigs contains every configuration snapshot saved on the device.
+type SavedConfigs struct {
+	XMLName xml.Name      `xml:"response"`
+	Status  string        `xml:"status,attr"`
+	Code    string        `xml:"code,attr"`
+	Configs []SavedConfig `xml:"completions>completion"`
+}
+
+// SavedConfig contains information about each individual configuration snapshot.
+type SavedConfig struct {
+	Name        string `xml:"value,attr"`
+	Description string `xml:"help-string,attr"`
+}
+
+// RevertConfig throws away every change in the candidate configuration, so it matches the running configuration
+// again. To only throw away the changes made by some administrators, such as the one your automation logs in as,
+// specify their names as parameters (e.g. RevertConfig("automation")).
+func (p *PaloAlto) RevertConfig(admins ...string) error {
+	return p.RevertConfigContext(context.Background(), admins...)
+}
+
+// RevertConfigContext is the same as RevertConfig, but uses ctx for all of its API requests.
+func (p *PaloAlto) RevertConfigContext(ctx context.Context, admins ...string) error {
+	cmd := "<revert><config></config></revert>"
+
+	if len(admins) > 0 {
+		cmd = fmt.Sprintf("<revert><config><partial>%s</partial></config></revert>", members("admin", admins))
+	}
+
+	if _, err := p.op(ctx, cmd); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveConfig saves a snapshot of the candidate configuration on the device, with the given name. If a snapshot
+// with that name already exists, it is replaced. Use LoadConfig to go back to it.
+func (p *PaloAlto) SaveConfig(name string) error {
+	return p.SaveConfigContext(context.Background(), name)
+}
+
+// SaveConfigContext is the same as SaveConfig, but uses ctx for all of its API requests.
+func (p *PaloAlto) SaveConfigContext(ctx context.Context, name string) error {
+	if name == "" {
+		return errors.New("you must specify a name for the saved configuration")
+	}
+
+	if _, err := p.op(ctx, fmt.Sprintf("<save><config><to>%s</to></config></save>", xmlEscape(name))); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LoadConfig replaces the candidate configuration with the snapshot of the given name, which was saved with
+// SaveConfig. The running configuration is not changed until you commit.
+func (p *PaloAlto) LoadConfig(name string) error {
+	return p.LoadConfigContext(context.Background(), name)
+}
+
+// LoadConfigContext is the same as LoadConfig, but uses ctx for all of its API requests.
+func (p *PaloAlto) LoadConfigContext(ctx context.Context, name string) error {
+	if name == "" {
+		return errors.New("you must specify the name of the saved configuration")
+	}
+
+	if _, err := p.op(ctx, fmt.Sprintf("<load><config><from>%s</from></config></load>", xmlEscape(name))); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListSavedConfigs returns every configuration snapshot saved on the device, which can be loaded with LoadConfig.
+func (p *PaloAlto) ListSavedConfigs() (*SavedConfigs, error) {
+	return p.ListSavedConfigsContext(context.Background())
+}
+
+// ListSavedConfigsContext is the same as ListSavedConfigs, but uses ctx for all of its API requests.
+func (p *PaloAlto) ListSavedConfigsContext(ctx context.Context) (*SavedConfigs, error) {
+	var configs SavedConfigs
+
+	// The device lists the saved configurations as the completions for the "from" parameter of "load config".
+	resp, err := p.request(ctx, url.Values{"type": {"op"}, "action": {"complete"}, "xpath": {"/operations/load/config/from"}})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := xml.Unmarshal([]byte(resp), &configs); err != nil {
+		return nil, err
+	}
+
+	return &configs, nil
+}
+
+// Backup exports the running configuration of the device to a timestamped file (e.g.
+// running-config-20260102-150405.123456.xml), in a directory under dir named after the device's serial number. When run
+// against a Panorama device, every connected firewall that it manages is backed up as well, to its own directory.
+//
+// Only the newest keep backups are kept in each directory, and older ones are removed. If keep is 0, every backup is
+// kept. Backup returns the path of every file that it wrote. If some devices could not be backed up, the rest still
+// are, and the error lists the ones that failed.
+func (p *PaloAlto) Backup(dir string, keep int) ([]string, error) {
+	return p.BackupContext(context.Background(), dir, keep)
+}
+
+// BackupContext is the same as Backup, but uses ctx for all of its API requests.
+func (p *PaloAlto) BackupContext(ctx context.Context, dir string, keep int) ([]string, error) {
+	var files, failed []string
+	var mu sync.Mutex
+
+	// Each backup directory is named after a serial number, which a lazy session may not have asked for yet.
+	info, err := p.discovered(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	serials := []string{info.Serial}
+
+	if p.DeviceType == "panorama" {
+		devices, err := p.DevicesContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range devices.Devices {
+			if d.Connected == "yes" {
+				serials = append(serials, d.Serial)
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	bulk := p.NewBulk(0)
+
+	for i, serial := range serials {
+		var target string
+		if i > 0 {
+			target = serial
+		}
+
+		serial := serial
+		bulk.Add(func(ctx context.Context) error {
+			file, err := p.backup(ctx, filepath.Join(dir, serial), target, now, keep)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %s", serial, err))
+				return nil
+			}
+
+			files = append(files, file)
+
+			return nil
+		})
+	}
+
+	if err := bulk.Run(ctx); err != nil {
+		return files, err
+	}
+
+	sort.Strings(files)
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return files, fmt.Errorf("unable to back up %d device(s) - %s", len(failed), strings.Join(failed, "; "))
+	}
+
+	return files, nil
+}
+
+// backup exports the running configuration of the device, or of the firewall with the given target serial number
+// through Panorama, to a file in dir named after the given time. It then removes all but the newest keep backups.
+func (p *PaloAlto) backup(ctx context.Context, dir, target string, now time.Time, keep int) (string, error) {
+	var config bytes.Buffer
+
+	params := url.Values{"type": {"export"}, "category": {"configuration"}}
+	if target != "" {
+		params.Set("target", target)
+	}
+
+	if err := p.export(ctx, params, &config); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	file, err := writeBackup(dir, now, config.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	if keep <= 0 {
+		return file, nil
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return file, err
+	}
+
+	var backups []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), backupPrefix) && strings.HasSuffix(e.Name(), backupSuffix) {
+			backups = append(backups, e.Name())
+		}
+	}
+
+	sort.Strings(backups)
+
+	for len(backups) > keep {
+		if err := os.Remove(filepath.Join(dir, backups[0])); err != nil {
+			return file, err
+		}
+
+		backups = backups[1:]
+	}
+
+	return file, nil
+}
+
+// writeBackup writes data to a new backup file in dir, named after the given time, and returns its path. An existing
+// backup is never overwritten: if one already has that name, the time is moved on by a microsecond until a name is
+// free, so the names still sort in the order the backups were taken.
+func writeBackup(dir string, now time.Time, data []byte) (string, error) {
+	for {
+		file := filepath.Join(dir, backupPrefix+now.Format(backupTimeFormat)+backupSuffix)
+
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			now = now.Add(time.Microsecond)
+			continue
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return "", err
+		}
+
+		return file, f.Close()
+	}
+}
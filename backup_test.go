@@ -0,0 +1,209 @@
+package panos
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottdware/go-panos/panostest"
+)
+
+func TestSaveLoadAndRevertConfig(t *testing.T) {
+	pan, srv := newTestSession(t)
+	defer srv.Close()
+
+	has := func(name string) bool {
+		return strings.Contains(srv.Candidate(), `<entry name="`+name+`">`)
+	}
+
+	if err := pan.CreateAddress("web-1", "ip", "10.1.1.1/32", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pan.SaveConfig("before-cleanup"); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := pan.ListSavedConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, c := range saved.Configs {
+		found = found || c.Name == "before-cleanup"
+	}
+
+	if !found {
+		t.Fatalf("before-cleanup is not one of the saved configurations: %+v", saved.Configs)
+	}
+
+	if err := pan.DeleteAddress("web-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pan.LoadConfig("before-cleanup"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !has("web-1") {
+		t.Fatal("loading the saved configuration did not restore web-1")
+	}
+
+	if _, err := pan.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pan.CreateAddress("web-2", "ip", "10.1.1.2/32", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pan.RevertConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	if has("web-2") || !has("web-1") {
+		t.Fatalf("the candidate configuration does not match the running configuration after reverting: %s", srv.Candidate())
+	}
+
+	if err := pan.CreateAddress("web-3", "ip", "10.1.1.3/32", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pan.RevertConfig("jdoe"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !has("web-3") {
+		t.Fatal("reverting the changes of another administrator threw away web-3")
+	}
+
+	if err := pan.RevertConfig("jdoe", "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	if has("web-3") || !has("web-1") {
+		t.Fatalf("reverting the changes of admin did not throw away web-3: %s", srv.Candidate())
+	}
+}
+
+func TestSavedConfigErrors(t *testing.T) {
+	pan, srv := newTestSession(t)
+	defer srv.Close()
+
+	if err := pan.SaveConfig(""); err == nil {
+		t.Error("saved a configuration without a name")
+	}
+
+	if err := pan.LoadConfig(""); err == nil {
+		t.Error("loaded a configuration without a name")
+	}
+
+	if err := pan.LoadConfig("missing"); err == nil {
+		t.Error("loaded a configuration that was never saved")
+	}
+}
+
+func TestBackupRetention(t *testing.T) {
+	pan, srv := newTestSession(t)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "panos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const keep = 3
+
+	// The backups are taken as quickly as possible, so several of them land within the same second.
+	var written []string
+	for i := 0; i < keep+2; i++ {
+		files, err := pan.Backup(dir, keep)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(files) != 1 {
+			t.Fatalf("got files %q, want one", files)
+		}
+
+		written = append(written, files[0])
+	}
+
+	entries, err := ioutil.ReadDir(filepath.Join(dir, "000000000001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kept []string
+	for _, e := range entries {
+		kept = append(kept, filepath.Join(dir, "000000000001", e.Name()))
+	}
+
+	if !reflect.DeepEqual(kept, written[len(written)-keep:]) {
+		t.Fatalf("got backups %q, want the newest %d of %q", kept, keep, written)
+	}
+}
+
+func TestWriteBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "panos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	var files []string
+	for _, config := range []string{"first", "second", "third"} {
+		file, err := writeBackup(dir, now, []byte(config))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files = append(files, filepath.Base(file))
+	}
+
+	want := []string{
+		"running-config-20260102-150405.000000.xml",
+		"running-config-20260102-150405.000001.xml",
+		"running-config-20260102-150405.000002.xml",
+	}
+
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("got %q, want %q", files, want)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, want[0]))
+	if err != nil || string(data) != "first" {
+		t.Fatalf("the first backup was overwritten: %q, %v", data, err)
+	}
+}
+
+func TestBackupPanorama(t *testing.T) {
+	firewall := panostest.NewServer(panostest.WithSystemInfo("serial", "000000000002"))
+	defer firewall.Close()
+
+	pan, srv := newTestSession(t, panostest.WithPanorama(), panostest.WithManagedDevice(firewall))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "panos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := pan.Backup(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 || filepath.Base(filepath.Dir(files[0])) != "000000000001" || filepath.Base(filepath.Dir(files[1])) != "000000000002" {
+		t.Fatalf("got files %q, want one for Panorama and one for its firewall", files)
+	}
+}
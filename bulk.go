@@ -0,0 +1,101 @@
+package panos
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultParallelism is the number of operations a Bulk runs at once, if the session was not given WithParallelism.
+const defaultParallelism = 4
+
+// Bulk runs a batch of operations concurrently, with no more than a set number of them running at once. Create one
+// with NewBulk, add each operation with Add, and then call Run. For example:
+//
+//	bulk := pan.NewBulk(0)
+//
+//	for _, name := range names {
+//		name := name
+//		bulk.Add(func(ctx context.Context) error {
+//			return pan.DeleteAddressContext(ctx, name, panos.DeviceGroupLocation("Branch-Offices"))
+//		})
+//	}
+//
+//	err := bulk.Run(context.Background())
+//
+// A Bulk itself is not safe to use from multiple goroutines, but the operations it runs are.
+type Bulk struct {
+	parallelism int
+	ops         []func(ctx context.Context) error
+}
+
+// NewBulk returns an empty Bulk that runs up to parallelism operations at once. If parallelism is zero, the
+// session's setting from WithParallelism is used.
+func (p *PaloAlto) NewBulk(parallelism int) *Bulk {
+	if parallelism <= 0 {
+		parallelism = p.parallelism
+	}
+
+	if parallelism <= 0 {
+		parallelism = defaultParallelism
+	}
+
+	return &Bulk{parallelism: parallelism}
+}
+
+// Add adds an operation to the batch. The operation should use the ctx it is given for its API requests, so that
+// it stops early when another operation has failed.
+func (b *Bulk) Add(op func(ctx context.Context) error) {
+	b.ops = append(b.ops, op)
+}
+
+// Len returns the number of operations in the batch.
+func (b *Bulk) Len() int {
+	return len(b.ops)
+}
+
+// Run runs every operation in the batch, and waits for them to finish. If an operation fails, no more operations
+// are started, the context given to the ones still running is canceled, and the first error is returned.
+func (b *Bulk) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	var once sync.Once
+	var runErr error
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, b.parallelism)
+
+	for _, op := range b.ops {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(op func(ctx context.Context) error) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := op(ctx); err != nil {
+				once.Do(func() {
+					runErr = err
+					cancel()
+				})
+			}
+		}(op)
+	}
+
+	wg.Wait()
+
+	if runErr != nil {
+		return runErr
+	}
+
+	return ctx.Err()
+}
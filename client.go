@@ -0,0 +1,424 @@
+package panos
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// keyHeaderVersion is the first PAN-OS release that accepts the API key in the X-PAN-KEY header.
+var keyHeaderVersion = Version{Major: 9}
+
+// Option is used to configure a session when calling NewSession. Options are applied in the order given.
+type Option func(*sessionOptions)
+
+// sessionOptions holds the settings gathered from each Option before the session's HTTP client is built.
+type sessionOptions struct {
+	client       *http.Client
+	timeout      time.Duration
+	rootCAs      *x509.CertPool
+	certificates []tls.Certificate
+	fingerprint  string
+	insecure     bool
+	parallelism  int
+	retry        RetryPolicy
+	rateLimit    float64
+	burst        int
+}
+
+// WithHTTPClient sets the *http.Client that the session uses for every API call. Use this if you need to route
+// requests through a proxy, use a custom transport, or point the session at a test server. The client itself
+// is never modified; if any TLS options are also given, they are applied to a copy of its transport.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *sessionOptions) {
+		o.client = client
+	}
+}
+
+// WithTimeout sets the overall time limit for each API call made by the session. A timeout of zero means
+// no timeout. The deadline of any context passed to a ...Context method still applies.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *sessionOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithRootCAs sets the pool of certificate authorities used to verify the device's certificate. If this is not
+// given, the host's root CA set is used.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(o *sessionOptions) {
+		o.rootCAs = pool
+	}
+}
+
+// WithClientCertificate adds a certificate that is presented to the device when it asks for client
+// certificate authentication.
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(o *sessionOptions) {
+		o.certificates = append(o.certificates, cert)
+	}
+}
+
+// WithPinnedCertificate pins the device's certificate to the given SHA-256 fingerprint, as a hex string (colons
+// are allowed, e.g. "AB:CD:..."). The connection fails unless the device's leaf certificate matches. If WithRootCAs
+// is not also given, the certificate chain is not verified, which lets you trust a self-signed certificate by its
+// fingerprint alone.
+func WithPinnedCertificate(fingerprint string) Option {
+	return func(o *sessionOptions) {
+		o.fingerprint = fingerprint
+	}
+}
+
+// WithInsecureSkipVerify turns off all verification of the device's certificate. This should only be used for
+// testing, or with devices that still use their default self-signed certificate.
+func WithInsecureSkipVerify() Option {
+	return func(o *sessionOptions) {
+		o.insecure = true
+	}
+}
+
+// WithParallelism sets how many API calls the session makes at once when running a Bulk, or any function that works
+// on many objects at a time, such as CreateObjectsFromCsv. The default is 4.
+func WithParallelism(n int) Option {
+	return func(o *sessionOptions) {
+		o.parallelism = n
+	}
+}
+
+// WithRetry sets how the session retries API calls that fail with a transient error. See RetryPolicy for which
+// calls are retried. By default, calls are not retried.
+func WithRetry(policy RetryPolicy) Option {
+	return func(o *sessionOptions) {
+		o.retry = policy
+	}
+}
+
+// WithRateLimit limits the session to perSecond API calls a second on average, with bursts of up to burst calls at
+// once. Calls over the limit wait their turn. The limit is shared by every copy of the session made with WithVsys.
+// By default, there is no limit.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(o *sessionOptions) {
+		o.rateLimit = perSecond
+		o.burst = burst
+	}
+}
+
+// tlsConfig returns the TLS settings for the session, or nil if no TLS options were given.
+func (o *sessionOptions) tlsConfig() (*tls.Config, error) {
+	if o.rootCAs == nil && len(o.certificates) == 0 && o.fingerprint == "" && !o.insecure {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		RootCAs:            o.rootCAs,
+		Certificates:       o.certificates,
+		InsecureSkipVerify: o.insecure,
+	}
+
+	if o.fingerprint != "" {
+		pin, err := hex.DecodeString(strings.Replace(o.fingerprint, ":", "", -1))
+		if err != nil || len(pin) != sha256.Size {
+			return nil, fmt.Errorf("invalid SHA-256 certificate fingerprint %q", o.fingerprint)
+		}
+
+		if o.rootCAs == nil {
+			config.InsecureSkipVerify = true
+		}
+
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("device did not present a certificate")
+			}
+
+			sum := sha256.Sum256(rawCerts[0])
+			if !bytes.Equal(sum[:], pin) {
+				return fmt.Errorf("certificate fingerprint %X does not match the pinned fingerprint", sum)
+			}
+
+			return nil
+		}
+	}
+
+	return config, nil
+}
+
+// httpClient builds the *http.Client for a session from the given options. A new transport is created whenever
+// TLS settings are needed, so one session's settings never leak into another session, or into http.DefaultClient.
+func (o *sessionOptions) httpClient() (*http.Client, error) {
+	config, err := o.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{}
+	if o.client != nil {
+		*client = *o.client
+	}
+
+	if config != nil || client.Transport == nil {
+		transport, ok := client.Transport.(*http.Transport)
+		if client.Transport != nil && !ok {
+			return nil, errors.New("TLS options can only be used with an HTTP client whose transport is an *http.Transport")
+		}
+
+		if transport == nil {
+			transport = http.DefaultTransport.(*http.Transport)
+		}
+
+		transport = transport.Clone()
+		if config != nil {
+			transport.TLSClientConfig = config
+		}
+
+		client.Transport = transport
+	}
+
+	if o.timeout > 0 {
+		client.Timeout = o.timeout
+	}
+
+	return client, nil
+}
+
+// keyHeaderSupported reports whether a device running the given PAN-OS version accepts the API key in
+// the X-PAN-KEY header.
+func keyHeaderSupported(version Version) bool {
+	return !version.IsZero() && version.Compare(keyHeaderVersion) >= 0
+}
+
+// request sends the given parameters to the device's API, and returns the body of the response. Every value is
+// URL-encoded, so parameters can hold any characters. The request is canceled when ctx is done.
+//
+// If the device responds with an error status, an *APIError is returned.
+//
+// Each attempt first waits for the session's rate limit, if one was set with WithRateLimit, and failed attempts
+// are retried according to the session's RetryPolicy.
+func (p *PaloAlto) request(ctx context.Context, params url.Values) (string, error) {
+	for attempt := 1; ; attempt++ {
+		if p.limiter != nil {
+			if err := p.limiter.wait(ctx); err != nil {
+				return "", err
+			}
+		}
+
+		body, err := p.send(ctx, params)
+		if err == nil || attempt >= p.retry.MaxAttempts || ctx.Err() != nil || !retryable(params, err) {
+			return body, err
+		}
+
+		if err := sleep(ctx, p.retry.backoff(attempt)); err != nil {
+			return "", err
+		}
+	}
+}
+
+// send makes a single attempt at the given request.
+//
+// Requests are always sent as a POST, with the parameters in the form body. The API key is never put in the URL.
+// It is sent in the X-PAN-KEY header when the device supports it. Otherwise, such as for older PAN-OS versions or
+// before the version is known, it is sent in the form body along with the other parameters.
+func (p *PaloAlto) send(ctx context.Context, params url.Values) (string, error) {
+	form := p.form(params)
+
+	if p.Key != "" && !p.keyHeader {
+		form.Set("key", p.Key)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.URI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return p.do(ctx, req, params)
+}
+
+// upload sends the given parameters to the device's API along with a file, as a multipart form, the way that
+// import requests need. The file is streamed from r as it is sent. Uploads are never retried, since r can only be
+// read once, but they do wait for the session's rate limit.
+func (p *PaloAlto) upload(ctx context.Context, params url.Values, filename string, r io.Reader) (string, error) {
+	if p.limiter != nil {
+		if err := p.limiter.wait(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+
+	go func() {
+		pw.CloseWithError(writeMultipart(form, p.form(params), p.keyField(), filename, r))
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, p.URI, pr)
+	if err != nil {
+		pr.Close()
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	return p.do(ctx, req, params)
+}
+
+// form returns a copy of the given parameters to send to the device. For a session returned by Target, it includes
+// the target parameter, which tells Panorama to forward the request to that firewall.
+func (p *PaloAlto) form(params url.Values) url.Values {
+	form := url.Values{}
+	for k, v := range params {
+		form[k] = v
+	}
+
+	if p.target != "" {
+		form.Set("target", p.target)
+	}
+
+	return form
+}
+
+// keyField returns the API key, if it has to be sent along with the other parameters rather than in a header.
+func (p *PaloAlto) keyField() string {
+	if p.keyHeader {
+		return ""
+	}
+
+	return p.Key
+}
+
+// writeMultipart writes each parameter, the API key (if given) and the file to form, then closes it.
+func writeMultipart(form *multipart.Writer, params url.Values, key, filename string, r io.Reader) error {
+	for k, values := range params {
+		for _, v := range values {
+			if err := form.WriteField(k, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	if key != "" {
+		if err := form.WriteField("key", key); err != nil {
+			return err
+		}
+	}
+
+	file, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		return err
+	}
+
+	return form.Close()
+}
+
+// do sends a request that has been built by send or upload, and returns the body of the response. The API key is
+// put in the X-PAN-KEY header, if the device supports it.
+func (p *PaloAlto) do(ctx context.Context, req *http.Request, params url.Values) (string, error) {
+	if p.Key != "" && p.keyHeader {
+		req.Header.Set("X-PAN-KEY", p.Key)
+	}
+
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to read response from %s - %w", p.Host, err)
+	}
+
+	if err := checkResponse(data, params.Get("xpath"), params.Get("cmd")); err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &statusError{host: p.Host, status: resp.Status, statusCode: resp.StatusCode}
+	}
+
+	return string(data), nil
+}
+
+// op runs the given XML-formatted operational command, and returns the response.
+func (p *PaloAlto) op(ctx context.Context, cmd string) (string, error) {
+	return p.request(ctx, url.Values{"type": {"op"}, "cmd": {cmd}})
+}
+
+// config runs a configuration action (get, set, delete, etc.) against the given xpath. Any parameters that
+// the action needs, such as element or newname, are given in params, which may be nil.
+func (p *PaloAlto) config(ctx context.Context, action, xpath string, params url.Values) (string, error) {
+	query := url.Values{"type": {"config"}, "action": {action}, "xpath": {xpath}}
+	for k, v := range params {
+		query[k] = v
+	}
+
+	return p.request(ctx, query)
+}
+
+// getConfig returns the candidate configuration at the given xpath.
+func (p *PaloAlto) getConfig(ctx context.Context, xpath string) (string, error) {
+	return p.config(ctx, "get", xpath, nil)
+}
+
+// setConfig adds or merges the given XML element into the configuration at xpath.
+func (p *PaloAlto) setConfig(ctx context.Context, xpath, element string) error {
+	_, err := p.config(ctx, "set", xpath, url.Values{"element": {element}})
+	return err
+}
+
+// deleteConfig removes the configuration at the given xpath.
+func (p *PaloAlto) deleteConfig(ctx context.Context, xpath string) error {
+	_, err := p.config(ctx, "delete", xpath, nil)
+	return err
+}
+
+// renameConfig renames the object at the given xpath to newname.
+func (p *PaloAlto) renameConfig(ctx context.Context, xpath, newname string) error {
+	_, err := p.config(ctx, "rename", xpath, url.Values{"newname": {newname}})
+	return err
+}
+
+// xmlEscape returns s with the characters that have a special meaning in XML (&, <, >, ' and ") escaped, so
+// it can be used as the text of an element or the value of an attribute.
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+
+	return buf.String()
+}
+
+// xpathQuote returns s as a quoted xpath string literal, for use in a predicate such as [@name=...]. Values that
+// contain both kinds of quote are built with concat(), since xpath 1.0 has no escape sequences.
+func xpathQuote(s string) string {
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+
+	parts := strings.Split(s, "'")
+	for i, part := range parts {
+		parts[i] = "'" + part + "'"
+	}
+
+	return "concat(" + strings.Join(parts, `, "'", `) + ")"
+}
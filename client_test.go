@@ -0,0 +1,482 @@
+package panos
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDevice is a TLS server that answers the requests that NewSession makes (keygen, show system info and show
+// panorama-status) like a firewall, and passes every other request to its handler, which can be nil. It records the
+// parameters of every request it gets after the session is created.
+type fakeDevice struct {
+	*httptest.Server
+
+	// info holds the fields of the device's show system info output, such as "sw-version".
+	info map[string]string
+
+	handler http.HandlerFunc
+
+	mu       sync.Mutex
+	requests []url.Values
+}
+
+// newFakeDevice starts a fakeDevice that passes requests to handler.
+func newFakeDevice(handler http.HandlerFunc) *fakeDevice {
+	d := &fakeDevice{
+		info: map[string]string{
+			"model":           "PA-VM",
+			"platform-family": "vm",
+			"serial":          "000000000001",
+			"sw-version":      "10.1.0",
+			"multi-vsys":      "off",
+		},
+		handler: handler,
+	}
+
+	d.Server = httptest.NewTLSServer(http.HandlerFunc(d.serveHTTP))
+
+	return d
+}
+
+// Host returns the address of the device, as passed to NewSession.
+func (d *fakeDevice) Host() string {
+	return d.Listener.Addr().String()
+}
+
+// session returns a session with the device, created with the given options.
+func (d *fakeDevice) session(t *testing.T, options ...Option) *PaloAlto {
+	t.Helper()
+
+	pan, err := NewSession(d.Host(), &AuthMethod{APIKey: "secret"}, append([]Option{WithHTTPClient(d.Client())}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.mu.Lock()
+	d.requests = nil
+	d.mu.Unlock()
+
+	return pan
+}
+
+// Requests returns the parameters of the requests made since the session was created.
+func (d *fakeDevice) Requests() []url.Values {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]url.Values{}, d.requests...)
+}
+
+func (d *fakeDevice) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cmd := r.Form.Get("cmd")
+
+	switch {
+	case r.Form.Get("type") == "keygen":
+		fmt.Fprint(w, `<response status="success"><result><key>secret</key></result></response>`)
+		return
+	case strings.HasPrefix(cmd, "<show><system><info>"):
+		var names []string
+		for name := range d.info {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprint(w, `<response status="success"><result><system>`)
+		for _, name := range names {
+			fmt.Fprintf(w, "<%s>%s</%s>", name, d.info[name], name)
+		}
+		fmt.Fprint(w, `</system></result></response>`)
+		return
+	case strings.HasPrefix(cmd, "<show><panorama-status>"):
+		fmt.Fprint(w, `<response status="success"><result/></response>`)
+		return
+	}
+
+	d.mu.Lock()
+	d.requests = append(d.requests, r.Form)
+	d.mu.Unlock()
+
+	if d.handler == nil {
+		fmt.Fprint(w, `<response status="success" code="20"><msg>command succeeded</msg></response>`)
+		return
+	}
+
+	d.handler(w, r)
+}
+
+// blockUntilCanceled is a handler that doesn't answer until the request is canceled.
+func blockUntilCanceled(w http.ResponseWriter, r *http.Request) {
+	<-r.Context().Done()
+}
+
+func TestNewSessionHTTPClient(t *testing.T) {
+	srv := newFakeDevice(nil)
+	defer srv.Close()
+
+	srv.info["sw-version"] = "10.2.4"
+	srv.info["serial"] = "000000000042"
+
+	pan := srv.session(t)
+
+	if pan.SoftwareVersion != "10.2.4" || pan.Serial != "000000000042" || pan.DeviceType != "panos" {
+		t.Fatalf("got version %q, serial %q, device type %q", pan.SoftwareVersion, pan.Serial, pan.DeviceType)
+	}
+
+	if _, err := pan.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if requests := srv.Requests(); len(requests) != 1 || requests[0].Get("type") != "commit" {
+		t.Fatalf("got requests %v, want a commit", requests)
+	}
+}
+
+func TestSessionClients(t *testing.T) {
+	srv := newFakeDevice(nil)
+	defer srv.Close()
+
+	client := &http.Client{Transport: srv.Client().Transport}
+
+	a, err := NewSession(srv.Host(), &AuthMethod{APIKey: "secret"}, WithHTTPClient(client), WithTimeout(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewSession(srv.Host(), &AuthMethod{APIKey: "secret"}, WithHTTPClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.client == b.client || a.client == http.DefaultClient {
+		t.Fatal("the sessions share an HTTP client")
+	}
+
+	if client.Timeout != 0 || a.client.Timeout != time.Minute || b.client.Timeout != 0 {
+		t.Fatalf("WithTimeout changed the caller's client: got timeouts %s and %s", client.Timeout, a.client.Timeout)
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	srv := newFakeDevice(blockUntilCanceled)
+	defer srv.Close()
+
+	pan := srv.session(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := pan.AddressesContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("the request took %s to stop", elapsed)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	srv := newFakeDevice(blockUntilCanceled)
+	defer srv.Close()
+
+	pan := srv.session(t, WithTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	if err := pan.CreateAddress("web-1", "ip", "10.1.1.1/32", ""); err == nil {
+		t.Fatal("the request did not time out")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("the request took %s to time out", elapsed)
+	}
+}
+
+func TestCertificateVerification(t *testing.T) {
+	srv := newFakeDevice(nil)
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	fingerprint := fmt.Sprintf("%X", sha256.Sum256(srv.Certificate().Raw))
+
+	tests := []struct {
+		name    string
+		options []Option
+		wantErr bool
+	}{
+		{name: "self-signed certificate by default", wantErr: true},
+		{name: "matching pin", options: []Option{WithPinnedCertificate(fingerprint)}},
+		{name: "matching pin with colons", options: []Option{WithPinnedCertificate(fingerprint[:2] + ":" + fingerprint[2:])}},
+		{name: "mismatched pin", options: []Option{WithPinnedCertificate(fmt.Sprintf("%X", sha256.Sum256(nil)))}, wantErr: true},
+		{name: "invalid pin", options: []Option{WithPinnedCertificate("AB:CD")}, wantErr: true},
+		{name: "custom CA pool", options: []Option{WithRootCAs(pool)}},
+		{name: "custom CA pool and matching pin", options: []Option{WithRootCAs(pool), WithPinnedCertificate(fingerprint)}},
+		{name: "custom CA pool without the device's CA", options: []Option{WithRootCAs(x509.NewCertPool())}, wantErr: true},
+		{name: "insecure", options: []Option{WithInsecureSkipVerify()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSession(srv.Host(), &AuthMethod{APIKey: "secret"}, tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	fingerprint := fmt.Sprintf("%X", sha256.Sum256(nil))
+
+	tests := []struct {
+		name       string
+		options    []Option
+		unverified bool
+	}{
+		{name: "no options"},
+		{name: "custom CA pool", options: []Option{WithRootCAs(x509.NewCertPool())}},
+		{name: "client certificate", options: []Option{WithClientCertificate(tls.Certificate{})}},
+		{name: "pin", options: []Option{WithPinnedCertificate(fingerprint)}},
+		{name: "custom CA pool and pin", options: []Option{WithRootCAs(x509.NewCertPool()), WithPinnedCertificate(fingerprint)}},
+		{name: "insecure", options: []Option{WithInsecureSkipVerify()}, unverified: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts sessionOptions
+			for _, option := range tt.options {
+				option(&opts)
+			}
+
+			config, err := opts.tlsConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A pin without a CA pool skips the chain, but still checks the certificate itself.
+			unverified := config != nil && config.InsecureSkipVerify && config.VerifyPeerCertificate == nil
+			if unverified != tt.unverified {
+				t.Fatalf("got unverified %v, want %v", unverified, tt.unverified)
+			}
+		})
+	}
+}
+
+func TestSessionTransport(t *testing.T) {
+	srv := newFakeDevice(nil)
+	defer srv.Close()
+
+	defaultTransport := http.DefaultTransport.(*http.Transport)
+	defaultConfig := defaultTransport.TLSClientConfig
+
+	pan, err := NewSession(srv.Host(), &AuthMethod{APIKey: "secret"}, WithInsecureSkipVerify())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if http.DefaultTransport != defaultTransport || defaultTransport.TLSClientConfig != defaultConfig {
+		t.Fatal("the session's options changed http.DefaultTransport")
+	}
+
+	if defaultConfig != nil && defaultConfig.InsecureSkipVerify {
+		t.Fatal("http.DefaultTransport skips certificate verification")
+	}
+
+	if pan.client.Transport == http.DefaultTransport {
+		t.Fatal("the session uses http.DefaultTransport")
+	}
+
+	// A second session without options must still verify the certificate.
+	if _, err := NewSession(srv.Host(), &AuthMethod{APIKey: "secret"}); err == nil {
+		t.Fatal("a session without options accepted a self-signed certificate")
+	}
+}
+
+func TestAPIKeyPlacement(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		header  bool
+	}{
+		{name: "PAN-OS 10.1", version: "10.1.0", header: true},
+		{name: "PAN-OS 9.0", version: "9.0.0", header: true},
+		{name: "PAN-OS 8.1", version: "8.1.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeDevice(nil)
+			defer srv.Close()
+
+			srv.info["sw-version"] = tt.version
+
+			var mu sync.Mutex
+			var requests []*http.Request
+
+			handler := srv.Config.Handler
+			srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.Contains(r.URL.RawQuery, "key=") || strings.Contains(r.URL.RawQuery, "password=") {
+					t.Errorf("credentials were sent in the URL: %s", r.URL.RawQuery)
+				}
+
+				if err := r.ParseForm(); err != nil {
+					t.Error(err)
+				}
+
+				mu.Lock()
+				requests = append(requests, r)
+				mu.Unlock()
+
+				handler.ServeHTTP(w, r)
+			})
+
+			pan, err := NewSession(srv.Host(), &AuthMethod{Credentials: []string{"admin", "admin"}}, WithHTTPClient(srv.Client()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			requests = nil
+			mu.Unlock()
+
+			if err := pan.CreateAddress("web-1", "ip", "10.1.1.1/32", ""); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(requests) == 0 {
+				t.Fatal("no requests were made")
+			}
+
+			for _, r := range requests {
+				if r.Method != http.MethodPost {
+					t.Errorf("got a %s request, want %s", r.Method, http.MethodPost)
+				}
+
+				header, body := r.Header.Get("X-PAN-KEY"), r.PostForm.Get("key")
+				if tt.header && (header != "secret" || body != "") {
+					t.Errorf("got key %q in the header and %q in the body, want it only in the header", header, body)
+				}
+
+				if !tt.header && (header != "" || body != "secret") {
+					t.Errorf("got key %q in the header and %q in the body, want it only in the body", header, body)
+				}
+			}
+		})
+	}
+}
+
+func TestXMLEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Web server", want: "Web server"},
+		{in: "R&D <lab> 'east'", want: "R&amp;D &lt;lab&gt; &#39;east&#39;"},
+		{in: `"quoted"`, want: "&#34;quoted&#34;"},
+		{in: "a+b.example.com/path?q=1+2", want: "a+b.example.com/path?q=1+2"},
+	}
+
+	for _, tt := range tests {
+		if got := xmlEscape(tt.in); got != tt.want {
+			t.Errorf("xmlEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestXpathQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "web-1", want: "'web-1'"},
+		{in: "", want: "''"},
+		{in: "bob's server", want: `"bob's server"`},
+		{in: `the "web" server`, want: `'the "web" server'`},
+		{in: `bob's "web" server`, want: `concat('bob', "'", 's "web" server')`},
+		{in: `'both" `, want: `concat('', "'", 'both" ')`},
+	}
+
+	for _, tt := range tests {
+		if got := xpathQuote(tt.in); got != tt.want {
+			t.Errorf("xpathQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSpecialCharacters(t *testing.T) {
+	srv := newFakeDevice(nil)
+	defer srv.Close()
+
+	pan := srv.session(t)
+
+	const vsys = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']"
+
+	name := `bob's "web" server`
+	description := "R&D <lab> 'east'"
+	url := "a+b.example.com/path?q=1+2&r=3"
+
+	if err := pan.CreateAddress(name, "ip", "10.1.1.1/32", description); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pan.DeleteAddress(name); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pan.CreateURLCategory("blocked", []string{url}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pan.EditURLCategory("remove", url, "blocked"); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := srv.Requests()
+	if len(requests) != 4 {
+		t.Fatalf("got %d requests, want 4", len(requests))
+	}
+
+	address := vsys + `/address/entry[@name=concat('bob', "'", 's "web" server')]`
+	category := vsys + "/profiles/custom-url-category/entry[@name='blocked']"
+
+	wantXpaths := []string{address, address, category, category + "/list/member[text()='" + url + "']"}
+	for i, want := range wantXpaths {
+		if got := requests[i].Get("xpath"); got != want {
+			t.Errorf("request %d: got xpath %s, want %s", i+1, got, want)
+		}
+	}
+
+	// The elements must be well-formed XML that holds the values exactly as they were given.
+	var entry struct {
+		Description string   `xml:"description"`
+		Members     []string `xml:"list>member"`
+	}
+
+	if err := xml.Unmarshal([]byte("<entry>"+requests[0].Get("element")+"</entry>"), &entry); err != nil || entry.Description != description {
+		t.Errorf("got description %q, error %v from element %s", entry.Description, err, requests[0].Get("element"))
+	}
+
+	if err := xml.Unmarshal([]byte("<entry>"+requests[2].Get("element")+"</entry>"), &entry); err != nil || !reflect.DeepEqual(entry.Members, []string{url}) {
+		t.Errorf("got members %q, error %v from element %s", entry.Members, err, requests[2].Get("element"))
+	}
+}
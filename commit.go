@@ -0,0 +1,295 @@
+package panos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultPollInterval is how often WaitForJob checks on a job, if no interval is given.
+const defaultPollInterval = 5 * time.Second
+
+// CommitJob is a commit that the device has accepted. Pass its ID to WaitForJob to find out whether the commit
+// succeeded.
+type CommitJob struct {
+	// ID is the job ID of the commit. It is 0 if there were no changes to commit, in which case no job was created.
+	ID int
+
+	// Message holds the message lines that the device returned, e.g. "Commit job enqueued with jobid 4".
+	Message []string
+}
+
+// CommitOptions limits a commit to part of the candidate configuration, and describes it. Pass it to Commit. Any
+// field left empty is not used to limit the commit, and a commit with no options, or only a description, commits
+// every pending change.
+//
+// A commit can be limited by administrator, location and the sections listed below, which is what the XML API's
+// <partial> element offers. It can't be limited to a single kind of object, such as only address objects; to leave
+// out every object, use ExcludePolicyAndObjects or ExcludeSharedObjects.
+type CommitOptions struct {
+	// Admins limits the commit to the changes made by the given administrators.
+	Admins []string
+
+	// ExcludeDeviceAndNetwork leaves out any changes to the device and network configuration.
+	ExcludeDeviceAndNetwork bool
+
+	// ExcludePolicyAndObjects leaves out any changes to policies and objects.
+	ExcludePolicyAndObjects bool
+
+	// ExcludeSharedObjects leaves out any changes to shared objects.
+	ExcludeSharedObjects bool
+
+	// DeviceGroups limits the commit to the given device-groups. It can only be used on a Panorama device.
+	DeviceGroups []string
+
+	// Templates limits the commit to the given templates. It can only be used on a Panorama device.
+	Templates []string
+
+	// TemplateStacks limits the commit to the given template stacks. It can only be used on a Panorama device.
+	TemplateStacks []string
+
+	// Description is the commit description, which shows up in the device's commit and config logs.
+	Description string
+}
+
+// commitCmd returns the <commit> command for the given options.
+func (p *PaloAlto) commitCmd(o *CommitOptions) (string, error) {
+	var partial string
+
+	if o == nil {
+		return "<commit></commit>", nil
+	}
+
+	if p.DeviceType != "panorama" && (len(o.DeviceGroups) > 0 || len(o.Templates) > 0 || len(o.TemplateStacks) > 0) {
+		return "", errors.New("you can only limit a commit to device-groups, templates or template stacks on a Panorama device")
+	}
+
+	partial += members("admin", o.Admins)
+	partial += members("device-group", o.DeviceGroups)
+	partial += members("template", o.Templates)
+	partial += members("template-stack", o.TemplateStacks)
+
+	if o.ExcludeDeviceAndNetwork {
+		partial += "<device-and-network>excluded</device-and-network>"
+	}
+
+	if o.ExcludePolicyAndObjects {
+		partial += "<policy-and-objects>excluded</policy-and-objects>"
+	}
+
+	if o.ExcludeSharedObjects {
+		partial += "<shared-object>excluded</shared-object>"
+	}
+
+	cmd := "<commit>"
+	if partial != "" {
+		cmd += "<partial>" + partial + "</partial>"
+	}
+
+	if o.Description != "" {
+		cmd += fmt.Sprintf("<description>%s</description>", xmlEscape(o.Description))
+	}
+
+	return cmd + "</commit>", nil
+}
+
+// members returns the given values as <member> elements within an element named tag, or nothing if there are no
+// values.
+func members(tag string, values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	xmlBody := fmt.Sprintf("<%s>", tag)
+	for _, v := range values {
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(v))
+	}
+
+	return xmlBody + fmt.Sprintf("</%s>", tag)
+}
+
+// commitResponse is used for parsing the response to a commit request.
+type commitResponse struct {
+	XMLName xml.Name `xml:"response"`
+	Job     int      `xml:"result>job"`
+}
+
+// Commit issues a commit on the device. When issuing a commit against a Panorama device,
+// the configuration will only be committed to Panorama, and not an individual device-group.
+//
+// By default, every pending change is committed. To only commit some of them, such as your own changes on a device
+// that several administrators share, you can (optionally) specify a *CommitOptions, e.g.:
+//
+//	pan.Commit(&panos.CommitOptions{Admins: []string{"jdoe"}, Description: "Add web servers"})
+func (p *PaloAlto) Commit(options ...*CommitOptions) (*CommitJob, error) {
+	return p.CommitContext(context.Background(), options...)
+}
+
+// CommitContext is the same as Commit, but uses ctx for all of its API requests.
+func (p *PaloAlto) CommitContext(ctx context.Context, options ...*CommitOptions) (*CommitJob, error) {
+	var o *CommitOptions
+	if len(options) > 0 {
+		o = options[0]
+	}
+
+	cmd, err := p.commitCmd(o)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.commit(ctx, url.Values{"type": {"commit"}, "cmd": {cmd}})
+}
+
+// CommitAll issues a commit to a Panorama device, for the given devicegroup. If you wish to push to specific
+// firewalls within the specified device group only, add each firewalls serial number as an additional parameter,
+// (e.g. CommitAll("Some-DeviceGroup", "000000000001", "000000000002")).
+func (p *PaloAlto) CommitAll(devicegroup string, devices ...string) (*CommitJob, error) {
+	return p.CommitAllContext(context.Background(), devicegroup, devices...)
+}
+
+// CommitAllContext is the same as CommitAll, but uses ctx for all of its API requests.
+func (p *PaloAlto) CommitAllContext(ctx context.Context, devicegroup string, devices ...string) (*CommitJob, error) {
+	cmd, err := p.commitAllCmd(devicegroup, false, devices)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.commit(ctx, url.Values{"type": {"commit"}, "action": {"all"}, "cmd": {cmd}})
+}
+
+// commitAllCmd returns the <commit-all> command that pushes the given device-group to its firewalls, or to only the
+// given devices. If validateOnly is true, the push is only validated.
+func (p *PaloAlto) commitAllCmd(devicegroup string, validateOnly bool, devices []string) (string, error) {
+	if p.DeviceType != "panorama" {
+		return "", errors.New("you can only commit to a device-group on a Panorama device")
+	}
+
+	cmd := fmt.Sprintf("<commit-all><shared-policy><device-group><entry name=\"%s\">", xmlEscape(devicegroup))
+
+	if len(devices) > 0 {
+		cmd += "<devices>"
+
+		for _, d := range devices {
+			cmd += fmt.Sprintf("<entry name=\"%s\"/>", xmlEscape(d))
+		}
+
+		cmd += "</devices>"
+	}
+
+	cmd += "</entry></device-group>"
+
+	if validateOnly {
+		cmd += "<validate-only>yes</validate-only>"
+	}
+
+	return cmd + "</shared-policy></commit-all>", nil
+}
+
+// Validate checks the candidate configuration for errors, the same way a commit would, without committing it. It
+// waits for the validation job to finish, and returns it. Any warnings are in the job's Warnings field. If the
+// configuration is not valid, the job is returned along with a *JobError, whose Details field holds the errors.
+func (p *PaloAlto) Validate() (*Job, error) {
+	return p.ValidateContext(context.Background())
+}
+
+// ValidateContext is the same as Validate, but uses ctx for all of its API requests.
+func (p *PaloAlto) ValidateContext(ctx context.Context) (*Job, error) {
+	job, err := p.commit(ctx, url.Values{"type": {"op"}, "cmd": {"<validate><full></full></validate>"}})
+	if err != nil {
+		return nil, err
+	}
+
+	return p.waitForValidation(ctx, job)
+}
+
+// ValidateAll checks whether pushing the given device-group from a Panorama device would succeed, without pushing
+// it. To only check some of the firewalls in the device-group, add each firewall's serial number as an additional
+// parameter. It waits for the validation job to finish, and returns it, the same way as Validate.
+func (p *PaloAlto) ValidateAll(devicegroup string, devices ...string) (*Job, error) {
+	return p.ValidateAllContext(context.Background(), devicegroup, devices...)
+}
+
+// ValidateAllContext is the same as ValidateAll, but uses ctx for all of its API requests.
+func (p *PaloAlto) ValidateAllContext(ctx context.Context, devicegroup string, devices ...string) (*Job, error) {
+	cmd, err := p.commitAllCmd(devicegroup, true, devices)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := p.commit(ctx, url.Values{"type": {"commit"}, "action": {"all"}, "cmd": {cmd}})
+	if err != nil {
+		return nil, err
+	}
+
+	return p.waitForValidation(ctx, job)
+}
+
+// waitForValidation waits for the given validation job to finish. Unlike a commit, a validation always creates a
+// job, so an error is returned if the device did not return one.
+func (p *PaloAlto) waitForValidation(ctx context.Context, job *CommitJob) (*Job, error) {
+	if job.ID == 0 {
+		msg := "the device did not return a validation job"
+		if len(job.Message) > 0 {
+			msg += fmt.Sprintf(" (%s)", strings.Join(job.Message, "; "))
+		}
+
+		return nil, errors.New(msg)
+	}
+
+	return p.WaitForJob(ctx, job.ID, 0)
+}
+
+// commit sends the given commit or validate request, and returns the job that it created.
+func (p *PaloAlto) commit(ctx context.Context, params url.Values) (*CommitJob, error) {
+	var resp commitResponse
+
+	body, err := p.request(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := xml.Unmarshal([]byte(body), &resp); err != nil {
+		return nil, err
+	}
+
+	return &CommitJob{ID: resp.Job, Message: messageLines([]byte(body))}, nil
+}
+
+// WaitForJob checks on the job with the given ID every pollInterval (5 seconds, if it is zero), until the job
+// finishes or ctx is done. It returns the finished job, whose Details and Warnings fields hold any lines that the
+// device reported.
+//
+// If the job did not succeed, the job is returned along with a *JobError.
+func (p *PaloAlto) WaitForJob(ctx context.Context, id int, pollInterval time.Duration) (*Job, error) {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	for {
+		jobs, err := p.JobsContext(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(jobs.Jobs) == 0 {
+			return nil, fmt.Errorf("job %d does not exist", id)
+		}
+
+		job := jobs.Jobs[0]
+		if job.Status == "FIN" {
+			if job.Result != "OK" {
+				return &job, &JobError{Job: job}
+			}
+
+			return &job, nil
+		}
+
+		if err := sleep(ctx, pollInterval); err != nil {
+			return nil, err
+		}
+	}
+}
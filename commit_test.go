@@ -0,0 +1,269 @@
+package panos
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottdware/go-panos/panostest"
+)
+
+func TestCommit(t *testing.T) {
+	pan, srv := newTestSession(t, panostest.WithJobDuration(50*time.Millisecond))
+	defer srv.Close()
+
+	job, err := pan.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.ID != 0 {
+		t.Fatalf("got job %d for a commit without changes, want 0", job.ID)
+	}
+
+	if err := pan.CreateAddress("web-1", "ip", "10.1.1.1/32", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err = pan.Commit(&CommitOptions{Description: "Add web-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.ID == 0 {
+		t.Fatal("no job was created")
+	}
+
+	finished, err := pan.WaitForJob(context.Background(), job.ID, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if finished.Status != "FIN" || finished.Result != "OK" {
+		t.Fatalf("got job status %s, result %s", finished.Status, finished.Result)
+	}
+
+	if !strings.Contains(srv.Running(), `<entry name="web-1">`) {
+		t.Fatal("web-1 is not in the running configuration")
+	}
+}
+
+func TestWaitForJobCanceled(t *testing.T) {
+	pan, srv := newTestSession(t, panostest.WithJobDuration(time.Hour))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := pan.ValidateContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestCommitCmd(t *testing.T) {
+	firewall := &PaloAlto{DeviceType: "panos"}
+	panorama := &PaloAlto{DeviceType: "panorama"}
+
+	tests := []struct {
+		name    string
+		session *PaloAlto
+		options *CommitOptions
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "no options",
+			session: firewall,
+			want:    "<commit></commit>",
+		},
+		{
+			name:    "empty options",
+			session: firewall,
+			options: &CommitOptions{},
+			want:    "<commit></commit>",
+		},
+		{
+			name:    "admins",
+			session: firewall,
+			options: &CommitOptions{Admins: []string{"jdoe", "asmith"}},
+			want:    "<commit><partial><admin><member>jdoe</member><member>asmith</member></admin></partial></commit>",
+		},
+		{
+			name:    "excluded device and network",
+			session: firewall,
+			options: &CommitOptions{ExcludeDeviceAndNetwork: true},
+			want:    "<commit><partial><device-and-network>excluded</device-and-network></partial></commit>",
+		},
+		{
+			name:    "excluded policy and objects",
+			session: firewall,
+			options: &CommitOptions{ExcludePolicyAndObjects: true},
+			want:    "<commit><partial><policy-and-objects>excluded</policy-and-objects></partial></commit>",
+		},
+		{
+			name:    "excluded shared objects",
+			session: firewall,
+			options: &CommitOptions{ExcludeSharedObjects: true},
+			want:    "<commit><partial><shared-object>excluded</shared-object></partial></commit>",
+		},
+		{
+			name:    "admin with excluded sections",
+			session: firewall,
+			options: &CommitOptions{Admins: []string{"jdoe"}, ExcludeDeviceAndNetwork: true, ExcludePolicyAndObjects: true},
+			want: "<commit><partial><admin><member>jdoe</member></admin>" +
+				"<device-and-network>excluded</device-and-network><policy-and-objects>excluded</policy-and-objects>" +
+				"</partial></commit>",
+		},
+		{
+			name:    "description",
+			session: firewall,
+			options: &CommitOptions{Description: `R&D <lab> "east"`},
+			want:    "<commit><description>R&amp;D &lt;lab&gt; &#34;east&#34;</description></commit>",
+		},
+		{
+			name:    "admin and description",
+			session: firewall,
+			options: &CommitOptions{Admins: []string{"j&doe"}, Description: "Add web servers"},
+			want:    "<commit><partial><admin><member>j&amp;doe</member></admin></partial><description>Add web servers</description></commit>",
+		},
+		{
+			name:    "device-groups",
+			session: panorama,
+			options: &CommitOptions{DeviceGroups: []string{"branch", "hq"}},
+			want:    "<commit><partial><device-group><member>branch</member><member>hq</member></device-group></partial></commit>",
+		},
+		{
+			name:    "templates and template stacks",
+			session: panorama,
+			options: &CommitOptions{Templates: []string{"branch-template"}, TemplateStacks: []string{"branches"}},
+			want: "<commit><partial><template><member>branch-template</member></template>" +
+				"<template-stack><member>branches</member></template-stack></partial></commit>",
+		},
+		{
+			name:    "everything",
+			session: panorama,
+			options: &CommitOptions{
+				Admins:                  []string{"jdoe"},
+				DeviceGroups:            []string{"branch"},
+				Templates:               []string{"branch-template"},
+				ExcludeDeviceAndNetwork: true,
+				Description:             "Branch <rollout>",
+			},
+			want: "<commit><partial><admin><member>jdoe</member></admin><device-group><member>branch</member></device-group>" +
+				"<template><member>branch-template</member></template><device-and-network>excluded</device-and-network>" +
+				"</partial><description>Branch &lt;rollout&gt;</description></commit>",
+		},
+		{
+			name:    "device-groups on a firewall",
+			session: firewall,
+			options: &CommitOptions{DeviceGroups: []string{"branch"}},
+			wantErr: true,
+		},
+		{
+			name:    "templates on a firewall",
+			session: firewall,
+			options: &CommitOptions{Templates: []string{"branch-template"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.session.commitCmd(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		panorama bool
+		validate func(p *PaloAlto) (*Job, error)
+		wantErr  bool
+	}{
+		{
+			name:     "Validate",
+			validate: func(p *PaloAlto) (*Job, error) { return p.Validate() },
+		},
+		{
+			name:     "ValidateAll",
+			panorama: true,
+			validate: func(p *PaloAlto) (*Job, error) { return p.ValidateAll("branch") },
+		},
+		{
+			name:     "ValidateAll with devices",
+			panorama: true,
+			validate: func(p *PaloAlto) (*Job, error) { return p.ValidateAll("branch", "000000000002", "000000000003") },
+		},
+		{
+			name:     "ValidateAll on a firewall",
+			validate: func(p *PaloAlto) (*Job, error) { return p.ValidateAll("branch") },
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []panostest.Option
+			if tt.panorama {
+				options = append(options, panostest.WithPanorama())
+			}
+
+			pan, srv := newTestSession(t, options...)
+			defer srv.Close()
+
+			job, err := tt.validate(pan)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if job.Type != "Validate" || job.Status != "FIN" || job.Result != "OK" {
+				t.Fatalf("got job type %s, status %s, result %s", job.Type, job.Status, job.Result)
+			}
+		})
+	}
+}
+
+func TestValidateWithoutJob(t *testing.T) {
+	var requests int
+
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+
+		body := `<response status="success"><result><msg><line>Validation is not available</line></msg></result></response>`
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body)), Request: r}, nil
+	})
+
+	pan := &PaloAlto{Host: "panorama", URI: "https://panorama/api/?", DeviceType: "panorama", client: &http.Client{Transport: transport}}
+
+	if _, err := pan.Validate(); err == nil || !strings.Contains(err.Error(), "Validation is not available") {
+		t.Fatalf("got %v from Validate, want an error for the missing job", err)
+	}
+
+	if _, err := pan.ValidateAll("branch"); err == nil {
+		t.Fatal("expected an error from ValidateAll for the missing job")
+	}
+
+	if requests != 2 {
+		t.Fatalf("got %d requests, want 2; job 0 must not be polled", requests)
+	}
+}
@@ -0,0 +1,451 @@
+package panos
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// configMetadata holds the attributes that PAN-OS adds to the candidate configuration to track who changed what,
+// and when. They are not part of the configuration, so they are ignored when comparing it.
+var configMetadata = map[string]bool{"admin": true, "dirtyId": true, "time": true}
+
+// ConfigDiff holds the differences between the running and candidate configuration, which are the changes that a
+// commit would make.
+type ConfigDiff struct {
+	// Xpath is the part of the configuration that was compared.
+	Xpath string
+
+	// Changes holds each difference, in the order they appear in the configuration.
+	Changes []ConfigChange
+}
+
+// ConfigChange is a single difference between the running and candidate configuration.
+type ConfigChange struct {
+	// Action is one of: added, removed, modified or moved.
+	Action string
+
+	// Xpath is the xpath of the element that changed.
+	Xpath string
+
+	// Where is the new position of a moved element among its siblings, the same way XpathMove takes it: "top", or
+	// "after" followed by the sibling's xpath step, e.g. "after entry[@name='Allow-DNS']". It is blank for other
+	// changes.
+	Where string
+
+	// Running is the element in the running configuration, as XML. It is blank for added elements.
+	Running string
+
+	// Candidate is the element in the candidate configuration, as XML. It is blank for removed elements.
+	Candidate string
+}
+
+// configNode is an element of the configuration, for comparing one configuration with another.
+type configNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr   `xml:",any,attr"`
+	Text     string       `xml:",chardata"`
+	Children []configNode `xml:",any"`
+}
+
+// configResult is used for parsing the response to a get or show request into configNodes.
+type configResult struct {
+	XMLName xml.Name   `xml:"response"`
+	Result  configNode `xml:"result"`
+}
+
+// PendingChanges compares the running and candidate configuration at the given xpath (e.g. "/config/shared"), and
+// returns every element that has been added, removed, modified or moved since the last commit. If xpath is blank,
+// the whole configuration is compared. The xpath should select a single element, such as a device-group or vsys, since
+// elements selected with // are only told apart by their names. Call String on the result for a unified diff that
+// is easy to review.
+func (p *PaloAlto) PendingChanges(xpath string) (*ConfigDiff, error) {
+	return p.PendingChangesContext(context.Background(), xpath)
+}
+
+// PendingChangesContext is the same as PendingChanges, but uses ctx for all of its API requests.
+func (p *PaloAlto) PendingChangesContext(ctx context.Context, xpath string) (*ConfigDiff, error) {
+	if xpath == "" {
+		xpath = "/config"
+	}
+
+	running, err := p.configTree(ctx, "show", xpath)
+	if err != nil {
+		return nil, err
+	}
+
+	candidate, err := p.configTree(ctx, "get", xpath)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ConfigDiff{Xpath: xpath}
+	parent := xpath
+
+	// When the xpath selects a single element, it is that element's own xpath. Otherwise, each element is told
+	// apart by its name, as a child of the xpath.
+	if len(running.Children) <= 1 && len(candidate.Children) <= 1 {
+		parent = parentXpath(xpath)
+
+		if len(running.Children) == 1 && len(candidate.Children) == 1 {
+			diff.compare(xpath, &running.Children[0], &candidate.Children[0])
+			return diff, nil
+		}
+	}
+
+	diff.compareChildren(parent, running, candidate)
+
+	return diff, nil
+}
+
+// parentXpath returns the xpath of the parent of the element that the given xpath selects, by removing its last step.
+// A / within a predicate, such as entry[@name='10.0.0.0/8'], is part of the step. If there is no parent, the xpath is
+// returned as it is.
+func parentXpath(xpath string) string {
+	var quote byte
+	depth, last := 0, -1
+
+	for i := 0; i < len(xpath); i++ {
+		switch c := xpath[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case depth > 0 && (c == '\'' || c == '"'):
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case c == '/' && depth == 0:
+			last = i
+		}
+	}
+
+	if last <= 0 {
+		return xpath
+	}
+
+	return xpath[:last]
+}
+
+// configTree returns the elements that the given xpath selects in the running (show) or candidate (get)
+// configuration, as the children of the returned node.
+func (p *PaloAlto) configTree(ctx context.Context, action, xpath string) (*configNode, error) {
+	var resp configResult
+
+	body, err := p.config(ctx, action, xpath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := xml.Unmarshal([]byte(body), &resp); err != nil {
+		return nil, err
+	}
+
+	resp.Result.normalize()
+
+	return &resp.Result, nil
+}
+
+// normalize removes the whitespace around the text of n and its children, the text of any element that has
+// children, and the candidate configuration's metadata attributes, so neither formatting nor who made a change
+// shows up as a change.
+func (n *configNode) normalize() {
+	n.Text = strings.TrimSpace(n.Text)
+	if len(n.Children) > 0 {
+		n.Text = ""
+	}
+
+	attrs := n.Attrs[:0]
+	for _, a := range n.Attrs {
+		if !configMetadata[a.Name.Local] {
+			attrs = append(attrs, a)
+		}
+	}
+	n.Attrs = attrs
+
+	for i := range n.Children {
+		n.Children[i].normalize()
+	}
+}
+
+// attr returns the value of the given attribute, or a blank string if it is not set.
+func (n *configNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+
+	return ""
+}
+
+// step returns the xpath step that selects n among its siblings, if it has a name or is the only one of its kind.
+// Use childSteps for the children of an element, which tells apart the ones that aren't.
+func (n *configNode) step() string {
+	if name := n.attr("name"); name != "" {
+		return fmt.Sprintf("%s[@name=%s]", n.XMLName.Local, xpathQuote(name))
+	}
+
+	if n.XMLName.Local == "member" {
+		return fmt.Sprintf("member[text()=%s]", xpathQuote(n.Text))
+	}
+
+	return n.XMLName.Local
+}
+
+// childSteps returns the xpath step of each child of the running and candidate versions of an element. Children
+// without a name that repeat in either version, such as the members of a list, are told apart by their text, and any
+// that are still alike by their position among them, e.g. member[text()='web'][2], so that no two children of the
+// same version share a step.
+func childSteps(running, candidate *configNode) (runningSteps, candidateSteps []string) {
+	repeated := map[string]bool{}
+	for _, n := range []*configNode{running, candidate} {
+		seen := map[string]bool{}
+		for i := range n.Children {
+			tag := n.Children[i].XMLName.Local
+			repeated[tag] = repeated[tag] || seen[tag]
+			seen[tag] = true
+		}
+	}
+
+	// Each step's count is the most times it occurs in either version, so that an element keeps the same step in
+	// both versions whenever it can.
+	counts := map[string]int{}
+
+	steps := func(n *configNode) []string {
+		out := make([]string, len(n.Children))
+		count := map[string]int{}
+
+		for i := range n.Children {
+			child := &n.Children[i]
+
+			step := child.step()
+			if step == child.XMLName.Local && repeated[step] && child.Text != "" {
+				step = fmt.Sprintf("%s[text()=%s]", step, xpathQuote(child.Text))
+			}
+
+			out[i] = step
+			count[step]++
+			if count[step] > counts[step] {
+				counts[step] = count[step]
+			}
+		}
+
+		return out
+	}
+
+	runningSteps, candidateSteps = steps(running), steps(candidate)
+
+	for _, steps := range [][]string{runningSteps, candidateSteps} {
+		position := map[string]int{}
+		for i, step := range steps {
+			if counts[step] > 1 {
+				position[step]++
+				steps[i] = fmt.Sprintf("%s[%d]", step, position[step])
+			}
+		}
+	}
+
+	return runningSteps, candidateSteps
+}
+
+// String returns n as XML, indented by two spaces for each level.
+func (n *configNode) String() string {
+	out, err := xml.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return ""
+	}
+
+	return string(out)
+}
+
+// attrsEqual reports whether a and b have the same attributes, in any order.
+func attrsEqual(a, b *configNode) bool {
+	if len(a.Attrs) != len(b.Attrs) {
+		return false
+	}
+
+	for _, attr := range a.Attrs {
+		if b.attr(attr.Name.Local) != attr.Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compare adds the differences between the running and candidate versions of the element at xpath.
+func (d *ConfigDiff) compare(xpath string, running, candidate *configNode) {
+	// Elements that only hold other elements are compared child by child, including empty ones, so adding the
+	// first entry to an empty element shows up as that entry being added.
+	container := running.Text == "" && candidate.Text == "" && (len(running.Children) > 0 || len(candidate.Children) > 0)
+	if container && attrsEqual(running, candidate) {
+		d.compareChildren(xpath, running, candidate)
+		return
+	}
+
+	if len(running.Children) == 0 && len(candidate.Children) == 0 && running.Text == candidate.Text && attrsEqual(running, candidate) {
+		return
+	}
+
+	d.Changes = append(d.Changes, ConfigChange{Action: "modified", Xpath: xpath, Running: running.String(), Candidate: candidate.String()})
+}
+
+// compareChildren adds the differences between the children of the running and candidate versions of the element
+// at xpath. Elements that were removed come first, followed by the rest in the candidate's order. Since the order
+// of entries, such as rules, matters, entries that changed places are reported as moved.
+func (d *ConfigDiff) compareChildren(xpath string, running, candidate *configNode) {
+	runningSteps, candidateSteps := childSteps(running, candidate)
+
+	runningNodes := map[string]*configNode{}
+	for i, step := range runningSteps {
+		runningNodes[step] = &running.Children[i]
+	}
+
+	candidateNodes := map[string]*configNode{}
+	for i, step := range candidateSteps {
+		candidateNodes[step] = &candidate.Children[i]
+	}
+
+	for i, step := range runningSteps {
+		if _, ok := candidateNodes[step]; !ok {
+			d.Changes = append(d.Changes, ConfigChange{Action: "removed", Xpath: xpath + "/" + step, Running: running.Children[i].String()})
+		}
+	}
+
+	moved := movedEntries(running, candidate)
+	previous := ""
+
+	for i, step := range candidateSteps {
+		child := &candidate.Children[i]
+		path := xpath + "/" + step
+
+		if r, ok := runningNodes[step]; ok {
+			if moved[step] {
+				where := "top"
+				if previous != "" {
+					where = "after " + previous
+				}
+
+				d.Changes = append(d.Changes, ConfigChange{Action: "moved", Xpath: path, Where: where})
+			}
+
+			if child.XMLName.Local == "entry" {
+				previous = step
+			}
+
+			d.compare(path, r, child)
+			continue
+		}
+
+		if child.XMLName.Local == "entry" {
+			previous = step
+		}
+
+		d.Changes = append(d.Changes, ConfigChange{Action: "added", Xpath: path, Candidate: child.String()})
+	}
+}
+
+// movedEntries returns the steps of the entries that are in both running and candidate, but are in a different
+// place relative to the others. The entries that kept the longest possible run of their running order are treated
+// as staying put, so moving one rule to the top reports just that rule, not every rule it jumped over.
+func movedEntries(running, candidate *configNode) map[string]bool {
+	positions := map[string]int{}
+	for i := range running.Children {
+		if child := &running.Children[i]; child.XMLName.Local == "entry" {
+			positions[child.step()] = len(positions)
+		}
+	}
+
+	var steps []string
+	var order []int
+	for i := range candidate.Children {
+		child := &candidate.Children[i]
+		if position, ok := positions[child.step()]; ok && child.XMLName.Local == "entry" {
+			steps = append(steps, child.step())
+			order = append(order, position)
+		}
+	}
+
+	moved := map[string]bool{}
+	kept := longestIncreasing(order)
+	for i, step := range steps {
+		if !kept[i] {
+			moved[step] = true
+		}
+	}
+
+	return moved
+}
+
+// longestIncreasing reports, for each value in seq, whether it is part of the longest increasing subsequence.
+func longestIncreasing(seq []int) []bool {
+	// tails[k] is the index of the last value of the best subsequence of length k+1 found so far, and prev links
+	// each value to the one before it in its subsequence.
+	var tails []int
+	prev := make([]int, len(seq))
+
+	for i, v := range seq {
+		k := sort.Search(len(tails), func(j int) bool { return seq[tails[j]] >= v })
+
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	kept := make([]bool, len(seq))
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			kept[i] = true
+		}
+	}
+
+	return kept
+}
+
+// String returns the differences as a unified diff, with a hunk for each change. Lines from the running
+// configuration start with "-", and lines from the candidate configuration start with "+".
+func (d *ConfigDiff) String() string {
+	var b strings.Builder
+
+	if len(d.Changes) == 0 {
+		return ""
+	}
+
+	fmt.Fprintf(&b, "--- running %s\n+++ candidate %s\n", d.Xpath, d.Xpath)
+
+	for _, c := range d.Changes {
+		if c.Action == "moved" {
+			fmt.Fprintf(&b, "@@ moved %s to %s @@\n", c.Xpath, c.Where)
+			continue
+		}
+
+		fmt.Fprintf(&b, "@@ %s %s @@\n", c.Action, c.Xpath)
+		writeLines(&b, "-", c.Running)
+		writeLines(&b, "+", c.Candidate)
+	}
+
+	return b.String()
+}
+
+// writeLines writes each line of text to b, after the given prefix.
+func writeLines(b *strings.Builder, prefix, text string) {
+	if text == "" {
+		return
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString(prefix + line + "\n")
+	}
+}
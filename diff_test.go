@@ -0,0 +1,302 @@
+package panos
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testRulebase = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/rulebase"
+
+func TestPendingChanges(t *testing.T) {
+	rules := testRulebase + "/security/rules"
+
+	tests := []struct {
+		name   string
+		change func(p *PaloAlto) error
+		want   []ConfigChange
+	}{
+		{
+			name:   "no changes",
+			change: func(p *PaloAlto) error { return nil },
+		},
+		{
+			name:   "move to the top",
+			change: func(p *PaloAlto) error { return p.XpathMove(rules+"/entry[@name='r2']", "top") },
+			want:   []ConfigChange{{Action: "moved", Xpath: rules + "/entry[@name='r2']", Where: "top"}},
+		},
+		{
+			name:   "move to the bottom",
+			change: func(p *PaloAlto) error { return p.XpathMove(rules+"/entry[@name='r1']", "bottom") },
+			want:   []ConfigChange{{Action: "moved", Xpath: rules + "/entry[@name='r1']", Where: "after entry[@name='r3']"}},
+		},
+		{
+			name: "move and modify",
+			change: func(p *PaloAlto) error {
+				if err := p.XpathMove(rules+"/entry[@name='r3']", "before", "r1"); err != nil {
+					return err
+				}
+
+				return p.XpathConfig("edit", rules+"/entry[@name='r3']/action", "<action>deny</action>")
+			},
+			want: []ConfigChange{
+				{Action: "moved", Xpath: rules + "/entry[@name='r3']", Where: "top"},
+				{Action: "modified", Xpath: rules + "/entry[@name='r3']/action", Running: "<action>allow</action>", Candidate: "<action>deny</action>"},
+			},
+		},
+		{
+			name: "add",
+			change: func(p *PaloAlto) error {
+				return p.XpathConfig("set", rules+"/entry[@name='r4']", "<action>deny</action>")
+			},
+			want: []ConfigChange{
+				{Action: "added", Xpath: rules + "/entry[@name='r4']", Candidate: "<entry name=\"r4\">\n  <action>deny</action>\n</entry>"},
+			},
+		},
+		{
+			name:   "remove",
+			change: func(p *PaloAlto) error { return p.XpathConfig("delete", rules+"/entry[@name='r2']") },
+			want: []ConfigChange{
+				{Action: "removed", Xpath: rules + "/entry[@name='r2']", Running: "<entry name=\"r2\">\n  <action>allow</action>\n</entry>"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pan, srv := newTestSession(t)
+			defer srv.Close()
+
+			element := `<entry name="r1"><action>allow</action></entry><entry name="r2"><action>allow</action></entry><entry name="r3"><action>allow</action></entry>`
+			if err := pan.XpathConfig("set", rules, element); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := pan.Commit(); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.change(pan); err != nil {
+				t.Fatal(err)
+			}
+
+			diff, err := pan.PendingChanges(testRulebase)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(diff.Changes, tt.want) {
+				t.Fatalf("got %+v, want %+v", diff.Changes, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareIgnoresMetadata(t *testing.T) {
+	var running, candidate configNode
+
+	if err := xml.Unmarshal([]byte(`<rules><entry name="r1"><action>allow</action></entry></rules>`), &running); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := xml.Unmarshal([]byte(`<rules admin="jdoe" dirtyId="3" time="2026/01/02 03:04:05"><entry name="r1" admin="jdoe" dirtyId="3" time="2026/01/02 03:04:05"><action admin="jdoe" dirtyId="3" time="2026/01/02 03:04:05">deny</action></entry></rules>`), &candidate); err != nil {
+		t.Fatal(err)
+	}
+
+	running.normalize()
+	candidate.normalize()
+
+	var diff ConfigDiff
+	diff.compare("/rules", &running, &candidate)
+
+	want := []ConfigChange{{Action: "modified", Xpath: "/rules/entry[@name='r1']/action", Running: "<action>allow</action>", Candidate: "<action>deny</action>"}}
+	if !reflect.DeepEqual(diff.Changes, want) {
+		t.Fatalf("got %+v, want %+v", diff.Changes, want)
+	}
+}
+
+func TestCompareRepeatedElements(t *testing.T) {
+	tests := []struct {
+		name      string
+		running   string
+		candidate string
+		want      []ConfigChange
+	}{
+		{
+			name:      "member added and removed",
+			running:   `<list><member>a</member><member>b</member></list>`,
+			candidate: `<list><member>b</member><member>c</member></list>`,
+			want: []ConfigChange{
+				{Action: "removed", Xpath: "/list/member[text()='a']", Running: "<member>a</member>"},
+				{Action: "added", Xpath: "/list/member[text()='c']", Candidate: "<member>c</member>"},
+			},
+		},
+		{
+			name:      "duplicate member removed",
+			running:   `<list><member>a</member><member>a</member></list>`,
+			candidate: `<list><member>a</member></list>`,
+			want:      []ConfigChange{{Action: "removed", Xpath: "/list/member[text()='a'][2]", Running: "<member>a</member>"}},
+		},
+		{
+			name:      "repeated element removed",
+			running:   `<servers><server>10.0.0.1</server><server>10.0.0.2</server></servers>`,
+			candidate: `<servers><server>10.0.0.2</server></servers>`,
+			want:      []ConfigChange{{Action: "removed", Xpath: "/servers/server[text()='10.0.0.1']", Running: "<server>10.0.0.1</server>"}},
+		},
+		{
+			name:      "repeated element without text modified",
+			running:   `<hops><hop><ip>10.0.0.1</ip></hop><hop><ip>10.0.0.2</ip></hop></hops>`,
+			candidate: `<hops><hop><ip>10.0.0.1</ip></hop><hop><ip>10.0.0.3</ip></hop></hops>`,
+			want: []ConfigChange{
+				{Action: "modified", Xpath: "/hops/hop[2]/ip", Running: "<ip>10.0.0.2</ip>", Candidate: "<ip>10.0.0.3</ip>"},
+			},
+		},
+		{
+			name:      "single element modified",
+			running:   `<entry><description>old</description></entry>`,
+			candidate: `<entry><description>new</description></entry>`,
+			want: []ConfigChange{
+				{Action: "modified", Xpath: "/entry/description", Running: "<description>old</description>", Candidate: "<description>new</description>"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, candidate configNode
+
+			if err := xml.Unmarshal([]byte(tt.running), &running); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := xml.Unmarshal([]byte(tt.candidate), &candidate); err != nil {
+				t.Fatal(err)
+			}
+
+			running.normalize()
+			candidate.normalize()
+
+			var diff ConfigDiff
+			diff.compare("/"+running.XMLName.Local, &running, &candidate)
+
+			if !reflect.DeepEqual(diff.Changes, tt.want) {
+				t.Fatalf("got %+v, want %+v", diff.Changes, tt.want)
+			}
+		})
+	}
+}
+
+func TestPendingChangesMembers(t *testing.T) {
+	pan, srv := newTestSession(t)
+	defer srv.Close()
+
+	for _, name := range []string{"web-1", "web-2", "web-3"} {
+		if err := pan.CreateAddress(name, "ip", "10.1.1.1/32", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := pan.CreateAddressGroup("web", "static", []string{"web-1", "web-2"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pan.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pan.EditGroup("address", "add", "web-3", "web"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pan.EditGroup("address", "remove", "web-1", "web"); err != nil {
+		t.Fatal(err)
+	}
+
+	xpath := "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='web']"
+
+	diff, err := pan.PendingChanges(xpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ConfigChange{
+		{Action: "removed", Xpath: xpath + "/static/member[text()='web-1']", Running: "<member>web-1</member>"},
+		{Action: "added", Xpath: xpath + "/static/member[text()='web-3']", Candidate: "<member>web-3</member>"},
+	}
+
+	if !reflect.DeepEqual(diff.Changes, want) {
+		t.Fatalf("got %+v, want %+v", diff.Changes, want)
+	}
+}
+
+func TestLongestIncreasing(t *testing.T) {
+	tests := []struct {
+		seq  []int
+		want []bool
+	}{
+		{seq: nil, want: []bool{}},
+		{seq: []int{0, 1, 2}, want: []bool{true, true, true}},
+		{seq: []int{1, 0, 2}, want: []bool{false, true, true}},
+		{seq: []int{1, 2, 3, 0}, want: []bool{true, true, true, false}},
+		{seq: []int{3, 0, 1, 2}, want: []bool{false, true, true, true}},
+	}
+
+	for _, tt := range tests {
+		if got := longestIncreasing(tt.seq); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("longestIncreasing(%v) = %v, want %v", tt.seq, got, tt.want)
+		}
+	}
+}
+
+func TestConfigDiffStringMoved(t *testing.T) {
+	diff := &ConfigDiff{Xpath: "/rules", Changes: []ConfigChange{{Action: "moved", Xpath: "/rules/entry[@name='r2']", Where: "top"}}}
+	if got := diff.String(); !strings.Contains(got, "@@ moved /rules/entry[@name='r2'] to top @@\n") {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestParentXpath(t *testing.T) {
+	tests := []struct {
+		xpath string
+		want  string
+	}{
+		{xpath: "/config", want: "/config"},
+		{xpath: "/config/shared", want: "/config"},
+		{xpath: "/config/shared/address/entry[@name='web-1']", want: "/config/shared/address"},
+		{xpath: "/config/shared/address/entry[@name='10.0.0.0/8']", want: "/config/shared/address"},
+		{xpath: `/config/shared/address/entry[@name="a/b'] c/d"]`, want: "/config/shared/address"},
+		{xpath: "/config/shared/address/entry[@name='a]/b']/description", want: "/config/shared/address/entry[@name='a]/b']"},
+		{xpath: testRulebase, want: strings.TrimSuffix(testRulebase, "/rulebase")},
+	}
+
+	for _, tt := range tests {
+		if got := parentXpath(tt.xpath); got != tt.want {
+			t.Errorf("parentXpath(%s) = %s, want %s", tt.xpath, got, tt.want)
+		}
+	}
+}
+
+func TestPendingChangesSlashInName(t *testing.T) {
+	pan, srv := newTestSession(t)
+	defer srv.Close()
+
+	if err := pan.CreateAddress("10.0.0.0/8", "ip", "10.0.0.0/8", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	xpath := "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='10.0.0.0/8']"
+
+	diff, err := pan.PendingChanges(xpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ConfigChange{
+		{Action: "added", Xpath: xpath, Candidate: "<entry name=\"10.0.0.0/8\">\n  <ip-netmask>10.0.0.0/8</ip-netmask>\n</entry>"},
+	}
+
+	if !reflect.DeepEqual(diff.Changes, want) {
+		t.Fatalf("got %+v, want %+v", diff.Changes, want)
+	}
+}
@@ -0,0 +1,158 @@
+package panos
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// APIError is returned whenever the device responds to an API call with an error status. It holds the error code,
+// the description of that code, and any message lines that the device returned along with it, as well as the
+// xpath or command that the request was made against.
+type APIError struct {
+	// Code is the error code returned by the device, e.g. "7".
+	Code string
+
+	// Message is the description of the error code.
+	Message string
+
+	// Details holds each of the <msg> and <line> entries returned by the device, if any.
+	Details []string
+
+	// Xpath is the xpath of the configuration request that failed, if any.
+	Xpath string
+
+	// Cmd is the command of the operational or commit request that failed, if any.
+	Cmd string
+}
+
+// Error returns the error code and its description, followed by any details returned by the device.
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("error code %s: %s", e.Code, e.Message)
+	if e.Code == "" {
+		msg = "request failed"
+	}
+
+	if len(e.Details) > 0 {
+		msg += fmt.Sprintf(" (%s)", strings.Join(e.Details, "; "))
+	}
+
+	return msg
+}
+
+// JobError is returned by WaitForJob when a job, such as a commit, finishes without succeeding.
+type JobError struct {
+	// Job is the finished job. Its Details field holds the reasons the job failed.
+	Job Job
+}
+
+// Error returns the job's ID, type and result, followed by any details reported by the device.
+func (e *JobError) Error() string {
+	msg := fmt.Sprintf("job %d (%s) finished with result %s", e.Job.ID, e.Job.Type, e.Job.Result)
+	if len(e.Job.Details) > 0 {
+		msg += fmt.Sprintf(" (%s)", strings.Join(e.Job.Details, "; "))
+	}
+
+	return msg
+}
+
+// IsObjectNotPresent reports whether err is an *APIError for an object that does not exist at the given xpath.
+func IsObjectNotPresent(err error) bool {
+	return hasErrorCode(err, "7")
+}
+
+// IsObjectNotUnique reports whether err is an *APIError for an xpath that matched more than one object.
+func IsObjectNotUnique(err error) bool {
+	return hasErrorCode(err, "8")
+}
+
+// IsReferenceCountNotZero reports whether err is an *APIError for an object that could not be deleted because
+// other objects still refer to it.
+func IsReferenceCountNotZero(err error) bool {
+	return hasErrorCode(err, "10")
+}
+
+// IsUnauthorized reports whether err is an *APIError caused by an invalid API key, or by an administrator
+// that does not have the rights to make the request.
+func IsUnauthorized(err error) bool {
+	return hasErrorCode(err, "403", "16")
+}
+
+// hasErrorCode reports whether err is an *APIError with one of the given codes.
+func hasErrorCode(err error, codes ...string) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	for _, code := range codes {
+		if apiErr.Code == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// responseStatus holds the status attributes of an API response.
+type responseStatus struct {
+	XMLName xml.Name
+	Status  string `xml:"status,attr"`
+	Code    string `xml:"code,attr"`
+}
+
+// checkResponse returns an *APIError if the given body is an API response with an error status. The xpath
+// and cmd parameters are recorded in the error. Bodies that are not API responses, such as exported files,
+// are left alone.
+func checkResponse(body []byte, xpath, cmd string) error {
+	var status responseStatus
+
+	if err := xml.Unmarshal(body, &status); err != nil || status.XMLName.Local != "response" {
+		return nil
+	}
+
+	if status.Status == "success" {
+		return nil
+	}
+
+	return &APIError{
+		Code:    status.Code,
+		Message: errorCodes[status.Code],
+		Details: messageLines(body),
+		Xpath:   xpath,
+		Cmd:     cmd,
+	}
+}
+
+// messageLines returns the text of every <msg> and <line> element in the response, in the order they appear.
+func messageLines(body []byte) []string {
+	var lines []string
+	depth := 0
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "msg" || depth > 0 {
+				depth++
+			}
+		case xml.EndElement:
+			if depth > 0 {
+				depth--
+			}
+		case xml.CharData:
+			if text := strings.TrimSpace(string(t)); depth > 0 && text != "" {
+				lines = append(lines, text)
+			}
+		}
+	}
+
+	return lines
+}
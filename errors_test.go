@@ -0,0 +1,121 @@
+package panos
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCheckResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr *APIError
+	}{
+		{
+			name: "success",
+			body: `<response status="success"><result/></response>`,
+		},
+		{
+			name: "not an API response",
+			body: `<config version="10.1.0"><devices/></config>`,
+		},
+		{
+			name:    "message text",
+			body:    `<response status="error" code="7"><msg>Object doesn't exist</msg></response>`,
+			wantErr: &APIError{Code: "7", Message: errorCodes["7"], Details: []string{"Object doesn't exist"}},
+		},
+		{
+			name: "multiple lines",
+			body: `<response status="error" code="12"><msg><line>address -> web-1 is invalid</line>` +
+				`<line>address is invalid</line></msg></response>`,
+			wantErr: &APIError{Code: "12", Message: errorCodes["12"], Details: []string{"address -> web-1 is invalid", "address is invalid"}},
+		},
+		{
+			name: "nested lines",
+			body: `<response status="error" code="10"><msg><line><![CDATA[ rules -> allow-web ]]>` +
+				`<line>address -> web-1 is in use</line></line></msg></response>`,
+			wantErr: &APIError{Code: "10", Message: errorCodes["10"], Details: []string{"rules -> allow-web", "address -> web-1 is in use"}},
+		},
+		{
+			name:    "lines in the result",
+			body:    `<response status="error"><result><msg><line>commit failed</line></msg></result></response>`,
+			wantErr: &APIError{Details: []string{"commit failed"}},
+		},
+		{
+			name:    "no message",
+			body:    `<response status="error" code="403"/>`,
+			wantErr: &APIError{Code: "403", Message: errorCodes["403"]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkResponse([]byte(tt.body), "/config", "")
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("got %v, want no error", err)
+				}
+
+				return
+			}
+
+			tt.wantErr.Xpath = "/config"
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("got %v, want an *APIError", err)
+			}
+
+			if !reflect.DeepEqual(apiErr, tt.wantErr) {
+				t.Fatalf("got %+v, want %+v", apiErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestErrorCodeHelpers(t *testing.T) {
+	tests := []struct {
+		name  string
+		is    func(error) bool
+		codes []string
+	}{
+		{name: "IsObjectNotPresent", is: IsObjectNotPresent, codes: []string{"7"}},
+		{name: "IsObjectNotUnique", is: IsObjectNotUnique, codes: []string{"8"}},
+		{name: "IsReferenceCountNotZero", is: IsReferenceCountNotZero, codes: []string{"10"}},
+		{name: "IsUnauthorized", is: IsUnauthorized, codes: []string{"16", "403"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, code := range []string{"7", "8", "10", "16", "403"} {
+				want := false
+				for _, c := range tt.codes {
+					want = want || c == code
+				}
+
+				apiErr := &APIError{Code: code, Message: errorCodes[code]}
+				errs := []error{
+					apiErr,
+					fmt.Errorf("unable to delete web-1 - %w", apiErr),
+					fmt.Errorf("bulk: %w", fmt.Errorf("unable to delete web-1 - %w", apiErr)),
+				}
+
+				for _, err := range errs {
+					if got := tt.is(err); got != want {
+						t.Errorf("got %v for %q, want %v", got, err, want)
+					}
+				}
+			}
+
+			if tt.is(errors.New("error code " + tt.codes[0])) {
+				t.Error("matched an error that is not an *APIError")
+			}
+
+			if tt.is(nil) {
+				t.Error("matched a nil error")
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package panos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+)
+
+// ExportConfig writes the running configuration of the device to w, as an XML file that ImportConfig can load
+// back.
+func (p *PaloAlto) ExportConfig(w io.Writer) error {
+	return p.ExportConfigContext(context.Background(), w)
+}
+
+// ExportConfigContext is the same as ExportConfig, but uses ctx for all of its API requests.
+func (p *PaloAlto) ExportConfigContext(ctx context.Context, w io.Writer) error {
+	return p.export(ctx, url.Values{"type": {"export"}, "category": {"configuration"}}, w)
+}
+
+// ImportConfig uploads the configuration file read from r to the device, and saves it with the given name. It
+// does not change the candidate configuration; call LoadConfig with the same name to do that.
+func (p *PaloAlto) ImportConfig(name string, r io.Reader) error {
+	return p.ImportConfigContext(context.Background(), name, r)
+}
+
+// ImportConfigContext is the same as ImportConfig, but uses ctx for all of its API requests.
+func (p *PaloAlto) ImportConfigContext(ctx context.Context, name string, r io.Reader) error {
+	if name == "" {
+		return errors.New("you must specify a name for the imported configuration")
+	}
+
+	if _, err := p.upload(ctx, url.Values{"type": {"import"}, "category": {"configuration"}}, name, r); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ExportCertificate writes the certificate with the given name to w, without its private key. Format can be one of:
+// pem or der.
+func (p *PaloAlto) ExportCertificate(name, format string, w io.Writer) error {
+	return p.ExportCertificateContext(context.Background(), name, format, w)
+}
+
+// ExportCertificateContext is the same as ExportCertificate, but uses ctx for all of its API requests.
+func (p *PaloAlto) ExportCertificateContext(ctx context.Context, name, format string, w io.Writer) error {
+	params := url.Values{
+		"type":             {"export"},
+		"category":         {"certificate"},
+		"certificate-name": {name},
+		"format":           {format},
+		"include-key":      {"no"},
+	}
+
+	return p.export(ctx, params, w)
+}
+
+// ExportKeyPair writes the certificate with the given name to w, along with its private key, which is encrypted
+// with passphrase. Format can be one of: pem or pkcs12.
+func (p *PaloAlto) ExportKeyPair(name, format, passphrase string, w io.Writer) error {
+	return p.ExportKeyPairContext(context.Background(), name, format, passphrase, w)
+}
+
+// ExportKeyPairContext is the same as ExportKeyPair, but uses ctx for all of its API requests.
+func (p *PaloAlto) ExportKeyPairContext(ctx context.Context, name, format, passphrase string, w io.Writer) error {
+	if passphrase == "" {
+		return errors.New("you must specify a passphrase to export a private key")
+	}
+
+	params := url.Values{
+		"type":             {"export"},
+		"category":         {"certificate"},
+		"certificate-name": {name},
+		"format":           {format},
+		"include-key":      {"yes"},
+		"passphrase":       {passphrase},
+	}
+
+	return p.export(ctx, params, w)
+}
+
+// ImportCertificate uploads the certificate read from r to the device, with the given name. Format can be one of:
+// pem or der. If a certificate with that name already exists, it is replaced, which is how a certificate is renewed.
+func (p *PaloAlto) ImportCertificate(name, format string, r io.Reader) error {
+	return p.ImportCertificateContext(context.Background(), name, format, r)
+}
+
+// ImportCertificateContext is the same as ImportCertificate, but uses ctx for all of its API requests.
+func (p *PaloAlto) ImportCertificateContext(ctx context.Context, name, format string, r io.Reader) error {
+	params := url.Values{
+		"type":             {"import"},
+		"category":         {"certificate"},
+		"certificate-name": {name},
+		"format":           {format},
+	}
+
+	if _, err := p.upload(ctx, params, name, r); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ImportKeyPair uploads a certificate and its private key, read from r, to the device with the given name. The
+// private key must be encrypted with passphrase. Format can be one of: pem or pkcs12. If a certificate with that
+// name already exists, it is replaced.
+func (p *PaloAlto) ImportKeyPair(name, format, passphrase string, r io.Reader) error {
+	return p.ImportKeyPairContext(context.Background(), name, format, passphrase, r)
+}
+
+// ImportKeyPairContext is the same as ImportKeyPair, but uses ctx for all of its API requests.
+func (p *PaloAlto) ImportKeyPairContext(ctx context.Context, name, format, passphrase string, r io.Reader) error {
+	if passphrase == "" {
+		return errors.New("you must specify the passphrase of the private key")
+	}
+
+	params := url.Values{
+		"type":             {"import"},
+		"category":         {"keypair"},
+		"certificate-name": {name},
+		"format":           {format},
+		"passphrase":       {passphrase},
+	}
+
+	if _, err := p.upload(ctx, params, name, r); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// export runs the given export request, and writes the file that the device returns to w.
+func (p *PaloAlto) export(ctx context.Context, params url.Values, w io.Writer) error {
+	data, err := p.request(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, data); err != nil {
+		return err
+	}
+
+	return nil
+}
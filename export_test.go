@@ -0,0 +1,122 @@
+package panos
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExportAndImportConfig(t *testing.T) {
+	pan, srv := newTestSession(t)
+	defer srv.Close()
+
+	if err := pan.CreateAddress("web-1", "ip", "10.1.1.1/32", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pan.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := pan.ExportConfig(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), `<entry name="web-1">`) {
+		t.Fatalf("the exported configuration is missing web-1: %s", buf.String())
+	}
+
+	if err := pan.DeleteAddress("web-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pan.ImportConfig("backup.xml", &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := pan.ListSavedConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, c := range saved.Configs {
+		found = found || c.Name == "backup.xml"
+	}
+
+	if !found {
+		t.Fatalf("backup.xml is not one of the saved configurations: %+v", saved.Configs)
+	}
+
+	if err := pan.LoadConfig("backup.xml"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(srv.Candidate(), `<entry name="web-1">`) {
+		t.Fatal("loading the imported configuration did not restore web-1")
+	}
+}
+
+func TestExportAndImportCertificate(t *testing.T) {
+	pan, srv := newTestSession(t)
+	defer srv.Close()
+
+	cert := "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr bool
+	}{
+		{
+			name: "import certificate",
+			run:  func() error { return pan.ImportCertificate("web", "pem", strings.NewReader(cert)) },
+		},
+		{
+			name: "export certificate",
+			run: func() error {
+				var buf bytes.Buffer
+				if err := pan.ExportCertificate("web", "pem", &buf); err != nil {
+					return err
+				}
+
+				if buf.String() != cert {
+					t.Errorf("got %q, want %q", buf.String(), cert)
+				}
+
+				return nil
+			},
+		},
+		{
+			name:    "export the private key of a certificate without one",
+			run:     func() error { return pan.ExportKeyPair("web", "pem", "passphrase", &bytes.Buffer{}) },
+			wantErr: true,
+		},
+		{
+			name:    "export a certificate that does not exist",
+			run:     func() error { return pan.ExportCertificate("missing", "pem", &bytes.Buffer{}) },
+			wantErr: true,
+		},
+		{
+			name:    "import a key pair without a passphrase",
+			run:     func() error { return pan.ImportKeyPair("vpn", "pem", "", strings.NewReader(cert)) },
+			wantErr: true,
+		},
+		{
+			name: "import a key pair",
+			run:  func() error { return pan.ImportKeyPair("vpn", "pem", "passphrase", strings.NewReader(cert)) },
+		},
+		{
+			name: "export a key pair",
+			run:  func() error { return pan.ExportKeyPair("vpn", "pem", "passphrase", &bytes.Buffer{}) },
+		},
+	}
+
+	// The cases run in order, since later ones use the certificates that earlier ones import.
+	for _, tt := range tests {
+		if err := tt.run(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -2,11 +2,4 @@ module github.com/scottdware/go-panos
 
 go 1.13
 
-require (
-	github.com/parnurzeal/gorequest v0.2.16
-	github.com/pkg/errors v0.9.1 // indirect
-	github.com/scottdware/go-easycsv v0.0.0-20180104194405-695e7e580f43
-	golang.org/x/net v0.0.0-20200114155413-6afb5195e5aa // indirect
-	gopkg.in/resty.v1 v1.12.0
-	moul.io/http2curl v1.0.0 // indirect
-)
+require github.com/scottdware/go-easycsv v0.0.0-20180104194405-695e7e580f43
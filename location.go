@@ -0,0 +1,160 @@
+package panos
+
+import (
+	"errors"
+	"fmt"
+)
+
+// locationKind is the type of configuration that a Location points to.
+type locationKind int
+
+const (
+	locationDefault locationKind = iota
+	locationShared
+	locationDeviceGroup
+	locationVsys
+	locationTemplate
+	locationTemplateStack
+)
+
+// Location is where in the configuration an object or rule lives. Use one of SharedLocation, DeviceGroupLocation,
+// VsysLocation, TemplateLocation or TemplateStackLocation to create one, and pass it as the last parameter to any of
+// the object, policy and tag functions, e.g.:
+//
+// CreateAddress("web-server", "ip", "10.1.1.10/32", "", panos.DeviceGroupLocation("Branch-Offices"))
+//
+// If you don't pass a location, objects on a firewall are created in the session's vsys (see WithVsys). On a Panorama
+// device you must always pass one.
+type Location struct {
+	kind locationKind
+	name string
+	vsys string
+}
+
+// SharedLocation is the shared location, on either a firewall or a Panorama device.
+func SharedLocation() Location {
+	return Location{kind: locationShared}
+}
+
+// DeviceGroupLocation is the given device-group on a Panorama device.
+func DeviceGroupLocation(name string) Location {
+	return Location{kind: locationDeviceGroup, name: name}
+}
+
+// VsysLocation is the given virtual system (e.g. "vsys2") on a firewall.
+func VsysLocation(name string) Location {
+	return Location{kind: locationVsys, vsys: name}
+}
+
+// TemplateLocation is the given virtual system within a template on a Panorama device. If vsys is blank, vsys1 is used.
+func TemplateLocation(name, vsys string) Location {
+	return Location{kind: locationTemplate, name: name, vsys: vsys}
+}
+
+// TemplateStackLocation is the given virtual system within a template stack on a Panorama device. If vsys is blank, vsys1
+// is used.
+func TemplateStackLocation(name, vsys string) Location {
+	return Location{kind: locationTemplateStack, name: name, vsys: vsys}
+}
+
+// String returns a description of the location, such as "device-group Branch-Offices".
+func (l Location) String() string {
+	switch l.kind {
+	case locationShared:
+		return "shared"
+	case locationDeviceGroup:
+		return fmt.Sprintf("device-group %s", l.name)
+	case locationVsys:
+		return fmt.Sprintf("vsys %s", l.vsys)
+	case locationTemplate:
+		return fmt.Sprintf("template %s (%s)", l.name, l.vsysName())
+	case locationTemplateStack:
+		return fmt.Sprintf("template-stack %s (%s)", l.name, l.vsysName())
+	}
+
+	return "default"
+}
+
+// vsysName returns the name of the location's vsys, or vsys1 if none was given.
+func (l Location) vsysName() string {
+	if l.vsys == "" {
+		return defaultVsys
+	}
+
+	return l.vsys
+}
+
+// locationXpath returns the xpath of the configuration at the first of the given locations. If no location is
+// given, the session's vsys is used on a firewall, and on a Panorama device an error is returned.
+func (p *PaloAlto) locationXpath(location ...Location) (string, error) {
+	var l Location
+	if len(location) > 0 {
+		l = location[0]
+	}
+
+	switch l.kind {
+	case locationDefault:
+		if p.DeviceType == "panorama" {
+			return "", errors.New("you must specify a location (e.g. a device-group) on a Panorama device")
+		}
+
+		return p.vsysXpath(), nil
+	case locationShared:
+		return "/config/shared", nil
+	case locationVsys:
+		if p.DeviceType == "panorama" {
+			return "", errors.New("you must be connected to a firewall when specifying a vsys")
+		}
+
+		if l.vsys == "" {
+			return "", errors.New("you must specify the name of the vsys")
+		}
+
+		return fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name=%s]", xpathQuote(l.vsys)), nil
+	}
+
+	if p.DeviceType != "panorama" {
+		return "", errors.New("you must be connected to a Panorama device when specifying a device-group, template or template-stack")
+	}
+
+	if l.name == "" {
+		return "", errors.New("you must specify the name of the device-group, template or template-stack")
+	}
+
+	switch l.kind {
+	case locationDeviceGroup:
+		return fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name=%s]", xpathQuote(l.name)), nil
+	case locationTemplate:
+		return fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name=%s]/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name=%s]", xpathQuote(l.name), xpathQuote(l.vsysName())), nil
+	case locationTemplateStack:
+		return fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name=%s]/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name=%s]", xpathQuote(l.name), xpathQuote(l.vsysName())), nil
+	}
+
+	return "", errors.New("unknown location")
+}
+
+// rulebaseXpath returns the xpath of the given rulebase at the given location. On a Panorama device, ruletype must
+// be pre or post, to choose between the pre-rulebase and post-rulebase. Firewalls and templates only have the one
+// rulebase, so ruletype is ignored.
+func (p *PaloAlto) rulebaseXpath(ruletype string, location ...Location) (string, error) {
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return "", err
+	}
+
+	var l Location
+	if len(location) > 0 {
+		l = location[0]
+	}
+
+	if p.DeviceType != "panorama" || l.kind == locationTemplate || l.kind == locationTemplateStack {
+		return base + "/rulebase", nil
+	}
+
+	switch ruletype {
+	case "pre", "post":
+		return fmt.Sprintf("%s/%s-rulebase", base, ruletype), nil
+	}
+
+	return "", errors.New("the rule type must be pre or post on a Panorama device")
+}
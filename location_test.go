@@ -0,0 +1,156 @@
+package panos
+
+import "testing"
+
+func TestLocationXpath(t *testing.T) {
+	firewall := &PaloAlto{DeviceType: "panos"}
+	panorama := &PaloAlto{DeviceType: "panorama"}
+
+	const device = "/config/devices/entry[@name='localhost.localdomain']"
+
+	tests := []struct {
+		name     string
+		session  *PaloAlto
+		location []Location
+		want     string
+		wantErr  bool
+	}{
+		{name: "firewall default", session: firewall, want: device + "/vsys/entry[@name='vsys1']"},
+		{name: "firewall default with WithVsys", session: firewall.WithVsys("vsys3"), want: device + "/vsys/entry[@name='vsys3']"},
+		{name: "firewall shared", session: firewall, location: []Location{SharedLocation()}, want: "/config/shared"},
+		{name: "firewall vsys", session: firewall, location: []Location{VsysLocation("vsys2")}, want: device + "/vsys/entry[@name='vsys2']"},
+		{name: "firewall vsys without a name", session: firewall, location: []Location{VsysLocation("")}, wantErr: true},
+		{name: "firewall device-group", session: firewall, location: []Location{DeviceGroupLocation("branch")}, wantErr: true},
+		{name: "firewall template", session: firewall, location: []Location{TemplateLocation("branch", "")}, wantErr: true},
+		{name: "firewall template-stack", session: firewall, location: []Location{TemplateStackLocation("branch", "")}, wantErr: true},
+		{name: "Panorama default", session: panorama, wantErr: true},
+		{name: "Panorama shared", session: panorama, location: []Location{SharedLocation()}, want: "/config/shared"},
+		{name: "Panorama vsys", session: panorama, location: []Location{VsysLocation("vsys2")}, wantErr: true},
+		{
+			name:     "Panorama device-group",
+			session:  panorama,
+			location: []Location{DeviceGroupLocation("branch")},
+			want:     device + "/device-group/entry[@name='branch']",
+		},
+		{
+			name:     "Panorama device-group with a quote",
+			session:  panorama,
+			location: []Location{DeviceGroupLocation("bob's branch")},
+			want:     device + `/device-group/entry[@name="bob's branch"]`,
+		},
+		{name: "Panorama device-group without a name", session: panorama, location: []Location{DeviceGroupLocation("")}, wantErr: true},
+		{
+			name:     "Panorama template",
+			session:  panorama,
+			location: []Location{TemplateLocation("branch", "")},
+			want:     device + "/template/entry[@name='branch']" + device + "/vsys/entry[@name='vsys1']",
+		},
+		{
+			name:     "Panorama template vsys",
+			session:  panorama,
+			location: []Location{TemplateLocation("branch", "vsys2")},
+			want:     device + "/template/entry[@name='branch']" + device + "/vsys/entry[@name='vsys2']",
+		},
+		{
+			name:     "Panorama template-stack",
+			session:  panorama,
+			location: []Location{TemplateStackLocation("branches", "")},
+			want:     device + "/template-stack/entry[@name='branches']" + device + "/vsys/entry[@name='vsys1']",
+		},
+		{
+			name:     "Panorama template-stack vsys",
+			session:  panorama,
+			location: []Location{TemplateStackLocation("branches", "vsys4")},
+			want:     device + "/template-stack/entry[@name='branches']" + device + "/vsys/entry[@name='vsys4']",
+		},
+		{
+			name:     "only the first location is used",
+			session:  panorama,
+			location: []Location{SharedLocation(), DeviceGroupLocation("branch")},
+			want:     "/config/shared",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.session.locationXpath(tt.location...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulebaseXpath(t *testing.T) {
+	firewall := &PaloAlto{DeviceType: "panos"}
+	panorama := &PaloAlto{DeviceType: "panorama"}
+
+	const device = "/config/devices/entry[@name='localhost.localdomain']"
+
+	tests := []struct {
+		name     string
+		session  *PaloAlto
+		ruletype string
+		location []Location
+		want     string
+		wantErr  bool
+	}{
+		{name: "firewall", session: firewall, ruletype: "local", want: device + "/vsys/entry[@name='vsys1']/rulebase"},
+		{name: "firewall ignores the rule type", session: firewall, ruletype: "pre", want: device + "/vsys/entry[@name='vsys1']/rulebase"},
+		{
+			name:     "firewall vsys",
+			session:  firewall,
+			location: []Location{VsysLocation("vsys2")},
+			want:     device + "/vsys/entry[@name='vsys2']/rulebase",
+		},
+		{name: "firewall device-group", session: firewall, ruletype: "pre", location: []Location{DeviceGroupLocation("branch")}, wantErr: true},
+		{
+			name:     "Panorama pre-rulebase",
+			session:  panorama,
+			ruletype: "pre",
+			location: []Location{DeviceGroupLocation("branch")},
+			want:     device + "/device-group/entry[@name='branch']/pre-rulebase",
+		},
+		{
+			name:     "Panorama post-rulebase",
+			session:  panorama,
+			ruletype: "post",
+			location: []Location{DeviceGroupLocation("branch")},
+			want:     device + "/device-group/entry[@name='branch']/post-rulebase",
+		},
+		{name: "Panorama shared pre-rulebase", session: panorama, ruletype: "pre", location: []Location{SharedLocation()}, want: "/config/shared/pre-rulebase"},
+		{name: "Panorama shared post-rulebase", session: panorama, ruletype: "post", location: []Location{SharedLocation()}, want: "/config/shared/post-rulebase"},
+		{name: "Panorama local rule type", session: panorama, ruletype: "local", location: []Location{DeviceGroupLocation("branch")}, wantErr: true},
+		{name: "Panorama without a location", session: panorama, ruletype: "pre", wantErr: true},
+		{
+			name:     "Panorama template",
+			session:  panorama,
+			ruletype: "pre",
+			location: []Location{TemplateLocation("branch", "vsys2")},
+			want:     device + "/template/entry[@name='branch']" + device + "/vsys/entry[@name='vsys2']/rulebase",
+		},
+		{
+			name:     "Panorama template-stack",
+			session:  panorama,
+			location: []Location{TemplateStackLocation("branches", "")},
+			want:     device + "/template-stack/entry[@name='branches']" + device + "/vsys/entry[@name='vsys1']/rulebase",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.session.rulebaseXpath(tt.ruletype, tt.location...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
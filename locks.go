@@ -0,0 +1,197 @@
+package panos
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// lockReleaseTimeout is how long WithConfigLock waits for the device to release its lock. It is a variable so tests
+// can shorten it.
+var lockReleaseTimeout = time.Minute
+
+// Locks holds every config and commit lock on the device.
+type Locks struct {
+	Config []Lock
+	Commit []Lock
+}
+
+// Lock holds information about a single config or commit lock.
+type Lock struct {
+	// Admin is the administrator that holds the lock.
+	Admin string `xml:"name,attr"`
+
+	// Location is where the lock applies: "shared", or the name of a vsys, device-group or template.
+	Location string `xml:"vsys"`
+
+	Comment  string `xml:"comment"`
+	Created  string `xml:"created"`
+	LoggedIn string `xml:"loggedin"`
+}
+
+// xmlLocks is used for parsing the response to a show config-locks or show commit-locks command.
+type xmlLocks struct {
+	XMLName xml.Name `xml:"response"`
+	Config  []Lock   `xml:"result>config-locks>entry"`
+	Commit  []Lock   `xml:"result>commit-locks>entry"`
+}
+
+// AcquireConfigLock takes a config lock, which stops other administrators from changing the configuration until it
+// is released. The comment is shown to anyone who runs into the lock. You can (optionally) specify the location to
+// lock, such as DeviceGroupLocation("name"), TemplateLocation("name", "") or VsysLocation("vsys2"); otherwise the
+// vsys set with WithVsys is locked, or the whole device if the session has none.
+func (p *PaloAlto) AcquireConfigLock(comment string, location ...Location) error {
+	return p.AcquireConfigLockContext(context.Background(), comment, location...)
+}
+
+// AcquireConfigLockContext is the same as AcquireConfigLock, but uses ctx for all of its API requests.
+func (p *PaloAlto) AcquireConfigLockContext(ctx context.Context, comment string, location ...Location) error {
+	return p.lock(ctx, "config-lock", addLockCmd(comment), location)
+}
+
+// ReleaseConfigLock releases the config lock that the session holds. You can (optionally) specify the location, if
+// the lock was taken on one.
+func (p *PaloAlto) ReleaseConfigLock(location ...Location) error {
+	return p.ReleaseConfigLockContext(context.Background(), location...)
+}
+
+// ReleaseConfigLockContext is the same as ReleaseConfigLock, but uses ctx for all of its API requests.
+func (p *PaloAlto) ReleaseConfigLockContext(ctx context.Context, location ...Location) error {
+	return p.lock(ctx, "config-lock", "<remove></remove>", location)
+}
+
+// AcquireCommitLock takes a commit lock, which stops other administrators from committing until it is released. The
+// comment is shown to anyone who runs into the lock. You can (optionally) specify the location to lock, the same way
+// as AcquireConfigLock.
+func (p *PaloAlto) AcquireCommitLock(comment string, location ...Location) error {
+	return p.AcquireCommitLockContext(context.Background(), comment, location...)
+}
+
+// AcquireCommitLockContext is the same as AcquireCommitLock, but uses ctx for all of its API requests.
+func (p *PaloAlto) AcquireCommitLockContext(ctx context.Context, comment string, location ...Location) error {
+	return p.lock(ctx, "commit-lock", addLockCmd(comment), location)
+}
+
+// ReleaseCommitLock releases the commit lock that the session holds. You can (optionally) specify the location, if
+// the lock was taken on one.
+func (p *PaloAlto) ReleaseCommitLock(location ...Location) error {
+	return p.ReleaseCommitLockContext(context.Background(), location...)
+}
+
+// ReleaseCommitLockContext is the same as ReleaseCommitLock, but uses ctx for all of its API requests.
+func (p *PaloAlto) ReleaseCommitLockContext(ctx context.Context, location ...Location) error {
+	return p.lock(ctx, "commit-lock", "<remove></remove>", location)
+}
+
+// Locks returns every config and commit lock on the device, for all locations.
+func (p *PaloAlto) Locks() (*Locks, error) {
+	return p.LocksContext(context.Background())
+}
+
+// LocksContext is the same as Locks, but uses ctx for all of its API requests.
+func (p *PaloAlto) LocksContext(ctx context.Context) (*Locks, error) {
+	var locks Locks
+
+	for _, cmd := range []string{"<show><config-locks></config-locks></show>", "<show><commit-locks></commit-locks></show>"} {
+		var parsed xmlLocks
+
+		resp, err := p.op(ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := xml.Unmarshal([]byte(resp), &parsed); err != nil {
+			return nil, err
+		}
+
+		locks.Config = append(locks.Config, parsed.Config...)
+		locks.Commit = append(locks.Commit, parsed.Commit...)
+	}
+
+	return &locks, nil
+}
+
+// WithConfigLock takes a config lock, runs fn, and then releases the lock, even if fn fails or panics. If fn returns
+// an error, that error is returned. You can (optionally) specify the location to lock, the same way as
+// AcquireConfigLock.
+func (p *PaloAlto) WithConfigLock(fn func() error, location ...Location) error {
+	return p.WithConfigLockContext(context.Background(), fn, location...)
+}
+
+// WithConfigLockContext is the same as WithConfigLock, but uses ctx for all of its API requests. The lock is
+// released even if ctx is canceled while fn runs, but releasing it gives up after a minute, so a device that stops
+// responding can't block the caller forever.
+func (p *PaloAlto) WithConfigLockContext(ctx context.Context, fn func() error, location ...Location) (err error) {
+	if err := p.AcquireConfigLockContext(ctx, "", location...); err != nil {
+		return err
+	}
+
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), lockReleaseTimeout)
+		defer cancel()
+
+		if releaseErr := p.ReleaseConfigLockContext(releaseCtx, location...); err == nil {
+			err = releaseErr
+		}
+	}()
+
+	return fn()
+}
+
+// addLockCmd returns the command that adds a lock with the given comment.
+func addLockCmd(comment string) string {
+	if comment == "" {
+		return "<add></add>"
+	}
+
+	return fmt.Sprintf("<add><comment>%s</comment></add>", xmlEscape(comment))
+}
+
+// lock runs the given config-lock or commit-lock command, against the given location. Without a location, it runs
+// against the session's vsys, if WithVsys set one.
+func (p *PaloAlto) lock(ctx context.Context, locktype, cmd string, location []Location) error {
+	params := url.Values{"type": {"op"}, "cmd": {fmt.Sprintf("<request><%s>%s</%s></request>", locktype, cmd, locktype)}}
+
+	switch {
+	case len(location) > 0:
+		scope, err := p.lockScope(location[0])
+		if err != nil {
+			return err
+		}
+
+		params.Set("vsys", scope)
+	case p.vsys != "":
+		params.Set("vsys", p.vsys)
+	}
+
+	if _, err := p.request(ctx, params); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// lockScope returns the name that PAN-OS uses for the location of a lock: "shared", or the name of the vsys,
+// device-group or template. The default location is the session's vsys.
+func (p *PaloAlto) lockScope(l Location) (string, error) {
+	if _, err := p.locationXpath(l); err != nil {
+		return "", err
+	}
+
+	switch l.kind {
+	case locationDefault:
+		if p.vsys == "" {
+			return defaultVsys, nil
+		}
+
+		return p.vsys, nil
+	case locationShared:
+		return "shared", nil
+	case locationVsys:
+		return l.vsys, nil
+	}
+
+	return l.name, nil
+}
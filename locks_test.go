@@ -0,0 +1,284 @@
+package panos
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		acquire func(p *PaloAlto) error
+		release func(p *PaloAlto) error
+		held    func(l *Locks) []Lock
+	}{
+		{
+			name:    "config",
+			acquire: func(p *PaloAlto) error { return p.AcquireConfigLock("maintenance") },
+			release: func(p *PaloAlto) error { return p.ReleaseConfigLock() },
+			held:    func(l *Locks) []Lock { return l.Config },
+		},
+		{
+			name:    "commit",
+			acquire: func(p *PaloAlto) error { return p.AcquireCommitLock("maintenance") },
+			release: func(p *PaloAlto) error { return p.ReleaseCommitLock() },
+			held:    func(l *Locks) []Lock { return l.Commit },
+		},
+		{
+			name:    "vsys config",
+			acquire: func(p *PaloAlto) error { return p.AcquireConfigLock("maintenance", VsysLocation("vsys1")) },
+			release: func(p *PaloAlto) error { return p.ReleaseConfigLock(VsysLocation("vsys1")) },
+			held:    func(l *Locks) []Lock { return l.Config },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pan, srv := newTestSession(t)
+			defer srv.Close()
+
+			if err := tt.acquire(pan); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.acquire(pan); err == nil {
+				t.Fatal("the lock was acquired twice")
+			}
+
+			locks, err := pan.Locks()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if held := tt.held(locks); len(held) != 1 || held[0].Comment != "maintenance" {
+				t.Fatalf("got locks %+v", held)
+			}
+
+			if err := tt.release(pan); err != nil {
+				t.Fatal(err)
+			}
+
+			locks, err = pan.Locks()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if held := tt.held(locks); len(held) != 0 {
+				t.Fatalf("got locks %+v after releasing", held)
+			}
+		})
+	}
+}
+
+func TestWithConfigLock(t *testing.T) {
+	pan, srv := newTestSession(t)
+	defer srv.Close()
+
+	failed := errors.New("failed")
+	err := pan.WithConfigLock(func() error {
+		locks, err := pan.Locks()
+		if err != nil {
+			return err
+		}
+
+		if len(locks.Config) != 1 {
+			t.Errorf("got %d config locks while fn runs, want 1", len(locks.Config))
+		}
+
+		return failed
+	})
+
+	if err != failed {
+		t.Fatalf("got %v, want %v", err, failed)
+	}
+
+	locks, err := pan.Locks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(locks.Config) != 0 {
+		t.Fatal("the lock was not released")
+	}
+}
+
+func TestWithConfigLockPanic(t *testing.T) {
+	pan, srv := newTestSession(t)
+	defer srv.Close()
+
+	var mu sync.Mutex
+	released := false
+
+	handler := srv.Config.Handler
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.FormValue("cmd"), "<config-lock><remove>") {
+			mu.Lock()
+			released = true
+			mu.Unlock()
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("the panic in fn was not passed on")
+			}
+		}()
+
+		pan.WithConfigLock(func() error {
+			panic("failed")
+		})
+	}()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !released {
+		t.Fatal("no request was made to release the lock")
+	}
+
+	locks, err := pan.Locks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(locks.Config) != 0 {
+		t.Fatalf("got locks %+v after fn panicked", locks.Config)
+	}
+}
+
+func TestWithConfigLockReleaseTimeout(t *testing.T) {
+	timeout := lockReleaseTimeout
+	lockReleaseTimeout = 50 * time.Millisecond
+	defer func() { lockReleaseTimeout = timeout }()
+
+	// The device takes the lock, then never answers the request to release it.
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+
+		if strings.Contains(r.PostForm.Get("cmd"), "<remove>") {
+			<-r.Context().Done()
+			return nil, r.Context().Err()
+		}
+
+		body := `<response status="success"><result>Successfully acquired lock</result></response>`
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body)), Request: r}, nil
+	})
+
+	pan := &PaloAlto{Host: "fw", URI: "https://fw/api/?", DeviceType: "panos", client: &http.Client{Transport: transport}}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pan.WithConfigLock(func() error { return nil })
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WithConfigLock is still waiting for the lock to be released")
+	}
+}
+
+func TestLockScope(t *testing.T) {
+	firewall := &PaloAlto{DeviceType: "panos"}
+	panorama := &PaloAlto{DeviceType: "panorama"}
+
+	tests := []struct {
+		name     string
+		session  *PaloAlto
+		location Location
+		want     string
+		wantErr  bool
+	}{
+		{name: "firewall default", session: firewall, want: "vsys1"},
+		{name: "firewall default with WithVsys", session: firewall.WithVsys("vsys3"), want: "vsys3"},
+		{name: "firewall shared", session: firewall, location: SharedLocation(), want: "shared"},
+		{name: "firewall vsys", session: firewall, location: VsysLocation("vsys2"), want: "vsys2"},
+		{name: "firewall device-group", session: firewall, location: DeviceGroupLocation("branch"), wantErr: true},
+		{name: "Panorama default", session: panorama, wantErr: true},
+		{name: "Panorama shared", session: panorama, location: SharedLocation(), want: "shared"},
+		{name: "Panorama device-group", session: panorama, location: DeviceGroupLocation("branch"), want: "branch"},
+		{name: "Panorama template", session: panorama, location: TemplateLocation("branch-template", ""), want: "branch-template"},
+		{name: "Panorama template-stack", session: panorama, location: TemplateStackLocation("branches", ""), want: "branches"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.session.lockScope(tt.location)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockVsys(t *testing.T) {
+	tests := []struct {
+		name     string
+		vsys     string
+		location []Location
+		want     string
+	}{
+		{name: "device", want: ""},
+		{name: "WithVsys", vsys: "vsys3", want: "vsys3"},
+		{name: "vsys location", location: []Location{VsysLocation("vsys2")}, want: "vsys2"},
+		{name: "WithVsys and shared location", vsys: "vsys3", location: []Location{SharedLocation()}, want: "shared"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pan, srv := newTestSession(t)
+			defer srv.Close()
+
+			var mu sync.Mutex
+			var got []string
+
+			handler := srv.Config.Handler
+			srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.Contains(r.FormValue("cmd"), "<config-lock>") {
+					mu.Lock()
+					got = append(got, r.FormValue("vsys"))
+					mu.Unlock()
+				}
+
+				handler.ServeHTTP(w, r)
+			})
+
+			if tt.vsys != "" {
+				pan = pan.WithVsys(tt.vsys)
+			}
+
+			if err := pan.AcquireConfigLock("maintenance", tt.location...); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := pan.ReleaseConfigLock(tt.location...); err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if len(got) != 2 || got[0] != tt.want || got[1] != tt.want {
+				t.Fatalf("got vsys %q, want %q for both requests", got, tt.want)
+			}
+		})
+	}
+}
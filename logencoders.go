@@ -0,0 +1,374 @@
+package panos
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LogEncoder writes logs to a stream, in a format such as NDJSON, CSV or Parquet. Create one with NewNDJSONEncoder,
+// NewCSVEncoder or NewParquetEncoder, pass it the logs as they come in, and call Close once you're done:
+//
+//	enc, err := panos.NewCSVEncoder(f, &panos.TrafficLog{}, "receive_time", "src", "dst", "app", "action")
+//	if err != nil {
+//		return err
+//	}
+//
+//	for logs.Next() {
+//		if err := enc.Encode(logs.Record()); err != nil {
+//			return err
+//		}
+//	}
+//
+//	if err := enc.Close(); err != nil {
+//		return err
+//	}
+//
+// The columns are the fields of the LogRecord types, named after their PAN-OS field names (the log tags), such as
+// "src" for Source or "logid" for ID. If no columns are given, all of them are written, in the order of the fields of
+// the type, starting with those of LogHeader. Times are written as "2006/01/02 15:04:05" in NDJSON and CSV, and
+// durations as a number of seconds, the same as PAN-OS does.
+//
+// The column names are the same as the xml tags of Log, so the Logs returned by RetrieveLogs can be written too, by
+// converting them with Log.Record or LogRecords.
+type LogEncoder interface {
+	// Encode writes the given logs.
+	Encode(records ...LogRecord) error
+
+	// Close writes anything that is still buffered, such as the footer of a Parquet file. It does not close the
+	// underlying writer.
+	Close() error
+}
+
+// logColumn is a field of a LogRecord type that can be written by a LogEncoder.
+type logColumn struct {
+	name string
+	typ  reflect.Type
+
+	// index is the index sequence of the field in the record's struct, or nil for a field in the Fields of an
+	// OtherLog.
+	index []int
+}
+
+var (
+	ipType       = reflect.TypeOf(net.IP{})
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	otherLogType = reflect.TypeOf(&OtherLog{})
+
+	// recordColumnCache holds the columns of each LogRecord type, by its pointer type, once they have been looked up.
+	recordColumnCache sync.Map
+)
+
+// recordColumns returns every field of the LogRecord type t that has a log tag, including those of embedded structs,
+// in order. Where two fields have the same tag, the first one is used.
+func recordColumns(t reflect.Type) []logColumn {
+	if columns, ok := recordColumnCache.Load(t); ok {
+		return columns.([]logColumn)
+	}
+
+	columns := addRecordColumns(nil, t.Elem(), nil)
+	recordColumnCache.Store(t, columns)
+
+	return columns
+}
+
+// addRecordColumns adds the fields of the struct type t, whose index sequence starts with index, to columns.
+func addRecordColumns(columns []logColumn, t reflect.Type, index []int) []logColumn {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			columns = addRecordColumns(columns, f.Type, fieldIndex)
+			continue
+		}
+
+		name := f.Tag.Get("log")
+		if name == "" || findLogColumn(columns, name) != nil {
+			continue
+		}
+
+		columns = append(columns, logColumn{name: name, typ: f.Type, index: fieldIndex})
+	}
+
+	return columns
+}
+
+// findLogColumn returns the column with the given name, or nil if there isn't one.
+func findLogColumn(columns []logColumn, name string) *logColumn {
+	for i := range columns {
+		if columns[i].name == name {
+			return &columns[i]
+		}
+	}
+
+	return nil
+}
+
+// selectLogColumns returns the columns with the given names of the type of record, or all of them if none are given.
+// Any name can be used with an OtherLog, since its fields are not known in advance.
+func selectLogColumns(record LogRecord, names []string) ([]logColumn, error) {
+	t := reflect.TypeOf(record)
+	if record == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("you must specify the type of the logs to write, such as &panos.TrafficLog{}")
+	}
+
+	all := recordColumns(t)
+	if len(names) == 0 {
+		return all, nil
+	}
+
+	columns := make([]logColumn, 0, len(names))
+	for _, name := range names {
+		c := findLogColumn(all, name)
+
+		switch {
+		case c != nil:
+			columns = append(columns, *c)
+		case t == otherLogType:
+			columns = append(columns, logColumn{name: name, typ: reflect.TypeOf("")})
+		default:
+			return nil, fmt.Errorf("%q is not a column of %s", name, t.Elem().Name())
+		}
+	}
+
+	return columns, nil
+}
+
+// value returns the value of the column in record. It returns the zero Value for a field that an OtherLog doesn't
+// have.
+func (c logColumn) value(record LogRecord) reflect.Value {
+	if c.index == nil {
+		value, ok := record.(*OtherLog).Fields[c.name]
+		if !ok {
+			return reflect.Value{}
+		}
+
+		return reflect.ValueOf(value)
+	}
+
+	return reflect.ValueOf(record).Elem().FieldByIndex(c.index)
+}
+
+// null reports whether v, a value of the column, is missing: a field that an OtherLog doesn't have, or an address or
+// time that was not in the log.
+func (c logColumn) null(v reflect.Value) bool {
+	switch {
+	case !v.IsValid():
+		return true
+	case c.typ == ipType:
+		return v.Len() == 0
+	case c.typ == timeType:
+		return v.Interface().(time.Time).IsZero()
+	}
+
+	return false
+}
+
+// numeric reports whether the column holds numbers, as opposed to text.
+func (c logColumn) numeric() bool {
+	return c.typ != timeType && c.typ != ipType && c.typ.Kind() != reflect.String
+}
+
+// number returns v, a value of a numeric or time column, as an integer. Durations are in seconds, and times in
+// milliseconds since the epoch.
+func (c logColumn) number(v reflect.Value) int64 {
+	switch c.typ {
+	case durationType:
+		return v.Int() / int64(time.Second)
+	case timeType:
+		return v.Interface().(time.Time).UnixNano() / int64(time.Millisecond)
+	}
+
+	return v.Int()
+}
+
+// text returns v, a value of the column, as text.
+func (c logColumn) text(v reflect.Value) string {
+	switch {
+	case c.null(v):
+		return ""
+	case c.typ == timeType:
+		return v.Interface().(time.Time).Format(logTimeFormat)
+	case c.typ == ipType:
+		return v.Interface().(net.IP).String()
+	case c.numeric():
+		return strconv.FormatInt(c.number(v), 10)
+	}
+
+	return v.String()
+}
+
+// ndjsonEncoder writes logs as newline-delimited JSON.
+type ndjsonEncoder struct {
+	w     io.Writer
+	names []string
+}
+
+// NewNDJSONEncoder returns a LogEncoder that writes each log to w as a JSON object on a line of its own, with the
+// given columns as keys. Logs of any type can be written; each one gets the columns that its type has, so a column
+// that a log's type doesn't have is left out of it, as are addresses and times that were not in the log. Numbers are
+// written as JSON numbers, and everything else as strings.
+//
+// Without any columns, an OtherLog is written with every field that it has.
+func NewNDJSONEncoder(w io.Writer, columns ...string) LogEncoder {
+	return &ndjsonEncoder{w: w, names: columns}
+}
+
+// Encode writes the given logs, one line each.
+func (e *ndjsonEncoder) Encode(records ...LogRecord) error {
+	var buf bytes.Buffer
+
+	for _, r := range records {
+		buf.WriteByte('{')
+
+		first := true
+		for _, c := range e.columns(r) {
+			v := c.value(r)
+			if c.null(v) {
+				continue
+			}
+
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+
+			key, _ := json.Marshal(c.name)
+			buf.Write(key)
+			buf.WriteByte(':')
+
+			if c.numeric() {
+				buf.WriteString(strconv.FormatInt(c.number(v), 10))
+			} else {
+				value, _ := json.Marshal(c.text(v))
+				buf.Write(value)
+			}
+		}
+
+		buf.WriteString("}\n")
+	}
+
+	_, err := e.w.Write(buf.Bytes())
+
+	return err
+}
+
+// columns returns the columns to write for record: those of the encoder that its type has, or all of them.
+func (e *ndjsonEncoder) columns(record LogRecord) []logColumn {
+	all := recordColumns(reflect.TypeOf(record))
+	other, isOther := record.(*OtherLog)
+
+	if len(e.names) == 0 {
+		if !isOther {
+			return all
+		}
+
+		var names []string
+		for name := range other.Fields {
+			if findLogColumn(all, name) == nil {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		columns := append([]logColumn{}, all...)
+		for _, name := range names {
+			columns = append(columns, logColumn{name: name, typ: reflect.TypeOf("")})
+		}
+
+		return columns
+	}
+
+	columns := make([]logColumn, 0, len(e.names))
+	for _, name := range e.names {
+		if c := findLogColumn(all, name); c != nil {
+			columns = append(columns, *c)
+		} else if isOther {
+			columns = append(columns, logColumn{name: name, typ: reflect.TypeOf("")})
+		}
+	}
+
+	return columns
+}
+
+// Close does nothing, since every log is written as soon as it is encoded.
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
+
+// csvEncoder writes logs as CSV.
+type csvEncoder struct {
+	w       *csv.Writer
+	typ     reflect.Type
+	columns []logColumn
+	header  bool
+}
+
+// NewCSVEncoder returns a LogEncoder that writes logs of the same type as record (such as &panos.TrafficLog{}) to w
+// as CSV, with a header row holding the names of the given columns. The header is written along with the first logs,
+// or by Close if there are none. Addresses and times that were not in a log are written as empty fields.
+func NewCSVEncoder(w io.Writer, record LogRecord, columns ...string) (LogEncoder, error) {
+	c, err := selectLogColumns(record, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	return &csvEncoder{w: csv.NewWriter(w), typ: reflect.TypeOf(record), columns: c}, nil
+}
+
+// Encode writes the given logs, one row each.
+func (e *csvEncoder) Encode(records ...LogRecord) error {
+	e.writeHeader()
+
+	row := make([]string, len(e.columns))
+	for _, r := range records {
+		if reflect.TypeOf(r) != e.typ {
+			e.w.Flush()
+			return fmt.Errorf("the encoder writes %s logs, not %T", e.typ.Elem().Name(), r)
+		}
+
+		for j, c := range e.columns {
+			row[j] = c.text(c.value(r))
+		}
+
+		e.w.Write(row)
+	}
+
+	e.w.Flush()
+
+	return e.w.Error()
+}
+
+// Close writes the header, if no logs were encoded.
+func (e *csvEncoder) Close() error {
+	e.writeHeader()
+	e.w.Flush()
+
+	return e.w.Error()
+}
+
+// writeHeader writes the header row, if it hasn't been written yet.
+func (e *csvEncoder) writeHeader() {
+	if e.header {
+		return
+	}
+
+	names := make([]string, len(e.columns))
+	for i, c := range e.columns {
+		names[i] = c.name
+	}
+
+	e.w.Write(names)
+	e.header = true
+}
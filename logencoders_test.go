@@ -0,0 +1,211 @@
+package panos
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/scottdware/go-panos/panostest"
+)
+
+func TestNDJSONEncoder(t *testing.T) {
+	received := time.Date(2026, 1, 1, 0, 0, 1, 0, time.UTC)
+
+	records := []LogRecord{
+		&TrafficLog{
+			LogHeader:   LogHeader{ReceiveTime: received},
+			SessionInfo: SessionInfo{Source: net.ParseIP("10.1.1.10"), DestinationPort: 443, Application: "ssl"},
+			Elapsed:     90 * time.Second,
+		},
+		&SystemLog{LogHeader: LogHeader{ReceiveTime: received}, Severity: "high"},
+		&OtherLog{LogHeader: LogHeader{Type: "AUTH"}, Fields: map[string]string{"type": "AUTH", "src_user": "jdoe"}},
+	}
+
+	tests := []struct {
+		name    string
+		columns []string
+		want    string
+	}{
+		{
+			name:    "columns",
+			columns: []string{"receive_time", "src", "dst", "dport", "app", "elapsed", "severity", "src_user"},
+			want: `{"receive_time":"2026/01/01 00:00:01","src":"10.1.1.10","dport":443,"app":"ssl","elapsed":90}` + "\n" +
+				`{"receive_time":"2026/01/01 00:00:01","severity":"high"}` + "\n" +
+				`{"src_user":"jdoe"}` + "\n",
+		},
+		{
+			name:    "shared and missing columns",
+			columns: []string{"type", "missing"},
+			want:    `{"type":""}` + "\n" + `{"type":""}` + "\n" + `{"type":"AUTH"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			enc := NewNDJSONEncoder(&buf, tt.columns...)
+			if err := enc.Encode(records...); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Encode wrote\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNDJSONEncoderAllColumns(t *testing.T) {
+	var buf bytes.Buffer
+
+	enc := NewNDJSONEncoder(&buf)
+	if err := enc.Encode(&OtherLog{LogHeader: LogHeader{Type: "AUTH"}, Fields: map[string]string{"type": "AUTH", "src_user": "jdoe"}}); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	want := `{"logid":0,"serial":"","device_name":"","vsys":"","type":"AUTH","subtype":"","seqno":0,"actionflags":"",` +
+		`"dg_hier_level_1":0,"dg_hier_level_2":0,"dg_hier_level_3":0,"dg_hier_level_4":0,"src_user":"jdoe"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Encode wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCSVEncoder(t *testing.T) {
+	var buf bytes.Buffer
+
+	enc, err := NewCSVEncoder(&buf, &TrafficLog{}, "receive_time", "src", "dst", "app", "elapsed")
+	if err != nil {
+		t.Fatalf("NewCSVEncoder: %v", err)
+	}
+
+	err = enc.Encode(&TrafficLog{
+		LogHeader:   LogHeader{ReceiveTime: time.Date(2026, 1, 1, 0, 0, 1, 0, time.UTC)},
+		SessionInfo: SessionInfo{Source: net.ParseIP("10.1.1.10"), Application: "web, browsing"},
+		Elapsed:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	if err := enc.Encode(&ThreatLog{}); err == nil {
+		t.Error("Encode of a ThreatLog with a TrafficLog encoder did not fail")
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	want := "receive_time,src,dst,app,elapsed\n2026/01/01 00:00:01,10.1.1.10,,\"web, browsing\",5\n"
+	if got := buf.String(); got != want {
+		t.Errorf("the encoder wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLogRecords(t *testing.T) {
+	pan, srv := newTestSession(t,
+		panostest.WithLogs("traffic",
+			"<type>TRAFFIC</type><subtype>end</subtype><receive_time>2026/01/01 00:00:01</receive_time>"+
+				"<src>10.1.1.10</src><dst>10.2.2.20</dst><dport>443</dport><app>ssl</app><elapsed>90</elapsed>",
+			"<type>TRAFFIC</type><subtype>deny</subtype><src>10.1.1.11</src><dst>10.2.2.21</dst><dport>22</dport><app>ssh</app>",
+		),
+		panostest.WithLogs("system", "<type>SYSTEM</type><subtype>general</subtype><severity>high</severity>"),
+	)
+	defer srv.Close()
+
+	var logs []Log
+	var want []LogRecord
+	for _, logtype := range []string{"traffic", "system"} {
+		id, err := pan.QueryLogs(logtype, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := pan.WaitForLogs(context.Background(), id, time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		logs = append(logs, result.Logs...)
+		want = append(want, result.Records...)
+	}
+
+	records := LogRecords(logs)
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("got records %+v, want %+v", records, want)
+	}
+
+	var buf bytes.Buffer
+
+	enc, err := NewCSVEncoder(&buf, &TrafficLog{}, "logid", "receive_time", "src", "dport", "app", "elapsed")
+	if err != nil {
+		t.Fatalf("NewCSVEncoder: %v", err)
+	}
+
+	if err := enc.Encode(records[:2]...); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	csv := "logid,receive_time,src,dport,app,elapsed\n1,2026/01/01 00:00:01,10.1.1.10,443,ssl,90\n2,,10.1.1.11,22,ssh,0\n"
+	if got := buf.String(); got != csv {
+		t.Errorf("the encoder wrote\n%s\nwant\n%s", got, csv)
+	}
+
+	buf.Reset()
+
+	if err := NewNDJSONEncoder(&buf, "src", "app", "severity").Encode(logs[2].Record()); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	if got, ndjson := buf.String(), `{"severity":"high"}`+"\n"; got != ndjson {
+		t.Errorf("the encoder wrote %s, want %s", got, ndjson)
+	}
+}
+
+func TestCSVEncoderNoLogs(t *testing.T) {
+	var buf bytes.Buffer
+
+	enc, err := NewCSVEncoder(&buf, &SystemLog{}, "eventid", "severity")
+	if err != nil {
+		t.Fatalf("NewCSVEncoder: %v", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got := buf.String(); got != "eventid,severity\n" {
+		t.Errorf("the encoder wrote %q", got)
+	}
+}
+
+func TestSelectLogColumnsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		record  LogRecord
+		columns []string
+	}{
+		{name: "no record type", columns: []string{"src"}},
+		{name: "unknown column", record: &TrafficLog{}, columns: []string{"src", "threatid"}},
+		{name: "column of another type", record: &SystemLog{}, columns: []string{"bytes"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCSVEncoder(&bytes.Buffer{}, tt.record, tt.columns...); err == nil {
+				t.Error("NewCSVEncoder did not fail")
+			}
+
+			if _, err := NewParquetEncoder(&bytes.Buffer{}, tt.record, tt.columns...); err == nil {
+				t.Error("NewParquetEncoder did not fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+package panos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// logTimeFormat is how times are written in log queries.
+const logTimeFormat = "2006/01/02 15:04:05"
+
+// LogFilterField is a field that a log query can match on, such as "app" or "addr.src". LogField holds the common
+// ones, and any other field can be used by converting its name, e.g. LogFilterField("flags").
+type LogFilterField string
+
+// LogEndpointField is a field that is found on both the source and destination side of a log, such as an address.
+// Use Src or Dst to match one side, or the field itself to match either side.
+type LogEndpointField struct {
+	Src LogFilterField
+	Dst LogFilterField
+}
+
+// LogFilterFields is the type of LogField.
+type LogFilterFields struct {
+	// Addr matches the source (addr.src) or destination (addr.dst) address, e.g. LogField.Addr.Src.In("10.1.1.0/24").
+	Addr LogEndpointField
+
+	// Port matches the source (port.src) or destination (port.dst) port, e.g. LogField.Port.Dst.Eq(443).
+	Port LogEndpointField
+
+	// Zone matches the source (zone.src) or destination (zone.dst) zone.
+	Zone LogEndpointField
+
+	// User matches the source (user.src) or destination (user.dst) user.
+	User LogEndpointField
+
+	// These match a single field of a log, e.g. LogField.Rule.Eq("allow-web").
+	App              LogFilterField
+	Rule             LogFilterField
+	Action           LogFilterField
+	Severity         LogFilterField
+	Subtype          LogFilterField
+	Category         LogFilterField
+	Protocol         LogFilterField
+	SessionEndReason LogFilterField
+	DeviceName       LogFilterField
+	SerialNumber     LogFilterField
+	VsysName         LogFilterField
+	Admin            LogFilterField
+	EventID          LogFilterField
+	ReceiveTime      LogFilterField
+	TimeGenerated    LogFilterField
+}
+
+// LogField holds the fields that log queries most often match on, e.g. LogField.App.Eq("ssl").
+var LogField = LogFilterFields{
+	Addr:             LogEndpointField{Src: "addr.src", Dst: "addr.dst"},
+	Port:             LogEndpointField{Src: "port.src", Dst: "port.dst"},
+	Zone:             LogEndpointField{Src: "zone.src", Dst: "zone.dst"},
+	User:             LogEndpointField{Src: "user.src", Dst: "user.dst"},
+	App:              "app",
+	Rule:             "rule",
+	Action:           "action",
+	Severity:         "severity",
+	Subtype:          "subtype",
+	Category:         "category",
+	Protocol:         "proto",
+	SessionEndReason: "session_end_reason",
+	DeviceName:       "device_name",
+	SerialNumber:     "serial",
+	VsysName:         "vsys",
+	Admin:            "admin",
+	EventID:          "eventid",
+	ReceiveTime:      "receive_time",
+	TimeGenerated:    "time_generated",
+}
+
+// LogFilter is a log query, built from conditions on log fields, such as:
+//
+//	LogField.Addr.Src.In("10.1.1.0/24").And(LogField.Port.Dst.Eq(443), LogField.App.Neq("ssl"))
+//
+// Its String method renders it in the same syntax as the filters in the web interface, which is what
+// LogParameters.Query expects. Set LogParameters.Filter to use one directly. ParseLogFilter turns a query back into
+// a LogFilter.
+type LogFilter struct {
+	// op is "and", "or" or "not" for a group of filters, and the operator (e.g. "eq") for a condition.
+	op      string
+	field   LogFilterField
+	value   string
+	filters []*LogFilter
+}
+
+// condition returns a filter that compares the field to value. Times are written the way log queries expect them,
+// and anything else is written with fmt.Sprint.
+func (f LogFilterField) condition(op string, value interface{}) *LogFilter {
+	if t, ok := value.(time.Time); ok {
+		return &LogFilter{op: op, field: f, value: t.Format(logTimeFormat)}
+	}
+
+	return &LogFilter{op: op, field: f, value: fmt.Sprint(value)}
+}
+
+// Eq matches logs where the field equals value.
+func (f LogFilterField) Eq(value interface{}) *LogFilter {
+	return f.condition("eq", value)
+}
+
+// Neq matches logs where the field does not equal value.
+func (f LogFilterField) Neq(value interface{}) *LogFilter {
+	return f.condition("neq", value)
+}
+
+// In matches logs where the field is in value, such as an address in a subnet or range.
+func (f LogFilterField) In(value interface{}) *LogFilter {
+	return f.condition("in", value)
+}
+
+// NotIn matches logs where the field is not in value.
+func (f LogFilterField) NotIn(value interface{}) *LogFilter {
+	return f.condition("notin", value)
+}
+
+// Geq matches logs where the field is greater than or equal to value.
+func (f LogFilterField) Geq(value interface{}) *LogFilter {
+	return f.condition("geq", value)
+}
+
+// Leq matches logs where the field is less than or equal to value.
+func (f LogFilterField) Leq(value interface{}) *LogFilter {
+	return f.condition("leq", value)
+}
+
+// Contains matches logs where the field contains value.
+func (f LogFilterField) Contains(value interface{}) *LogFilter {
+	return f.condition("contains", value)
+}
+
+// Between matches logs where a time field, such as LogField.ReceiveTime, is from start to end, inclusive. The times are
+// written as they are, without converting them to the device's time zone, so convert them first (e.g. with their In
+// method) if they are in a different one.
+func (f LogFilterField) Between(start, end time.Time) *LogFilter {
+	return f.Geq(start).And(f.Leq(end))
+}
+
+// Eq matches logs where either side equals value.
+func (f LogEndpointField) Eq(value interface{}) *LogFilter {
+	return f.Src.Eq(value).Or(f.Dst.Eq(value))
+}
+
+// Neq matches logs where neither side equals value.
+func (f LogEndpointField) Neq(value interface{}) *LogFilter {
+	return f.Src.Neq(value).And(f.Dst.Neq(value))
+}
+
+// In matches logs where either side is in value.
+func (f LogEndpointField) In(value interface{}) *LogFilter {
+	return f.Src.In(value).Or(f.Dst.In(value))
+}
+
+// NotIn matches logs where neither side is in value.
+func (f LogEndpointField) NotIn(value interface{}) *LogFilter {
+	return f.Src.NotIn(value).And(f.Dst.NotIn(value))
+}
+
+// And returns a filter that matches logs that match f and every one of filters.
+func (f *LogFilter) And(filters ...*LogFilter) *LogFilter {
+	return group("and", append([]*LogFilter{f}, filters...))
+}
+
+// Or returns a filter that matches logs that match f or any one of filters.
+func (f *LogFilter) Or(filters ...*LogFilter) *LogFilter {
+	return group("or", append([]*LogFilter{f}, filters...))
+}
+
+// Not returns a filter that matches logs that don't match f.
+func (f *LogFilter) Not() *LogFilter {
+	return &LogFilter{op: "not", filters: []*LogFilter{f}}
+}
+
+// group returns filters joined by op, taking the place of any that are already joined by it, so a.And(b).And(c)
+// is the same as a.And(b, c).
+func group(op string, filters []*LogFilter) *LogFilter {
+	g := &LogFilter{op: op}
+
+	for _, f := range filters {
+		if f.op == op {
+			g.filters = append(g.filters, f.filters...)
+			continue
+		}
+
+		g.filters = append(g.filters, f)
+	}
+
+	return g
+}
+
+// String returns the filter as a log query, e.g. "(addr.src in 10.1.1.0/24) and (app neq ssl)".
+func (f *LogFilter) String() string {
+	switch f.op {
+	case "and", "or":
+		var parts []string
+		for _, c := range f.filters {
+			parts = append(parts, c.operand())
+		}
+
+		return strings.Join(parts, " "+f.op+" ")
+	case "not":
+		return "!" + f.filters[0].operand()
+	}
+
+	return fmt.Sprintf("(%s %s %s)", f.field, f.op, quoteLogValue(f.value))
+}
+
+// operand returns the filter as part of a larger one, which means putting a group in parentheses.
+func (f *LogFilter) operand() string {
+	if f.op == "and" || f.op == "or" {
+		return "(" + f.String() + ")"
+	}
+
+	return f.String()
+}
+
+// quoteLogValue puts value in quotes, if it has anything in it that would otherwise end it early.
+func quoteLogValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t()'\"!") {
+		return value
+	}
+
+	if !strings.Contains(value, "'") {
+		return "'" + value + "'"
+	}
+
+	if !strings.Contains(value, "\"") {
+		return "\"" + value + "\""
+	}
+
+	return "'" + strings.Replace(value, "'", "\\'", -1) + "'"
+}
+
+// ParseLogFilter parses a log query, such as "(addr.src in 10.1.1.0/24) and !(app eq ssl)", into a LogFilter. Where
+// a query mixes "and" and "or" without parentheses, "and" is applied first. Calling String on the result gives back
+// the same query, apart from spacing and redundant parentheses.
+func ParseLogFilter(query string) (*LogFilter, error) {
+	l := &logFilterParser{query: query}
+
+	f, err := l.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if l.skipSpace(); l.pos < len(l.query) {
+		return nil, l.errorf("unexpected %q", l.query[l.pos:])
+	}
+
+	return f, nil
+}
+
+// logFilterParser holds the state of ParseLogFilter.
+type logFilterParser struct {
+	query string
+	pos   int
+}
+
+// errorf returns an error that says where in the query parsing failed.
+func (l *logFilterParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid log query at position %d: %s", l.pos+1, fmt.Sprintf(format, args...))
+}
+
+// skipSpace moves past any whitespace.
+func (l *logFilterParser) skipSpace() {
+	for l.pos < len(l.query) && strings.ContainsRune(" \t\r\n", rune(l.query[l.pos])) {
+		l.pos++
+	}
+}
+
+// keyword moves past the given word (e.g. "and"), in any case, if it comes next.
+func (l *logFilterParser) keyword(word string) bool {
+	l.skipSpace()
+
+	end := l.pos + len(word)
+	if end >= len(l.query) || !strings.EqualFold(l.query[l.pos:end], word) || !strings.ContainsRune(" \t\r\n(!", rune(l.query[end])) {
+		return false
+	}
+
+	l.pos = end
+
+	return true
+}
+
+// or parses operands joined by "or".
+func (l *logFilterParser) or() (*LogFilter, error) {
+	return l.joined("or", l.and)
+}
+
+// and parses operands joined by "and".
+func (l *logFilterParser) and() (*LogFilter, error) {
+	return l.joined("and", l.operand)
+}
+
+// joined parses one or more of what next parses, joined by op.
+func (l *logFilterParser) joined(op string, next func() (*LogFilter, error)) (*LogFilter, error) {
+	f, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []*LogFilter{f}
+	for l.keyword(op) {
+		f, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, f)
+	}
+
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+
+	return group(op, filters), nil
+}
+
+// operand parses a condition, a negated operand, or a group in parentheses.
+func (l *logFilterParser) operand() (*LogFilter, error) {
+	l.skipSpace()
+
+	if l.pos >= len(l.query) {
+		return nil, l.errorf("expected a condition")
+	}
+
+	if l.query[l.pos] == '!' {
+		l.pos++
+
+		f, err := l.operand()
+		if err != nil {
+			return nil, err
+		}
+
+		return f.Not(), nil
+	}
+
+	if l.query[l.pos] != '(' {
+		return nil, l.errorf("expected ( or !")
+	}
+
+	l.pos++
+	l.skipSpace()
+
+	var f *LogFilter
+	var err error
+
+	if l.pos < len(l.query) && (l.query[l.pos] == '(' || l.query[l.pos] == '!') {
+		f, err = l.or()
+	} else {
+		f, err = l.condition()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if l.skipSpace(); l.pos >= len(l.query) || l.query[l.pos] != ')' {
+		return nil, l.errorf("expected )")
+	}
+
+	l.pos++
+
+	return f, nil
+}
+
+// condition parses the inside of a condition, such as "app eq ssl".
+func (l *logFilterParser) condition() (*LogFilter, error) {
+	field := l.word()
+	if field == "" {
+		return nil, l.errorf("expected a field name")
+	}
+
+	op := strings.ToLower(l.word())
+	if op == "" {
+		return nil, l.errorf("expected an operator after %s", field)
+	}
+
+	l.skipSpace()
+
+	value, err := l.value()
+	if err != nil {
+		return nil, err
+	}
+
+	return &LogFilter{op: op, field: LogFilterField(field), value: value}, nil
+}
+
+// word returns the next run of characters up to whitespace or a parenthesis.
+func (l *logFilterParser) word() string {
+	l.skipSpace()
+
+	start := l.pos
+	for l.pos < len(l.query) && !strings.ContainsRune(" \t\r\n()", rune(l.query[l.pos])) {
+		l.pos++
+	}
+
+	return l.query[start:l.pos]
+}
+
+// value returns the value of a condition, which is either quoted, or runs up to the closing parenthesis.
+func (l *logFilterParser) value() (string, error) {
+	if l.pos >= len(l.query) {
+		return "", l.errorf("expected a value")
+	}
+
+	quote := l.query[l.pos]
+	if quote != '\'' && quote != '"' {
+		start := l.pos
+		for l.pos < len(l.query) && l.query[l.pos] != ')' {
+			l.pos++
+		}
+
+		value := strings.TrimSpace(l.query[start:l.pos])
+		if value == "" {
+			return "", l.errorf("expected a value")
+		}
+
+		return value, nil
+	}
+
+	var value strings.Builder
+	for l.pos++; l.pos < len(l.query); l.pos++ {
+		switch c := l.query[l.pos]; {
+		case c == quote:
+			l.pos++
+			return value.String(), nil
+		case c == '\\' && l.pos+1 < len(l.query) && l.query[l.pos+1] == quote:
+			l.pos++
+			value.WriteByte(quote)
+		default:
+			value.WriteByte(c)
+		}
+	}
+
+	return "", l.errorf("missing closing %c", quote)
+}
@@ -0,0 +1,89 @@
+package panos
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogFilterString(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		filter *LogFilter
+		want   string
+	}{
+		{name: "rule", filter: LogField.Rule.Eq("allow-web"), want: "(rule eq allow-web)"},
+		{name: "endpoint", filter: LogField.Addr.Src.In("10.1.1.0/24"), want: "(addr.src in 10.1.1.0/24)"},
+		{name: "either endpoint", filter: LogField.Zone.Eq("trust"), want: "(zone.src eq trust) or (zone.dst eq trust)"},
+		{name: "quoted", filter: LogField.User.Src.Eq("corp\\j doe"), want: "(user.src eq 'corp\\j doe')"},
+		{name: "custom field", filter: LogFilterField("flags").Contains("nat"), want: "(flags contains nat)"},
+		{
+			name:   "and",
+			filter: LogField.Addr.Src.In("10.1.1.0/24").And(LogField.Port.Dst.Eq(443), LogField.App.Neq("ssl")),
+			want:   "(addr.src in 10.1.1.0/24) and (port.dst eq 443) and (app neq ssl)",
+		},
+		{
+			name:   "or inside and",
+			filter: LogField.Action.Eq("deny").And(LogField.Severity.Eq("high").Or(LogField.Severity.Eq("critical"))),
+			want:   "(action eq deny) and ((severity eq high) or (severity eq critical))",
+		},
+		{name: "not", filter: LogField.App.Eq("dns").Not(), want: "!(app eq dns)"},
+		{
+			name:   "between",
+			filter: LogField.ReceiveTime.Between(start, end),
+			want:   "(receive_time geq '2026/01/01 00:00:00') and (receive_time leq '2026/01/01 01:00:00')",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.filter.String()
+			if got != tt.want {
+				t.Fatalf("String() = %q, want %q", got, tt.want)
+			}
+
+			parsed, err := ParseLogFilter(got)
+			if err != nil {
+				t.Fatalf("ParseLogFilter(%q): %v", got, err)
+			}
+
+			if parsed.String() != got {
+				t.Errorf("ParseLogFilter(%q).String() = %q", got, parsed.String())
+			}
+		})
+	}
+}
+
+func TestParseLogFilterErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"(app eq ssl",
+		"(app eq ssl))",
+		"(app ssl)",
+		"(app eq 'ssl)",
+		"(app eq ssl) and",
+		"(app eq ssl) xor (app eq dns)",
+	}
+
+	for _, query := range tests {
+		if f, err := ParseLogFilter(query); err == nil {
+			t.Errorf("ParseLogFilter(%q) = %q, want an error", query, f)
+		}
+	}
+}
+
+func TestQueryLogsQueryAndFilter(t *testing.T) {
+	pan, srv := newTestSession(t)
+	defer srv.Close()
+
+	_, err := pan.QueryLogs("traffic", &LogParameters{Query: "(app eq ssl)", Filter: LogField.App.Eq("ssl")})
+	if err == nil {
+		t.Fatal("QueryLogs with both Query and Filter did not fail")
+	}
+
+	if _, err := pan.QueryLogs("traffic", &LogParameters{Filter: LogField.App.Eq("ssl")}); err != nil {
+		t.Fatalf("QueryLogs with Filter: %v", err)
+	}
+}
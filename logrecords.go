@@ -0,0 +1,434 @@
+package panos
+
+import (
+	"encoding/xml"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogRecord is a single log, as the type that matches its log type: *TrafficLog, *ThreatLog, *URLLog, *WildfireLog,
+// *DataLog, *SystemLog, *ConfigLog, *HIPMatchLog, *GlobalProtectLog or *UserIDLog. Logs of any other type are an
+// *OtherLog. Use a type switch to get at the fields of each one:
+//
+//	switch l := record.(type) {
+//	case *panos.TrafficLog:
+//		fmt.Println(l.Source, l.Destination, l.Bytes)
+//	case *panos.ThreatLog:
+//		fmt.Println(l.ThreatName, l.Severity)
+//	}
+type LogRecord interface {
+	// Header returns the fields that every log has.
+	Header() *LogHeader
+}
+
+// LogHeader holds the fields that every log has. Fields that are missing from a log, or that can't be parsed, are
+// left as their zero value.
+//
+// Log times are in the device's own time zone, but PAN-OS doesn't say which one that is, so they are parsed as if
+// they were UTC.
+type LogHeader struct {
+	ID             int64     `log:"logid"`
+	Serial         string    `log:"serial"`
+	DeviceName     string    `log:"device_name"`
+	Vsys           string    `log:"vsys"`
+	Type           string    `log:"type"`
+	Subtype        string    `log:"subtype"`
+	ReceiveTime    time.Time `log:"receive_time"`
+	TimeGenerated  time.Time `log:"time_generated"`
+	SequenceNumber int64     `log:"seqno"`
+	ActionFlags    string    `log:"actionflags"`
+
+	DeviceGroupHierarchy1 int `log:"dg_hier_level_1"`
+	DeviceGroupHierarchy2 int `log:"dg_hier_level_2"`
+	DeviceGroupHierarchy3 int `log:"dg_hier_level_3"`
+	DeviceGroupHierarchy4 int `log:"dg_hier_level_4"`
+}
+
+// Header returns h, so that every log type that embeds a LogHeader is a LogRecord.
+func (h *LogHeader) Header() *LogHeader {
+	return h
+}
+
+// SessionInfo holds the fields that describe the session that a traffic, threat, URL, WildFire or data log is
+// about.
+type SessionInfo struct {
+	Rule                 string `log:"rule"`
+	RuleUUID             string `log:"rule_uuid"`
+	Source               net.IP `log:"src"`
+	Destination          net.IP `log:"dst"`
+	NATSource            net.IP `log:"natsrc"`
+	NATDestination       net.IP `log:"natdst"`
+	SourcePort           int    `log:"sport"`
+	DestinationPort      int    `log:"dport"`
+	NATSourcePort        int    `log:"natsport"`
+	NATDestinationPort   int    `log:"natdport"`
+	SourceUser           string `log:"srcuser"`
+	DestinationUser      string `log:"dstuser"`
+	SourceZone           string `log:"from"`
+	DestinationZone      string `log:"to"`
+	SourceCountry        string `log:"srcloc"`
+	DestinationCountry   string `log:"dstloc"`
+	InboundInterface     string `log:"inbound_if"`
+	OutboundInterface    string `log:"outbound_if"`
+	Application          string `log:"app"`
+	Protocol             string `log:"proto"`
+	Action               string `log:"action"`
+	SessionID            int64  `log:"sessionid"`
+	RepeatCount          int    `log:"repeatcnt"`
+	Flags                string `log:"flags"`
+	ApplicationCategory  string `log:"category_of_app"`
+	ApplicationRisk      int    `log:"risk_of_app"`
+	SourceDeviceCategory string `log:"src_category"`
+}
+
+// TrafficLog is a log of type traffic, which records the start or end of a session.
+type TrafficLog struct {
+	LogHeader
+	SessionInfo
+
+	Category         string        `log:"category"`
+	SessionEndReason string        `log:"session_end_reason"`
+	Start            time.Time     `log:"start"`
+	Elapsed          time.Duration `log:"elapsed"`
+	Bytes            int64         `log:"bytes"`
+	BytesSent        int64         `log:"bytes_sent"`
+	BytesReceived    int64         `log:"bytes_received"`
+	Packets          int64         `log:"packets"`
+	PacketsSent      int64         `log:"pkts_sent"`
+	PacketsReceived  int64         `log:"pkts_received"`
+}
+
+// ThreatLog is a log of type threat, which records a threat that was detected, such as a virus or vulnerability.
+// URL filtering, WildFire and data filtering logs are also threat logs in PAN-OS, but have their own types here.
+type ThreatLog struct {
+	LogHeader
+	SessionInfo
+
+	ThreatName     string `log:"threatid"`
+	ThreatID       int64  `log:"tid"`
+	ThreatCategory string `log:"thr_category"`
+	Category       string `log:"category"`
+	Severity       string `log:"severity"`
+	Direction      string `log:"direction"`
+	Misc           string `log:"misc"`
+	FileDigest     string `log:"filedigest"`
+	FileType       string `log:"filetype"`
+	ContentVersion string `log:"contentver"`
+	PcapID         int64  `log:"pcap_id"`
+	ReportID       int64  `log:"reportid"`
+}
+
+// URLLog is a URL filtering log, which records a web request.
+type URLLog struct {
+	LogHeader
+	SessionInfo
+
+	URL           string `log:"misc"`
+	Category      string `log:"category"`
+	Severity      string `log:"severity"`
+	Direction     string `log:"direction"`
+	ContentType   string `log:"contenttype"`
+	HTTPMethod    string `log:"http_method"`
+	UserAgent     string `log:"user_agent"`
+	Referer       string `log:"referer"`
+	XForwardedFor string `log:"xff"`
+}
+
+// WildfireLog is a WildFire submission log, which records a file that was sent to WildFire and its verdict.
+type WildfireLog struct {
+	LogHeader
+	SessionInfo
+
+	FileName   string `log:"misc"`
+	Verdict    string `log:"category"`
+	Severity   string `log:"severity"`
+	Direction  string `log:"direction"`
+	FileDigest string `log:"filedigest"`
+	FileType   string `log:"filetype"`
+	Cloud      string `log:"cloud"`
+	ReportID   int64  `log:"reportid"`
+	Sender     string `log:"sender"`
+	Recipient  string `log:"recipient"`
+	Subject    string `log:"subject"`
+}
+
+// DataLog is a data filtering log, which records a file or data pattern that matched a file blocking or data
+// filtering profile.
+type DataLog struct {
+	LogHeader
+	SessionInfo
+
+	FileName   string `log:"misc"`
+	ThreatName string `log:"threatid"`
+	ThreatID   int64  `log:"tid"`
+	Category   string `log:"category"`
+	Severity   string `log:"severity"`
+	Direction  string `log:"direction"`
+	FileType   string `log:"filetype"`
+}
+
+// SystemLog is a log of type system, which records an event on the device itself.
+type SystemLog struct {
+	LogHeader
+
+	EventID     string `log:"eventid"`
+	Object      string `log:"object"`
+	Module      string `log:"module"`
+	Severity    string `log:"severity"`
+	Description string `log:"opaque"`
+}
+
+// ConfigLog is a log of type config, which records a change to the configuration.
+type ConfigLog struct {
+	LogHeader
+
+	Host                string `log:"host"`
+	Command             string `log:"cmd"`
+	Admin               string `log:"admin"`
+	Client              string `log:"client"`
+	Result              string `log:"result"`
+	Path                string `log:"path"`
+	FullPath            string `log:"full-path"`
+	BeforeChangePreview string `log:"before-change-preview"`
+	AfterChangePreview  string `log:"after-change-preview"`
+}
+
+// HIPMatchLog is a log of type hipmatch, which records a host that matched a HIP object or profile.
+type HIPMatchLog struct {
+	LogHeader
+
+	SourceUser  string `log:"srcuser"`
+	Source      net.IP `log:"src"`
+	MachineName string `log:"machinename"`
+	OS          string `log:"os"`
+	HIP         string `log:"matchname"`
+	HIPType     string `log:"matchtype"`
+	HostID      string `log:"hostid"`
+	RepeatCount int    `log:"repeatcnt"`
+}
+
+// GlobalProtectLog is a log of type globalprotect, which records a GlobalProtect connection event.
+type GlobalProtectLog struct {
+	LogHeader
+
+	EventID         string        `log:"eventid"`
+	Stage           string        `log:"stage"`
+	AuthMethod      string        `log:"auth_method"`
+	TunnelType      string        `log:"tunnel_type"`
+	User            string        `log:"srcuser"`
+	SourceRegion    string        `log:"srcregion"`
+	MachineName     string        `log:"machinename"`
+	PublicIP        net.IP        `log:"public_ip"`
+	PrivateIP       net.IP        `log:"private_ip"`
+	HostID          string        `log:"hostid"`
+	ClientVersion   string        `log:"client_ver"`
+	ClientOS        string        `log:"client_os"`
+	ClientOSVersion string        `log:"client_os_ver"`
+	Status          string        `log:"status"`
+	Reason          string        `log:"reason"`
+	Error           string        `log:"error"`
+	Description     string        `log:"opaque"`
+	Portal          string        `log:"portal"`
+	Gateway         string        `log:"gateway"`
+	ConnectMethod   string        `log:"connect_method"`
+	LoginDuration   time.Duration `log:"login_duration"`
+	RepeatCount     int           `log:"repeatcnt"`
+}
+
+// UserIDLog is a log of type userid, which records an IP address being mapped to a user.
+type UserIDLog struct {
+	LogHeader
+
+	Source         net.IP        `log:"ip"`
+	User           string        `log:"user"`
+	DataSource     string        `log:"datasource"`
+	DataSourceName string        `log:"datasourcename"`
+	DataSourceType string        `log:"datasourcetype"`
+	EventID        string        `log:"eventid"`
+	Timeout        time.Duration `log:"timeout"`
+	BeginPort      int           `log:"beginport"`
+	EndPort        int           `log:"endport"`
+	UserBySource   string        `log:"userbysource"`
+	TagName        string        `log:"tag_name"`
+	RepeatCount    int           `log:"repeatcnt"`
+}
+
+// OtherLog is a log of a type that doesn't have its own type here, such as decryption or authentication logs.
+type OtherLog struct {
+	LogHeader
+
+	// Fields holds every field of the log, by name.
+	Fields map[string]string
+}
+
+// logFields holds the fields of a single log, by name, before they are parsed into a LogRecord.
+type logFields map[string]string
+
+// xmlLogFields returns the fields of a log entry from a log query, including its logid attribute.
+func xmlLogFields(entry *configNode) logFields {
+	fields := logFields{}
+
+	for _, a := range entry.Attrs {
+		fields[a.Name.Local] = a.Value
+	}
+
+	for _, c := range entry.Children {
+		fields[c.XMLName.Local] = strings.TrimSpace(c.Text)
+	}
+
+	return fields
+}
+
+// record returns the log as the LogRecord that matches its type and subtype.
+func (f logFields) record() LogRecord {
+	var r LogRecord
+
+	switch strings.ToLower(f["type"]) {
+	case "traffic":
+		r = &TrafficLog{}
+	case "threat":
+		switch strings.ToLower(f["subtype"]) {
+		case "url":
+			r = &URLLog{}
+		case "wildfire":
+			r = &WildfireLog{}
+		case "file", "data":
+			r = &DataLog{}
+		default:
+			r = &ThreatLog{}
+		}
+	case "system":
+		r = &SystemLog{}
+	case "config":
+		r = &ConfigLog{}
+	case "hipmatch", "hip-match":
+		r = &HIPMatchLog{}
+	case "globalprotect":
+		r = &GlobalProtectLog{}
+	case "userid":
+		r = &UserIDLog{}
+	default:
+		other := &OtherLog{Fields: map[string]string{}}
+		for k, v := range f {
+			other.Fields[k] = v
+		}
+
+		r = other
+	}
+
+	f.fill(reflect.ValueOf(r).Elem())
+
+	return r
+}
+
+// Record returns the log as the LogRecord that matches its type, such as *TrafficLog, the same as the Records of
+// Logs, so that it can be passed to a LogEncoder. Each field is carried over by its xml tag, which is its PAN-OS field
+// name and so the name of its column. Fields that the record's type doesn't have are dropped, except from an OtherLog.
+func (l Log) Record() LogRecord {
+	f := logFields{}
+
+	v := reflect.ValueOf(l)
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.Split(v.Type().Field(i).Tag.Get("xml"), ",")[0]
+		if name == "" {
+			continue
+		}
+
+		switch field := v.Field(i); field.Kind() {
+		case reflect.String:
+			if field.String() != "" {
+				f[name] = field.String()
+			}
+		case reflect.Int, reflect.Int64:
+			if field.Int() != 0 {
+				f[name] = strconv.FormatInt(field.Int(), 10)
+			}
+		}
+	}
+
+	return f.record()
+}
+
+// LogRecords returns each of logs as a LogRecord, as Log.Record does, so that they can all be passed to a LogEncoder
+// at once:
+//
+//	if err := enc.Encode(panos.LogRecords(logs.Logs)...); err != nil {
+//		return err
+//	}
+func LogRecords(logs []Log) []LogRecord {
+	records := make([]LogRecord, 0, len(logs))
+	for _, l := range logs {
+		records = append(records, l.Record())
+	}
+
+	return records
+}
+
+// fill sets each field of the struct v that has a log tag, including those of embedded structs, from the log field
+// that the tag names.
+func (f logFields) fill(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field, sf := v.Field(i), v.Type().Field(i)
+
+		if sf.Anonymous && field.Kind() == reflect.Struct {
+			f.fill(field)
+			continue
+		}
+
+		value, ok := f[sf.Tag.Get("log")]
+		if !ok || value == "" {
+			continue
+		}
+
+		setLogField(field, value)
+	}
+}
+
+// setLogField parses value into field, according to the field's type. Values that can't be parsed are skipped.
+func setLogField(field reflect.Value, value string) {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(value)
+	case net.IP:
+		if ip := net.ParseIP(value); ip != nil {
+			field.Set(reflect.ValueOf(ip))
+		}
+	case time.Time:
+		if t, err := time.Parse(logTimeFormat, value); err == nil {
+			field.Set(reflect.ValueOf(t))
+		}
+	case time.Duration:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			field.SetInt(int64(time.Duration(n) * time.Second))
+		}
+	case int, int64:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			field.SetInt(n)
+		}
+	}
+}
+
+// logEntries is used for parsing the log entries in the response to a log query, one field at a time.
+type logEntries struct {
+	XMLName xml.Name     `xml:"response"`
+	Entries []configNode `xml:"result>log>logs>entry"`
+}
+
+// logRecords returns each log in the response to a log query as a LogRecord.
+func logRecords(body []byte) ([]LogRecord, error) {
+	var entries logEntries
+
+	if err := xml.Unmarshal(body, &entries); err != nil {
+		return nil, err
+	}
+
+	records := make([]LogRecord, 0, len(entries.Entries))
+	for i := range entries.Entries {
+		records = append(records, xmlLogFields(&entries.Entries[i]).record())
+	}
+
+	return records, nil
+}
@@ -0,0 +1,143 @@
+package panos
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// maxLogsPerQuery is the most logs that a single log query can return.
+	maxLogsPerQuery = 5000
+
+	// defaultLogPollInterval is how often WaitForLogs checks on a log query, if no interval is given.
+	defaultLogPollInterval = time.Second
+)
+
+// LogIterator steps through every log that matches a query, running as many log queries as it takes. Create one
+// with IterateLogs, and call Next until it returns false:
+//
+//	logs := pan.IterateLogs("traffic", &panos.LogParameters{Query: "(addr.src in 10.1.1.10)"})
+//	for logs.Next() {
+//		fmt.Println(logs.Log().Source)
+//	}
+//
+//	if err := logs.Err(); err != nil {
+//		return err
+//	}
+type LogIterator struct {
+	p       *PaloAlto
+	ctx     context.Context
+	logtype string
+	params  LogParameters
+	page    []Log
+	records []LogRecord
+	log     Log
+	record  LogRecord
+	done    bool
+	err     error
+}
+
+// IterateLogs returns a LogIterator for the logs of the given type (e.g. traffic or system) that match parameters,
+// which can be nil. Nothing is sent to the device until Next is called.
+//
+// Each query returns up to parameters.NLogs logs (5000, the most that PAN-OS allows, if it is zero or more than
+// that), and the next query skips the logs that were already returned. Since new logs arrive at the start of the
+// results, they shift the logs that later queries return when iterating newest first. To step through a fixed range of
+// logs, bound the query with a time range (e.g. "(receive_time leq '2026/01/02 00:00:00')"), or set
+// parameters.Direction to "forward".
+func (p *PaloAlto) IterateLogs(logtype string, parameters *LogParameters) *LogIterator {
+	return p.IterateLogsContext(context.Background(), logtype, parameters)
+}
+
+// IterateLogsContext is the same as IterateLogs, but uses ctx for all of its API requests.
+func (p *PaloAlto) IterateLogsContext(ctx context.Context, logtype string, parameters *LogParameters) *LogIterator {
+	it := &LogIterator{p: p, ctx: ctx, logtype: logtype}
+
+	if parameters != nil {
+		it.params = *parameters
+	}
+
+	if it.params.NLogs <= 0 || it.params.NLogs > maxLogsPerQuery {
+		it.params.NLogs = maxLogsPerQuery
+	}
+
+	return it
+}
+
+// Next moves on to the next log, running another log query and waiting for it to finish if needed. It returns false
+// once there are no more logs, or an error occurs; call Err to tell the two apart.
+func (it *LogIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		it.err = it.fetch()
+	}
+
+	it.log, it.page = it.page[0], it.page[1:]
+	it.record, it.records = it.records[0], it.records[1:]
+
+	return true
+}
+
+// Log returns the current log.
+func (it *LogIterator) Log() Log {
+	return it.log
+}
+
+// Record returns the current log, as the type that matches its log type, such as *TrafficLog.
+func (it *LogIterator) Record() LogRecord {
+	return it.record
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (it *LogIterator) Err() error {
+	return it.err
+}
+
+// fetch runs a log query for the next page of logs.
+func (it *LogIterator) fetch() error {
+	id, err := it.p.QueryLogsContext(it.ctx, it.logtype, &it.params)
+	if err != nil {
+		return err
+	}
+
+	logs, err := it.p.WaitForLogs(it.ctx, id, 0)
+	if err != nil {
+		return err
+	}
+
+	if len(logs.Logs) < it.params.NLogs {
+		it.done = true
+	}
+
+	it.params.Skip += len(logs.Logs)
+	it.page = logs.Logs
+	it.records = logs.Records
+
+	return nil
+}
+
+// WaitForLogs checks on the log query with the given ID, as returned by QueryLogs, every pollInterval (1 second, if it
+// is zero), until the query finishes or ctx is done. It returns the logs that the query found.
+func (p *PaloAlto) WaitForLogs(ctx context.Context, id int, pollInterval time.Duration) (*Logs, error) {
+	if pollInterval <= 0 {
+		pollInterval = defaultLogPollInterval
+	}
+
+	for {
+		logs, err := p.RetrieveLogsContext(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if logs.JobStatus == "FIN" {
+			return logs, nil
+		}
+
+		if err := sleep(ctx, pollInterval); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package panos
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/scottdware/go-panos/panostest"
+)
+
+func TestIterateLogs(t *testing.T) {
+	var entries []string
+	for i := 12; i > 0; i-- {
+		entries = append(entries, fmt.Sprintf("<type>TRAFFIC</type><subtype>end</subtype><src>10.0.0.%d</src><sport>%d</sport>", i, 1000+i))
+	}
+
+	tests := []struct {
+		name      string
+		nlogs     int
+		direction string
+		first     string
+		last      string
+	}{
+		{name: "newest first", nlogs: 5, first: "10.0.0.12", last: "10.0.0.1"},
+		{name: "page size that divides evenly", nlogs: 6, first: "10.0.0.12", last: "10.0.0.1"},
+		{name: "oldest first", nlogs: 5, direction: "forward", first: "10.0.0.1", last: "10.0.0.12"},
+		{name: "one page", first: "10.0.0.12", last: "10.0.0.1"},
+		{name: "page size above the maximum", nlogs: maxLogsPerQuery + 1000, first: "10.0.0.12", last: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pan, srv := newTestSession(t, panostest.WithLogs("traffic", entries...))
+			defer srv.Close()
+
+			it := pan.IterateLogs("traffic", &LogParameters{NLogs: tt.nlogs, Direction: tt.direction})
+
+			var logs []Log
+			for it.Next() {
+				logs = append(logs, it.Log())
+
+				traffic, ok := it.Record().(*TrafficLog)
+				if !ok {
+					t.Fatalf("got a %T record, want *TrafficLog", it.Record())
+				}
+
+				if traffic.Source.String() != it.Log().Source || traffic.SourcePort != it.Log().SourcePort {
+					t.Fatalf("the record %+v does not match the log %+v", traffic.SessionInfo, it.Log())
+				}
+			}
+
+			if err := it.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(logs) != len(entries) {
+				t.Fatalf("got %d logs, want %d", len(logs), len(entries))
+			}
+
+			if logs[0].Source != tt.first || logs[len(logs)-1].Source != tt.last {
+				t.Fatalf("got logs from %s to %s, want %s to %s", logs[0].Source, logs[len(logs)-1].Source, tt.first, tt.last)
+			}
+		})
+	}
+}
+
+func TestIterateLogsEmpty(t *testing.T) {
+	pan, srv := newTestSession(t)
+	defer srv.Close()
+
+	it := pan.IterateLogs("system", nil)
+	if it.Next() {
+		t.Fatal("got a log from an empty log type")
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogCountersAboveInt32(t *testing.T) {
+	// More than fits in a 32-bit int, so an int field fails to parse on 386 and arm.
+	const bytes = 5000000000
+
+	pan, srv := newTestSession(t, panostest.WithLogs("traffic",
+		fmt.Sprintf("<type>TRAFFIC</type><subtype>end</subtype><bytes>%d</bytes><sessionid>%d</sessionid>", int64(bytes), int64(bytes))))
+	defer srv.Close()
+
+	id, err := pan.QueryLogs("traffic", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logs, err := pan.RetrieveLogs(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logs.Logs) != 1 || logs.Logs[0].Bytes != bytes || logs.Logs[0].SessionID != bytes {
+		t.Fatalf("got logs %+v from RetrieveLogs", logs.Logs)
+	}
+
+	if traffic, ok := logs.Records[0].(*TrafficLog); !ok || traffic.Bytes != bytes {
+		t.Fatalf("got record %+v from RetrieveLogs", logs.Records[0])
+	}
+
+	if traffic, ok := logs.Logs[0].Record().(*TrafficLog); !ok || traffic.Bytes != bytes {
+		t.Fatalf("got record %+v from Log.Record", logs.Logs[0].Record())
+	}
+
+	it := pan.IterateLogs("traffic", nil)
+	if !it.Next() {
+		t.Fatalf("got no logs from IterateLogs: %v", it.Err())
+	}
+
+	if it.Log().Bytes != bytes || it.Record().(*TrafficLog).Bytes != bytes {
+		t.Fatalf("got log %+v from IterateLogs", it.Log())
+	}
+
+	if it.Next() {
+		t.Fatal("got a second log from IterateLogs")
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
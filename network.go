@@ -1,6 +1,7 @@
 package panos
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -204,84 +205,83 @@ type InterfaceHardware struct {
 // be sure to append the VLAN tag to the interface name (e.g. ethernet1/1.700). You must also specify the subnet mask in
 // CIDR notation when specifying the IP address.
 func (p *PaloAlto) CreateLayer3Interface(ifname, ipaddress string, comment ...string) error {
+	return p.CreateLayer3InterfaceContext(context.Background(), ifname, ipaddress, comment...)
+}
+
+// CreateLayer3InterfaceContext is the same as CreateLayer3Interface, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateLayer3InterfaceContext(ctx context.Context, ifname, ipaddress string, comment ...string) error {
 	var xmlBody string
-	var reqError requestError
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot create interfaces on a Panorama device")
 	}
 
 	ifDetails := strings.Split(ifname, ".")
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name='%s']", ifDetails[0])
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name=%s]", xpathQuote(ifDetails[0]))
 
 	if len(ifDetails) > 1 {
-		xmlBody = fmt.Sprintf("<layer3><units><entry name=\"%s.%s\"><ip><entry name=\"%s\"/></ip><tag>%s</tag>", ifDetails[0], ifDetails[1], ipaddress, ifDetails[1])
+		xmlBody = fmt.Sprintf("<layer3><units><entry name=\"%s.%s\"><ip><entry name=\"%s\"/></ip><tag>%s</tag>", xmlEscape(ifDetails[0]), xmlEscape(ifDetails[1]), xmlEscape(ipaddress), xmlEscape(ifDetails[1]))
 		if len(comment) > 0 {
-			xmlBody += fmt.Sprintf("<comment>%s</comment></entry></units></layer3>", comment[0])
+			xmlBody += fmt.Sprintf("<comment>%s</comment></entry></units></layer3>", xmlEscape(comment[0]))
 		} else {
 			xmlBody += "</entry></units></layer3>"
 		}
 	} else {
-		xmlBody = fmt.Sprintf("<layer3><ip><entry name=\"%s\"/></ip></layer3>", ipaddress)
+		xmlBody = fmt.Sprintf("<layer3><ip><entry name=\"%s\"/></ip></layer3>", xmlEscape(ipaddress))
 		if len(comment) > 0 {
-			xmlBody += fmt.Sprintf("<comment>%s</comment>", comment[0])
+			xmlBody += fmt.Sprintf("<comment>%s</comment>", xmlEscape(comment[0]))
 		}
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
-// CreateInterface creates the given interface type specified in the `iftype`` parameter (e.g. tap, vwire, layer2, layer3, vlan,
+// CreateInterface creates the given interface type specified in the `iftype“ parameter (e.g. tap, vwire, layer2, layer3, vlan,
 // loopback or tunnel). If adding a sub-interface, be sure to append the VLAN tag to the interface name (e.g. ethernet1/1.700).
 // The (optional) ipaddr parameter allows you to assign an IP address to a layer 3/vlan/loopback or tunnel interface, or an
 // IP classifier to a virtual-wire sub-interface. You do not need to specify the ipaddr parameter on a tap or layer2 interface type.
 // Note that you must specify the subnet mask in CIDR notation when including an IP address.
 func (p *PaloAlto) CreateInterface(iftype, ifname, comment string, ipaddr ...string) error {
+	return p.CreateInterfaceContext(context.Background(), iftype, ifname, comment, ipaddr...)
+}
+
+// CreateInterfaceContext is the same as CreateInterface, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateInterfaceContext(ctx context.Context, iftype, ifname, comment string, ipaddr ...string) error {
 	var xmlBody string
 	var xpath string
-	var reqError requestError
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot create interfaces on a Panorama device")
 	}
 
 	ifDetails := strings.Split(ifname, ".")
-	xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name='%s']", ifDetails[0])
+	xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name=%s]", xpathQuote(ifDetails[0]))
 
 	switch iftype {
 	case "tap":
 		xmlBody = "<tap/>"
 		if len(comment) > 0 {
-			xmlBody += fmt.Sprintf("<comment>%s</comment>", comment)
+			xmlBody += fmt.Sprintf("<comment>%s</comment>", xmlEscape(comment))
 		}
 	case "vwire":
 		xmlBody = "<virtual-wire><lldp><enable>no</enable></lldp></virtual-wire>"
 		if len(comment) > 0 {
-			xmlBody += fmt.Sprintf("<comment>%s</comment>", comment)
+			xmlBody += fmt.Sprintf("<comment>%s</comment>", xmlEscape(comment))
 		}
 
 		if len(ifDetails) > 1 {
-			xmlBody = fmt.Sprintf("<virtual-wire><lldp><enable>no</enable></lldp><units><entry name=\"%s.%s\"><tag>%s</tag>", ifDetails[0], ifDetails[1], ifDetails[1])
+			xmlBody = fmt.Sprintf("<virtual-wire><lldp><enable>no</enable></lldp><units><entry name=\"%s.%s\"><tag>%s</tag>", xmlEscape(ifDetails[0]), xmlEscape(ifDetails[1]), xmlEscape(ifDetails[1]))
 
 			if len(ipaddr) > 0 {
-				xmlBody = fmt.Sprintf("<virtual-wire><lldp><enable>no</enable></lldp><units><entry name=\"%s.%s\"><ip-classifier><member>%s</member></ip-classifier><tag>%s</tag>", ifDetails[0], ifDetails[1], ipaddr[0], ifDetails[1])
+				xmlBody = fmt.Sprintf("<virtual-wire><lldp><enable>no</enable></lldp><units><entry name=\"%s.%s\"><ip-classifier><member>%s</member></ip-classifier><tag>%s</tag>", xmlEscape(ifDetails[0]), xmlEscape(ifDetails[1]), xmlEscape(ipaddr[0]), xmlEscape(ifDetails[1]))
 			}
 
 			if len(comment) > 0 {
-				xmlBody += fmt.Sprintf("<comment>%s</comment></entry></units></virtual-wire>", comment)
+				xmlBody += fmt.Sprintf("<comment>%s</comment></entry></units></virtual-wire>", xmlEscape(comment))
 			} else {
 				xmlBody += "</entry></units></virtual-wire>"
 			}
@@ -289,14 +289,14 @@ func (p *PaloAlto) CreateInterface(iftype, ifname, comment string, ipaddr ...str
 	case "layer2":
 		xmlBody = "<layer2><lldp><enable>no</enable></lldp></layer2>"
 		if len(comment) > 0 {
-			xmlBody += fmt.Sprintf("<comment>%s</comment>", comment)
+			xmlBody += fmt.Sprintf("<comment>%s</comment>", xmlEscape(comment))
 		}
 
 		if len(ifDetails) > 1 {
-			xmlBody = fmt.Sprintf("<layer2><lldp><enable>no</enable></lldp><units><entry name=\"%s.%s\"><tag>%s</tag>", ifDetails[0], ifDetails[1], ifDetails[1])
+			xmlBody = fmt.Sprintf("<layer2><lldp><enable>no</enable></lldp><units><entry name=\"%s.%s\"><tag>%s</tag>", xmlEscape(ifDetails[0]), xmlEscape(ifDetails[1]), xmlEscape(ifDetails[1]))
 
 			if len(comment) > 0 {
-				xmlBody += fmt.Sprintf("<comment>%s</comment></entry></units></virtual-wire>", comment)
+				xmlBody += fmt.Sprintf("<comment>%s</comment></entry></units></virtual-wire>", xmlEscape(comment))
 			} else {
 				xmlBody += "</entry></units></layer2>"
 			}
@@ -305,22 +305,22 @@ func (p *PaloAlto) CreateInterface(iftype, ifname, comment string, ipaddr ...str
 		xmlBody = "<layer3/>"
 
 		if len(ipaddr) > 0 {
-			xmlBody = fmt.Sprintf("<layer3><ip><entry name=\"%s\"/></ip></layer3>", ipaddr[0])
+			xmlBody = fmt.Sprintf("<layer3><ip><entry name=\"%s\"/></ip></layer3>", xmlEscape(ipaddr[0]))
 		}
 
 		if len(comment) > 0 {
-			xmlBody += fmt.Sprintf("<comment>%s</comment>", comment)
+			xmlBody += fmt.Sprintf("<comment>%s</comment>", xmlEscape(comment))
 		}
 
 		if len(ifDetails) > 1 {
-			xmlBody = fmt.Sprintf("<layer3><units><entry name=\"%s.%s\"><tag>%s</tag>", ifDetails[0], ifDetails[1], ifDetails[1])
+			xmlBody = fmt.Sprintf("<layer3><units><entry name=\"%s.%s\"><tag>%s</tag>", xmlEscape(ifDetails[0]), xmlEscape(ifDetails[1]), xmlEscape(ifDetails[1]))
 
 			if len(ipaddr) > 0 {
-				xmlBody = fmt.Sprintf("<layer3><units><entry name=\"%s.%s\"><ip><entry name=\"%s\"/></ip><tag>%s</tag>", ifDetails[0], ifDetails[1], ipaddr[0], ifDetails[1])
+				xmlBody = fmt.Sprintf("<layer3><units><entry name=\"%s.%s\"><ip><entry name=\"%s\"/></ip><tag>%s</tag>", xmlEscape(ifDetails[0]), xmlEscape(ifDetails[1]), xmlEscape(ipaddr[0]), xmlEscape(ifDetails[1]))
 			}
 
 			if len(comment) > 0 {
-				xmlBody += fmt.Sprintf("<comment>%s</comment></entry></units></layer3>", comment)
+				xmlBody += fmt.Sprintf("<comment>%s</comment></entry></units></layer3>", xmlEscape(comment))
 			} else {
 				xmlBody += "</entry></units></layer3>"
 			}
@@ -333,14 +333,14 @@ func (p *PaloAlto) CreateInterface(iftype, ifname, comment string, ipaddr ...str
 		xpath = "/config/devices/entry[@name='localhost.localdomain']/network/interface/vlan/units"
 
 		if len(ifDetails) > 1 {
-			xmlBody = fmt.Sprintf("<entry name=\"%s.%s\">", ifDetails[0], ifDetails[1])
+			xmlBody = fmt.Sprintf("<entry name=\"%s.%s\">", xmlEscape(ifDetails[0]), xmlEscape(ifDetails[1]))
 
 			if len(ipaddr) > 0 {
-				xmlBody = fmt.Sprintf("<entry name=\"%s.%s\"><ip><entry name=\"%s\"/></ip>", ifDetails[0], ifDetails[1], ipaddr[0])
+				xmlBody = fmt.Sprintf("<entry name=\"%s.%s\"><ip><entry name=\"%s\"/></ip>", xmlEscape(ifDetails[0]), xmlEscape(ifDetails[1]), xmlEscape(ipaddr[0]))
 			}
 
 			if len(comment) > 0 {
-				xmlBody += fmt.Sprintf("<comment>%s</comment></entry>", comment)
+				xmlBody += fmt.Sprintf("<comment>%s</comment></entry>", xmlEscape(comment))
 			} else {
 				xmlBody += "</entry>"
 			}
@@ -353,7 +353,7 @@ func (p *PaloAlto) CreateInterface(iftype, ifname, comment string, ipaddr ...str
 		xpath = "/config/devices/entry[@name='localhost.localdomain']/network/interface/loopback/units"
 
 		if len(ifDetails) > 1 {
-			xmlBody = fmt.Sprintf("<entry name=\"%s.%s\">", ifDetails[0], ifDetails[1])
+			xmlBody = fmt.Sprintf("<entry name=\"%s.%s\">", xmlEscape(ifDetails[0]), xmlEscape(ifDetails[1]))
 
 			if len(ipaddr) > 0 {
 				ip := strings.Split(ipaddr[0], "/")
@@ -362,11 +362,11 @@ func (p *PaloAlto) CreateInterface(iftype, ifname, comment string, ipaddr ...str
 					return errors.New("you can only specify a /32 subnet mask for a loopback interface")
 				}
 
-				xmlBody = fmt.Sprintf("<entry name=\"%s.%s\"><ip><entry name=\"%s\"/></ip>", ifDetails[0], ifDetails[1], ipaddr[0])
+				xmlBody = fmt.Sprintf("<entry name=\"%s.%s\"><ip><entry name=\"%s\"/></ip>", xmlEscape(ifDetails[0]), xmlEscape(ifDetails[1]), xmlEscape(ipaddr[0]))
 			}
 
 			if len(comment) > 0 {
-				xmlBody += fmt.Sprintf("<comment>%s</comment></entry>", comment)
+				xmlBody += fmt.Sprintf("<comment>%s</comment></entry>", xmlEscape(comment))
 			} else {
 				xmlBody += "</entry>"
 			}
@@ -379,40 +379,35 @@ func (p *PaloAlto) CreateInterface(iftype, ifname, comment string, ipaddr ...str
 		xpath = "/config/devices/entry[@name='localhost.localdomain']/network/interface/tunnel/units"
 
 		if len(ifDetails) > 1 {
-			xmlBody = fmt.Sprintf("<entry name=\"%s.%s\">", ifDetails[0], ifDetails[1])
+			xmlBody = fmt.Sprintf("<entry name=\"%s.%s\">", xmlEscape(ifDetails[0]), xmlEscape(ifDetails[1]))
 
 			if len(ipaddr) > 0 {
-				xmlBody = fmt.Sprintf("<entry name=\"%s.%s\"><ip><entry name=\"%s\"/></ip>", ifDetails[0], ifDetails[1], ipaddr[0])
+				xmlBody = fmt.Sprintf("<entry name=\"%s.%s\"><ip><entry name=\"%s\"/></ip>", xmlEscape(ifDetails[0]), xmlEscape(ifDetails[1]), xmlEscape(ipaddr[0]))
 			}
 
 			if len(comment) > 0 {
-				xmlBody += fmt.Sprintf("<comment>%s</comment></entry>", comment)
+				xmlBody += fmt.Sprintf("<comment>%s</comment></entry>", xmlEscape(comment))
 			} else {
 				xmlBody += "</entry>"
 			}
 		}
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // DeleteInterface removes an interface or sub-interface from the device. You must specify the interface
 // type in the iftype parameter (e.g. tap, vwire, layer2, layer3, vlan, loopback or tunnel).
 func (p *PaloAlto) DeleteInterface(iftype, ifname string) error {
-	var reqError requestError
+	return p.DeleteInterfaceContext(context.Background(), iftype, ifname)
+}
+
+// DeleteInterfaceContext is the same as DeleteInterface, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteInterfaceContext(ctx context.Context, iftype, ifname string) error {
 	var xpath string
 	var ifDetails []string
 	var subIntName string
@@ -428,68 +423,63 @@ func (p *PaloAlto) DeleteInterface(iftype, ifname string) error {
 		ifDetails = []string{ifname}
 	}
 
-	xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name='%s']", ifDetails[0])
+	xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name=%s]", xpathQuote(ifDetails[0]))
 
 	switch iftype {
 	case "vwire":
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name='%s']", ifDetails[0])
+		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name=%s]", xpathQuote(ifDetails[0]))
 
 		if len(ifDetails) > 1 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name='%s']/virtual-wire/units/entry[@name='%s']", ifDetails[0], subIntName)
+			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name=%s]/virtual-wire/units/entry[@name=%s]", xpathQuote(ifDetails[0]), xpathQuote(subIntName))
 		}
 	case "layer2":
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name='%s']", ifDetails[0])
+		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name=%s]", xpathQuote(ifDetails[0]))
 
 		if len(ifDetails) > 1 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name='%s']/layer2/units/entry[@name='%s']", ifDetails[0], subIntName)
+			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name=%s]/layer2/units/entry[@name=%s]", xpathQuote(ifDetails[0]), xpathQuote(subIntName))
 		}
 	case "layer3":
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name='%s']", ifDetails[0])
+		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name=%s]", xpathQuote(ifDetails[0]))
 
 		if len(ifDetails) > 1 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name='%s']/layer3/units/entry[@name='%s']", ifDetails[0], subIntName)
+			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/ethernet/entry[@name=%s]/layer3/units/entry[@name=%s]", xpathQuote(ifDetails[0]), xpathQuote(subIntName))
 		}
 	case "vlan":
 		if len(ifDetails) > 1 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/vlan/units/entry[@name='%s']", subIntName)
+			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/vlan/units/entry[@name=%s]", xpathQuote(subIntName))
 		}
 	case "loopback":
 		if len(ifDetails) > 1 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/loopback/units/entry[@name='%s']", subIntName)
+			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/loopback/units/entry[@name=%s]", xpathQuote(subIntName))
 		}
 	case "tunnel":
 		if len(ifDetails) > 1 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/tunnel/units/entry[@name='%s']", subIntName)
+			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/interface/tunnel/units/entry[@name=%s]", xpathQuote(subIntName))
 		}
 	}
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // CreateZone will add a new zone to the device. Zonetype must be one of tap, vwire, layer2, layer3. If
 // you wish to enable user-id on the zone, specify true for the userid parameter, false if not.
 func (p *PaloAlto) CreateZone(name, zonetype string, userid bool) error {
+	return p.CreateZoneContext(context.Background(), name, zonetype, userid)
+}
+
+// CreateZoneContext is the same as CreateZone, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateZoneContext(ctx context.Context, name, zonetype string, userid bool) error {
 	var xmlBody string
-	var reqError requestError
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot create zones on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/zone/entry[@name='%s']", name)
+	xpath := fmt.Sprintf("%s/zone/entry[@name=%s]", p.vsysXpath(), xpathQuote(name))
 	switch zonetype {
 	case "tap":
 		xmlBody = "<network><tap/></network>"
@@ -505,252 +495,208 @@ func (p *PaloAlto) CreateZone(name, zonetype string, userid bool) error {
 		xmlBody += "<enable-user-identification>yes</enable-user-identification>"
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // DeleteZone will remove a zone from the device.
 func (p *PaloAlto) DeleteZone(name string) error {
-	var reqError requestError
+	return p.DeleteZoneContext(context.Background(), name)
+}
+
+// DeleteZoneContext is the same as DeleteZone, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteZoneContext(ctx context.Context, name string) error {
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot delete zones on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/zone/entry[@name='%s']", name)
-
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("%s/zone/entry[@name=%s]", p.vsysXpath(), xpathQuote(name))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // AddInterfaceToZone adds an interface or interfaces to the given zone. Zonetype must be one of tap, vwire, layer2, layer3.
 // Separate multiple interfaces using a comma (e.g. "ethernet1/2, ethernet1/3").
 func (p *PaloAlto) AddInterfaceToZone(name, zonetype, ifname string) error {
+	return p.AddInterfaceToZoneContext(context.Background(), name, zonetype, ifname)
+}
+
+// AddInterfaceToZoneContext is the same as AddInterfaceToZone, but uses ctx for all of its API requests.
+func (p *PaloAlto) AddInterfaceToZoneContext(ctx context.Context, name, zonetype, ifname string) error {
 	var xmlBody string
-	var reqError requestError
 	ints := strings.Split(ifname, ",")
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot add interfaces to zones on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/zone/entry[@name='%s']", name)
+	xpath := fmt.Sprintf("%s/zone/entry[@name=%s]", p.vsysXpath(), xpathQuote(name))
 
 	switch zonetype {
 	case "tap":
 		xmlBody = "<network><tap>"
 		for _, i := range ints {
-			xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(i))
+			xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(i)))
 		}
 		xmlBody += "</tap></network>"
 	case "vwire":
 		xmlBody = "<network><virtual-wire>"
 		for _, i := range ints {
-			xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(i))
+			xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(i)))
 		}
 		xmlBody += "</virtual-wire></network>"
 	case "layer2":
 		xmlBody = "<network><layer2>"
 		for _, i := range ints {
-			xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(i))
+			xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(i)))
 		}
 		xmlBody += "</layer2></network>"
 	case "layer3":
 		xmlBody = "<network><layer3>"
 		for _, i := range ints {
-			xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(i))
+			xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(i)))
 		}
 		xmlBody += "</layer3></network>"
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // RemoveInterfaceFromZone removes an interface from the specified zone.
 func (p *PaloAlto) RemoveInterfaceFromZone(name, zonetype, ifname string) error {
-	var reqError requestError
+	return p.RemoveInterfaceFromZoneContext(context.Background(), name, zonetype, ifname)
+}
+
+// RemoveInterfaceFromZoneContext is the same as RemoveInterfaceFromZone, but uses ctx for all of its API requests.
+func (p *PaloAlto) RemoveInterfaceFromZoneContext(ctx context.Context, name, zonetype, ifname string) error {
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot remove interfaces from zones on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/zone/entry[@name='%s']", name)
+	xpath := fmt.Sprintf("%s/zone/entry[@name=%s]", p.vsysXpath(), xpathQuote(name))
 
 	switch zonetype {
 	case "tap":
-		xpath += fmt.Sprintf("/network/tap/member[text()='%s']", ifname)
+		xpath += fmt.Sprintf("/network/tap/member[text()=%s]", xpathQuote(ifname))
 	case "vwire":
-		xpath += fmt.Sprintf("/network/virtual-wire/member[text()='%s']", ifname)
+		xpath += fmt.Sprintf("/network/virtual-wire/member[text()=%s]", xpathQuote(ifname))
 	case "layer2":
-		xpath += fmt.Sprintf("/network/layer2/member[text()='%s']", ifname)
+		xpath += fmt.Sprintf("/network/layer2/member[text()=%s]", xpathQuote(ifname))
 	case "layer3":
-		xpath += fmt.Sprintf("/network/layer3/member[text()='%s']", ifname)
+		xpath += fmt.Sprintf("/network/layer3/member[text()=%s]", xpathQuote(ifname))
 	}
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // CreateVirtualRouter will add a new virtual-router to the device.
 func (p *PaloAlto) CreateVirtualRouter(name string) error {
+	return p.CreateVirtualRouterContext(context.Background(), name)
+}
+
+// CreateVirtualRouterContext is the same as CreateVirtualRouter, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateVirtualRouterContext(ctx context.Context, name string) error {
 	var xmlBody string
-	var reqError requestError
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot create virtual-routers on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name='%s']", name)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name=%s]", xpathQuote(name))
 	xmlBody = "<protocol><bgp><routing-options><graceful-restart><enable>yes</enable></graceful-restart><as-format>2-byte</as-format></routing-options><enable>no</enable></bgp></protocol>"
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // DeleteVirtualRouter removes a virtual-router from the device.
 func (p *PaloAlto) DeleteVirtualRouter(vr string) error {
-	var reqError requestError
+	return p.DeleteVirtualRouterContext(context.Background(), vr)
+}
+
+// DeleteVirtualRouterContext is the same as DeleteVirtualRouter, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteVirtualRouterContext(ctx context.Context, vr string) error {
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot delete a virtual-router on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name='%s']", vr)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name=%s]", xpathQuote(vr))
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // AddInterfaceToVirtualRouter will add an interface or interfaces to the given virtual-router. Separate multiple
 // interfaces using a comma (e.g. "ethernet1/2, ethernet1/3").
 func (p *PaloAlto) AddInterfaceToVirtualRouter(vr, ifname string) error {
+	return p.AddInterfaceToVirtualRouterContext(context.Background(), vr, ifname)
+}
+
+// AddInterfaceToVirtualRouterContext is the same as AddInterfaceToVirtualRouter, but uses ctx for all of its API requests.
+func (p *PaloAlto) AddInterfaceToVirtualRouterContext(ctx context.Context, vr, ifname string) error {
 	var xmlBody string
-	var reqError requestError
 	ints := strings.Split(ifname, ",")
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot add interfaces to virtual-routers on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name='%s']", vr)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name=%s]", xpathQuote(vr))
 	xmlBody = "<interface>"
 	for _, i := range ints {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(i))
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(i)))
 	}
 	xmlBody += "</interface>"
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // RemoveInterfaceFromVirtualRouter removes a given interface from the specified virtual-router.
 func (p *PaloAlto) RemoveInterfaceFromVirtualRouter(vr, ifname string) error {
-	var reqError requestError
+	return p.RemoveInterfaceFromVirtualRouterContext(context.Background(), vr, ifname)
+}
+
+// RemoveInterfaceFromVirtualRouterContext is the same as RemoveInterfaceFromVirtualRouter, but uses ctx for all of its API requests.
+func (p *PaloAlto) RemoveInterfaceFromVirtualRouterContext(ctx context.Context, vr, ifname string) error {
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot remove interfaces from a virtual-router on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name='%s']/interface/member[text()='%s']", vr, ifname)
-
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name=%s]/interface/member[text()=%s]", xpathQuote(vr), xpathQuote(ifname))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -758,8 +704,12 @@ func (p *PaloAlto) RemoveInterfaceFromVirtualRouter(vr, ifname string) error {
 // include the mask (e.g. "192.168.0.0/24" or "0.0.0.0/0"). For nexthop, you can also specify an interface
 // instead of an IP address. You can optionally specify a metric for the route (default metric is 10).
 func (p *PaloAlto) CreateStaticRoute(vr, name, destination, nexthop string, metric ...int) error {
+	return p.CreateStaticRouteContext(context.Background(), vr, name, destination, nexthop, metric...)
+}
+
+// CreateStaticRouteContext is the same as CreateStaticRoute, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateStaticRouteContext(ctx context.Context, vr, name, destination, nexthop string, metric ...int) error {
 	var xmlBody string
-	var reqError requestError
 	re := regexp.MustCompile("ethernet|tunnel|ae|loopback|vlan")
 	ints := re.FindAllString(nexthop, -1)
 
@@ -767,13 +717,13 @@ func (p *PaloAlto) CreateStaticRoute(vr, name, destination, nexthop string, metr
 		return errors.New("you cannot create static routes on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name='%s']", vr)
-	xmlBody = fmt.Sprintf("<routing-table><ip><static-route><entry name=\"%s\">", name)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name=%s]", xpathQuote(vr))
+	xmlBody = fmt.Sprintf("<routing-table><ip><static-route><entry name=\"%s\">", xmlEscape(name))
 
 	if len(ints) > 0 {
-		xmlBody += fmt.Sprintf("<interface>%s</interface><destination>%s</destination>", nexthop, destination)
+		xmlBody += fmt.Sprintf("<interface>%s</interface><destination>%s</destination>", xmlEscape(nexthop), xmlEscape(destination))
 	} else {
-		xmlBody += fmt.Sprintf("<nexthop><ip-address>%s</ip-address></nexthop><destination>%s</destination>", nexthop, destination)
+		xmlBody += fmt.Sprintf("<nexthop><ip-address>%s</ip-address></nexthop><destination>%s</destination>", xmlEscape(nexthop), xmlEscape(destination))
 	}
 
 	if len(metric) > 0 {
@@ -784,219 +734,175 @@ func (p *PaloAlto) CreateStaticRoute(vr, name, destination, nexthop string, metr
 
 	xmlBody += "</entry></static-route></ip></routing-table>"
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // DeleteStaticRoute will remove a static route from the device.
 func (p *PaloAlto) DeleteStaticRoute(vr, name string) error {
-	var reqError requestError
+	return p.DeleteStaticRouteContext(context.Background(), vr, name)
+}
+
+// DeleteStaticRouteContext is the same as DeleteStaticRoute, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteStaticRouteContext(ctx context.Context, vr, name string) error {
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot delete static routes on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name='%s']/routing-table/ip/static-route/entry[@name='%s']", vr, name)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-router/entry[@name=%s]/routing-table/ip/static-route/entry[@name=%s]", xpathQuote(vr), xpathQuote(name))
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // CreateVlan will add a new layer 2 vlan to the device. Optionally, if you wish to assign a vlan interface to the vlan,
 // specify the interface name as the last parameter. Otherwise, only specify the name of the vlan when creating it.
 func (p *PaloAlto) CreateVlan(name string, vlaninterface ...string) error {
+	return p.CreateVlanContext(context.Background(), name, vlaninterface...)
+}
+
+// CreateVlanContext is the same as CreateVlan, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateVlanContext(ctx context.Context, name string, vlaninterface ...string) error {
 	var xmlBody string
-	var reqError requestError
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot create vlans on a Panorama device")
 	}
 
 	xpath := "/config/devices/entry[@name='localhost.localdomain']/network/vlan"
-	xmlBody = fmt.Sprintf("<entry name=\"%s\"/>", name)
+	xmlBody = fmt.Sprintf("<entry name=\"%s\"/>", xmlEscape(name))
 
 	if len(vlaninterface) > 0 {
-		xmlBody = fmt.Sprintf("<entry name=\"%s\"><virtual-interface><interface>%s</interface></virtual-interface></entry>", name, vlaninterface[0])
+		xmlBody = fmt.Sprintf("<entry name=\"%s\"><virtual-interface><interface>%s</interface></virtual-interface></entry>", xmlEscape(name), xmlEscape(vlaninterface[0]))
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // AddInterfaceToVlan will add an interface or interfaces to the given vlan. Separate multiple
 // interfaces using a comma (e.g. "ethernet1/2, ethernet1/3").
 func (p *PaloAlto) AddInterfaceToVlan(vlan, ifname string) error {
+	return p.AddInterfaceToVlanContext(context.Background(), vlan, ifname)
+}
+
+// AddInterfaceToVlanContext is the same as AddInterfaceToVlan, but uses ctx for all of its API requests.
+func (p *PaloAlto) AddInterfaceToVlanContext(ctx context.Context, vlan, ifname string) error {
 	var xmlBody string
-	var reqError requestError
 	ints := strings.Split(ifname, ",")
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot add interfaces to a vlan on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/vlan/entry[@name='%s']", vlan)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/vlan/entry[@name=%s]", xpathQuote(vlan))
 	xmlBody = "<interface>"
 	for _, i := range ints {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(i))
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(i)))
 	}
 	xmlBody += "</interface>"
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // RemoveInterfaceFromVlan removes a given interface from the specified vlan.
 func (p *PaloAlto) RemoveInterfaceFromVlan(vlan, ifname string) error {
-	var reqError requestError
+	return p.RemoveInterfaceFromVlanContext(context.Background(), vlan, ifname)
+}
+
+// RemoveInterfaceFromVlanContext is the same as RemoveInterfaceFromVlan, but uses ctx for all of its API requests.
+func (p *PaloAlto) RemoveInterfaceFromVlanContext(ctx context.Context, vlan, ifname string) error {
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot remove interfaces from a vlan on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/vlan/entry[@name='%s']/interface/member[text()='%s']", vlan, ifname)
-
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/vlan/entry[@name=%s]/interface/member[text()=%s]", xpathQuote(vlan), xpathQuote(ifname))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // DeleteVlan removes a vlan from the device.
 func (p *PaloAlto) DeleteVlan(vlan string) error {
-	var reqError requestError
+	return p.DeleteVlanContext(context.Background(), vlan)
+}
+
+// DeleteVlanContext is the same as DeleteVlan, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteVlanContext(ctx context.Context, vlan string) error {
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot delete a vlan on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/vlan/entry[@name='%s']", vlan)
-
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/vlan/entry[@name=%s]", xpathQuote(vlan))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // CreateVwire creates a virtual-wire on the device. For the tagallowed parameter, enter integers (e.g. 10)
 // or ranges (100-200) separated by commas (e.g. 1-10,15,20-30). Integer values can be between 0 and 4094.
 func (p *PaloAlto) CreateVwire(name, interface1, interface2, tagallowed string) error {
+	return p.CreateVwireContext(context.Background(), name, interface1, interface2, tagallowed)
+}
+
+// CreateVwireContext is the same as CreateVwire, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateVwireContext(ctx context.Context, name, interface1, interface2, tagallowed string) error {
 	var xmlBody string
-	var reqError requestError
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot create vlans on a Panorama device")
 	}
 
 	xpath := "/config/devices/entry[@name='localhost.localdomain']/network/virtual-wire"
-	xmlBody = fmt.Sprintf("<entry name=\"%s\"><interface1>%s</interface1><interface2>%s</interface2><tag-allowed>%s</tag-allowed></entry>", name, interface1, interface2, tagallowed)
+	xmlBody = fmt.Sprintf("<entry name=\"%s\"><interface1>%s</interface1><interface2>%s</interface2><tag-allowed>%s</tag-allowed></entry>", xmlEscape(name), xmlEscape(interface1), xmlEscape(interface2), xmlEscape(tagallowed))
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // DeleteVwire removes a virtual-wire from the device.
 func (p *PaloAlto) DeleteVwire(name string) error {
-	var reqError requestError
+	return p.DeleteVwireContext(context.Background(), name)
+}
+
+// DeleteVwireContext is the same as DeleteVwire, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteVwireContext(ctx context.Context, name string) error {
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot delete a vlan on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-wire/entry[@name='%s']", name)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/virtual-wire/entry[@name=%s]", xpathQuote(name))
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -1006,6 +912,11 @@ func (p *PaloAlto) DeleteVwire(name string) error {
 //
 // s - static, c - complete, e - expiring, i - incomplete.
 func (p *PaloAlto) ARPTable(option ...string) (*ARPTable, error) {
+	return p.ARPTableContext(context.Background(), option...)
+}
+
+// ARPTableContext is the same as ARPTable, but uses ctx for all of its API requests.
+func (p *PaloAlto) ARPTableContext(ctx context.Context, option ...string) (*ARPTable, error) {
 	var arpTable ARPTable
 	command := "<show><arp><entry name = 'all'/></arp></show>"
 
@@ -1014,12 +925,12 @@ func (p *PaloAlto) ARPTable(option ...string) (*ARPTable, error) {
 	}
 
 	if len(option) > 0 {
-		command = fmt.Sprintf("<show><arp><entry name = '%s'/></arp></show>", option[0])
+		command = fmt.Sprintf("<show><arp><entry name = '%s'/></arp></show>", xmlEscape(option[0]))
 	}
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=op&cmd=%s&key=%s", command, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	resp, err := p.op(ctx, command)
+	if err != nil {
+		return nil, err
 	}
 
 	formatted := strings.Replace(resp, "  ", "", -1)
@@ -1032,6 +943,11 @@ func (p *PaloAlto) ARPTable(option ...string) (*ARPTable, error) {
 
 // IPSecTunnels will return a list of all configured IPsec tunnels on the device.
 func (p *PaloAlto) IPSecTunnels() (*Tunnels, error) {
+	return p.IPSecTunnelsContext(context.Background())
+}
+
+// IPSecTunnelsContext is the same as IPSecTunnels, but uses ctx for all of its API requests.
+func (p *PaloAlto) IPSecTunnelsContext(ctx context.Context) (*Tunnels, error) {
 	var tunnels Tunnels
 	xpath := "/config/devices/entry[@name='localhost.localdomain']/network/tunnel/ipsec"
 
@@ -1039,24 +955,25 @@ func (p *PaloAlto) IPSecTunnels() (*Tunnels, error) {
 		return nil, errors.New("tunnels can only be listed from a local device")
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	resp, err := p.getConfig(ctx, xpath)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(resp), &tunnels); err != nil {
 		return nil, err
 	}
 
-	if tunnels.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", tunnels.Code, errorCodes[tunnels.Code])
-	}
-
 	return &tunnels, nil
 }
 
 // IKEGateways will return a list of all configured IKE gateways on the device.
 func (p *PaloAlto) IKEGateways() (*Gateways, error) {
+	return p.IKEGatewaysContext(context.Background())
+}
+
+// IKEGatewaysContext is the same as IKEGateways, but uses ctx for all of its API requests.
+func (p *PaloAlto) IKEGatewaysContext(ctx context.Context) (*Gateways, error) {
 	var gws Gateways
 	xpath := "/config/devices/entry[@name='localhost.localdomain']/network/ike/gateway"
 
@@ -1064,24 +981,25 @@ func (p *PaloAlto) IKEGateways() (*Gateways, error) {
 		return nil, errors.New("IKE gateways can only be listed from a local device")
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	resp, err := p.getConfig(ctx, xpath)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(resp), &gws); err != nil {
 		return nil, err
 	}
 
-	if gws.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", gws.Code, errorCodes[gws.Code])
-	}
-
 	return &gws, nil
 }
 
 // CryptoProfiles will return a list of all configured IKE and IPSec crypto profiles on the device.
 func (p *PaloAlto) CryptoProfiles() (*EncryptionProfiles, error) {
+	return p.CryptoProfilesContext(context.Background())
+}
+
+// CryptoProfilesContext is the same as CryptoProfiles, but uses ctx for all of its API requests.
+func (p *PaloAlto) CryptoProfilesContext(ctx context.Context) (*EncryptionProfiles, error) {
 	var ike ikeCryptoProfiles
 	var ipsec ipsecCryptoProfiles
 	var profiles EncryptionProfiles
@@ -1092,32 +1010,24 @@ func (p *PaloAlto) CryptoProfiles() (*EncryptionProfiles, error) {
 		return nil, errors.New("IKE crypto profiles can only be listed from a local device")
 	}
 
-	_, ikeData, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", ikeXpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	ikeData, err := p.getConfig(ctx, ikeXpath)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(ikeData), &ike); err != nil {
 		return nil, err
 	}
 
-	if ike.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", ike.Code, errorCodes[ike.Code])
-	}
-
-	_, ipsecData, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", ipsecXpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	ipsecData, err := p.getConfig(ctx, ipsecXpath)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(ipsecData), &ipsec); err != nil {
 		return nil, err
 	}
 
-	if ipsec.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", ipsec.Code, errorCodes[ipsec.Code])
-	}
-
 	profiles.IKE = ike.Profiles
 	profiles.IPSec = ipsec.Profiles
 
@@ -1126,55 +1036,45 @@ func (p *PaloAlto) CryptoProfiles() (*EncryptionProfiles, error) {
 
 // AddProxyID will add a new proxy-id to the given IPsec tunnel.
 func (p *PaloAlto) AddProxyID(tunnel, name, localip, remoteip string) error {
+	return p.AddProxyIDContext(context.Background(), tunnel, name, localip, remoteip)
+}
+
+// AddProxyIDContext is the same as AddProxyID, but uses ctx for all of its API requests.
+func (p *PaloAlto) AddProxyIDContext(ctx context.Context, tunnel, name, localip, remoteip string) error {
 	var xmlBody string
-	var reqError requestError
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot add a proxy-id on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/tunnel/ipsec/entry[@name='%s']/auto-key/proxy-id/entry[@name='%s']", tunnel, name)
-	xmlBody = fmt.Sprintf("<protocol><any/></protocol><local>%s</local><remote>%s</remote>", localip, remoteip)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/tunnel/ipsec/entry[@name=%s]/auto-key/proxy-id/entry[@name=%s]", xpathQuote(tunnel), xpathQuote(name))
+	xmlBody = fmt.Sprintf("<protocol><any/></protocol><local>%s</local><remote>%s</remote>", xmlEscape(localip), xmlEscape(remoteip))
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // DeleteProxyID will remove a proxy-id from the given IPsec tunnel.
 func (p *PaloAlto) DeleteProxyID(tunnel, name string) error {
-	var reqError requestError
+	return p.DeleteProxyIDContext(context.Background(), tunnel, name)
+}
+
+// DeleteProxyIDContext is the same as DeleteProxyID, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteProxyIDContext(ctx context.Context, tunnel, name string) error {
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot delete a proxy-id on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/tunnel/ipsec/entry[@name='%s']/auto-key/proxy-id/entry[@name='%s']", tunnel, name)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/tunnel/ipsec/entry[@name=%s]/auto-key/proxy-id/entry[@name=%s]", xpathQuote(tunnel), xpathQuote(name))
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -1190,8 +1090,12 @@ func (p *PaloAlto) DeleteProxyID(tunnel, name string) error {
 // For lifetime, you must specify the value, followed by seconds, minutes, hours, or days,
 // all surrounded in quotes (e.g. "8 hours" or "86400 seconds").
 func (p *PaloAlto) CreateIKEProfile(name, encryption, authentication, dhgroup string, lifetime string) error {
+	return p.CreateIKEProfileContext(context.Background(), name, encryption, authentication, dhgroup, lifetime)
+}
+
+// CreateIKEProfileContext is the same as CreateIKEProfile, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateIKEProfileContext(ctx context.Context, name, encryption, authentication, dhgroup string, lifetime string) error {
 	var xmlBody string
-	var reqError requestError
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot create IKE profiles on a Panorama device")
@@ -1199,40 +1103,31 @@ func (p *PaloAlto) CreateIKEProfile(name, encryption, authentication, dhgroup st
 
 	lt := strings.Split(lifetime, " ")
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/ike/crypto-profiles/ike-crypto-profiles/entry[@name='%s']", name)
-	xmlBody = fmt.Sprintf("<lifetime><%s>%s</%s></lifetime>", lt[1], lt[0], lt[1])
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/ike/crypto-profiles/ike-crypto-profiles/entry[@name=%s]", xpathQuote(name))
+	xmlBody = fmt.Sprintf("<lifetime><%s>%s</%s></lifetime>", lt[1], xmlEscape(lt[0]), lt[1])
 
 	xmlBody += "<encryption>"
 	for _, encr := range strings.Split(encryption, ", ") {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(encr))
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(encr)))
 	}
 	xmlBody += "</encryption>"
 
 	xmlBody += "<hash>"
 	for _, hash := range strings.Split(authentication, ", ") {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(hash))
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(hash)))
 	}
 	xmlBody += "</hash>"
 
 	xmlBody += "<dh-group>"
 	for _, dh := range strings.Split(dhgroup, ", ") {
-		xmlBody += fmt.Sprintf("<member>group%s</member>", strings.TrimSpace(dh))
+		xmlBody += fmt.Sprintf("<member>group%s</member>", xmlEscape(strings.TrimSpace(dh)))
 	}
 	xmlBody += "</dh-group>"
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -1248,8 +1143,12 @@ func (p *PaloAlto) CreateIKEProfile(name, encryption, authentication, dhgroup st
 // For lifetime, you must specify the value, followed by seconds, minutes, hours, or days,
 // all surrounded in quotes (e.g. "8 hours" or "86400 seconds").
 func (p *PaloAlto) CreateIPSecProfile(name, encryption, authentication, lifetime string, dhgroup ...string) error {
+	return p.CreateIPSecProfileContext(context.Background(), name, encryption, authentication, lifetime, dhgroup...)
+}
+
+// CreateIPSecProfileContext is the same as CreateIPSecProfile, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateIPSecProfileContext(ctx context.Context, name, encryption, authentication, lifetime string, dhgroup ...string) error {
 	var xmlBody string
-	var reqError requestError
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot create IPSec profiles on a Panorama device")
@@ -1257,44 +1156,35 @@ func (p *PaloAlto) CreateIPSecProfile(name, encryption, authentication, lifetime
 
 	lt := strings.Split(lifetime, " ")
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/ike/crypto-profiles/ipsec-crypto-profiles/entry[@name='%s']", name)
-	xmlBody = fmt.Sprintf("<lifetime><%s>%s</%s></lifetime>", lt[1], lt[0], lt[1])
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/ike/crypto-profiles/ipsec-crypto-profiles/entry[@name=%s]", xpathQuote(name))
+	xmlBody = fmt.Sprintf("<lifetime><%s>%s</%s></lifetime>", lt[1], xmlEscape(lt[0]), lt[1])
 
 	xmlBody += "<esp><encryption>"
 	for _, encr := range strings.Split(encryption, ", ") {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(encr))
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(encr)))
 	}
 	xmlBody += "</encryption>"
 
 	xmlBody += "<authentication>"
 	for _, hash := range strings.Split(authentication, ", ") {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(hash))
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(hash)))
 	}
 	xmlBody += "</authentication></esp>"
 
 	if len(dhgroup) > 0 {
 		xmlBody += "<dh-group>"
 		for _, dh := range strings.Split(dhgroup[0], ", ") {
-			xmlBody += fmt.Sprintf("<member>group%s</member>", strings.TrimSpace(dh))
+			xmlBody += fmt.Sprintf("<member>group%s</member>", xmlEscape(strings.TrimSpace(dh)))
 		}
 		xmlBody += "</dh-group>"
 	} else {
 		xmlBody += "<dh-group>no-pfs</dh-group>"
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -1308,8 +1198,12 @@ func (p *PaloAlto) CreateIPSecProfile(name, encryption, authentication, lifetime
 // crypto profile on the device. The options parameter is optional, and contains additional IKE
 // parameters that you can set. Please see the documentation for the IKEOptions struct.
 func (p *PaloAlto) CreateIKEGateway(name, version, local, peer, psk, mode, profile string, options ...*IKEOptions) error {
+	return p.CreateIKEGatewayContext(context.Background(), name, version, local, peer, psk, mode, profile, options...)
+}
+
+// CreateIKEGatewayContext is the same as CreateIKEGateway, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateIKEGatewayContext(ctx context.Context, name, version, local, peer, psk, mode, profile string, options ...*IKEOptions) error {
 	var xmlBody string
-	var reqError requestError
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot create IKE gateways on a Panorama device")
@@ -1317,9 +1211,9 @@ func (p *PaloAlto) CreateIKEGateway(name, version, local, peer, psk, mode, profi
 
 	localaddr := strings.Split(local, " ")
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/ike/gateway/entry[@name='%s']", name)
-	xmlBody += fmt.Sprintf("<authentication><pre-shared-key><key>%s</key></pre-shared-key></authentication>", psk)
-	xmlBody += fmt.Sprintf("<protocol><ikev1><ike-crypto-profile>%s</ike-crypto-profile><exchange-mode>%s</exchange-mode>", profile, mode)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/ike/gateway/entry[@name=%s]", xpathQuote(name))
+	xmlBody += fmt.Sprintf("<authentication><pre-shared-key><key>%s</key></pre-shared-key></authentication>", xmlEscape(psk))
+	xmlBody += fmt.Sprintf("<protocol><ikev1><ike-crypto-profile>%s</ike-crypto-profile><exchange-mode>%s</exchange-mode>", xmlEscape(profile), xmlEscape(mode))
 
 	if len(options) > 0 {
 		if options[0].DPDInterval > 0 && options[0].DPDRetry > 0 {
@@ -1328,7 +1222,7 @@ func (p *PaloAlto) CreateIKEGateway(name, version, local, peer, psk, mode, profi
 	}
 
 	xmlBody += "</ikev1>"
-	xmlBody += fmt.Sprintf("<ikev2><ike-crypto-profile>%s</ike-crypto-profile><dpd><enable>no</enable></dpd>", profile)
+	xmlBody += fmt.Sprintf("<ikev2><ike-crypto-profile>%s</ike-crypto-profile><dpd><enable>no</enable></dpd>", xmlEscape(profile))
 
 	if len(options) > 0 {
 		if options[0].RequireCookie == true {
@@ -1336,30 +1230,30 @@ func (p *PaloAlto) CreateIKEGateway(name, version, local, peer, psk, mode, profi
 		}
 	}
 
-	xmlBody += fmt.Sprintf("</ikev2><version>ike%s</version></protocol>", version)
+	xmlBody += fmt.Sprintf("</ikev2><version>ike%s</version></protocol>", xmlEscape(version))
 
 	switch len(localaddr) {
 	case 1:
-		xmlBody += fmt.Sprintf("<local-address><interface>%s</interface></local-address>", localaddr[0])
+		xmlBody += fmt.Sprintf("<local-address><interface>%s</interface></local-address>", xmlEscape(localaddr[0]))
 	case 2:
-		xmlBody += fmt.Sprintf("<local-address><interface>%s</interface><ip>%s</ip></local-address>", localaddr[0], localaddr[1])
+		xmlBody += fmt.Sprintf("<local-address><interface>%s</interface><ip>%s</ip></local-address>", xmlEscape(localaddr[0]), xmlEscape(localaddr[1]))
 	}
 
 	if peer == "dynamic" {
 		xmlBody += "<peer-address><dynamic/></peer-address>"
 	} else {
-		xmlBody += fmt.Sprintf("<peer-address><ip>%s</ip></peer-address>", peer)
+		xmlBody += fmt.Sprintf("<peer-address><ip>%s</ip></peer-address>", xmlEscape(peer))
 	}
 
 	if len(options) > 0 {
 		if len(options[0].LocalID) > 0 {
-			xmlBody += fmt.Sprintf("<local-id><type>%s</type><id>%s</id></local-id>", options[0].LocalIDType, options[0].LocalID)
+			xmlBody += fmt.Sprintf("<local-id><type>%s</type><id>%s</id></local-id>", xmlEscape(options[0].LocalIDType), xmlEscape(options[0].LocalID))
 		}
 	}
 
 	if len(options) > 0 {
 		if len(options[0].PeerID) > 0 {
-			xmlBody += fmt.Sprintf("<peer-id><type>%s</type><id>%s</id></peer-id>", options[0].PeerIDType, options[0].PeerID)
+			xmlBody += fmt.Sprintf("<peer-id><type>%s</type><id>%s</id></peer-id>", xmlEscape(options[0].PeerIDType), xmlEscape(options[0].PeerID))
 		}
 	}
 
@@ -1375,19 +1269,10 @@ func (p *PaloAlto) CreateIKEGateway(name, version, local, peer, psk, mode, profi
 		}
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -1395,37 +1280,37 @@ func (p *PaloAlto) CreateIKEGateway(name, version, local, peer, psk, mode, profi
 // be the name of a tunnel interface (e.g. "tunnel.1"). The gateway and profile settings
 // must contain the name of a pre-existing IKE gateway and IPSec crypto profile, respectively.
 func (p *PaloAlto) CreateIPSecTunnel(name, iface, gateway, profile string) error {
+	return p.CreateIPSecTunnelContext(context.Background(), name, iface, gateway, profile)
+}
+
+// CreateIPSecTunnelContext is the same as CreateIPSecTunnel, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateIPSecTunnelContext(ctx context.Context, name, iface, gateway, profile string) error {
 	var xmlBody string
-	var reqError requestError
 
 	if p.DeviceType == "panorama" {
 		return errors.New("you cannot create IPSec tunnels on a Panorama device")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/tunnel/ipsec/entry[@name='%s']", name)
-	xmlBody = fmt.Sprintf("<auto-key><ike-gateway><entry name=\"%s\"/></ike-gateway><ipsec-crypto-profile>%s</ipsec-crypto-profile></auto-key>", gateway, profile)
-	xmlBody += fmt.Sprintf("<tunnel-interface>%s</tunnel-interface>", iface)
-
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/network/tunnel/ipsec/entry[@name=%s]", xpathQuote(name))
+	xmlBody = fmt.Sprintf("<auto-key><ike-gateway><entry name=\"%s\"/></ike-gateway><ipsec-crypto-profile>%s</ipsec-crypto-profile></auto-key>", xmlEscape(gateway), xmlEscape(profile))
+	xmlBody += fmt.Sprintf("<tunnel-interface>%s</tunnel-interface>", xmlEscape(iface))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // InterfaceInfo will gather all of the logical and physical interface information from a firewall.
 func (p *PaloAlto) InterfaceInfo() (*InterfaceInformation, error) {
+	return p.InterfaceInfoContext(context.Background())
+}
+
+// InterfaceInfoContext is the same as InterfaceInfo, but uses ctx for all of its API requests.
+func (p *PaloAlto) InterfaceInfoContext(ctx context.Context) (*InterfaceInformation, error) {
 	var ifs InterfaceInformation
-	cmd := fmt.Sprintf("%s&key=%s&type=op&cmd=<show><interface>all</interface></show>", p.URI, p.Key)
+	cmd := "<show><interface>all</interface></show>"
 
 	if p.DeviceType != "panos" {
 		return nil, errors.New("you can only retrieve interface information on a local firewall")
@@ -1435,18 +1320,14 @@ func (p *PaloAlto) InterfaceInfo() (*InterfaceInformation, error) {
 	// 	cmd = fmt.Sprintf("%s&key=%s&type=op&cmd=<show><interface>%s</interface></show>", p.URI, p.Key, name[0])
 	// }
 
-	_, resp, errs := r.Post(p.URI).Query(cmd).End()
-	if errs != nil {
-		return nil, errs[0]
+	resp, err := p.op(ctx, cmd)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(resp), &ifs); err != nil {
 		return nil, err
 	}
 
-	if ifs.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", ifs.Code, errorCodes[ifs.Code])
-	}
-
 	return &ifs, nil
 }
@@ -1,11 +1,11 @@
 package panos
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"strings"
-	"time"
 
 	easycsv "github.com/scottdware/go-easycsv"
 )
@@ -138,39 +138,41 @@ var (
 	}
 )
 
-// URLCategory returns a list of all custom URL category objects. You can (optionally) specify a device-group
-// when ran against a Panorama device. If no device-group is specified, then all objects are returned.
-func (p *PaloAlto) URLCategory(devicegroup ...string) (*URLCategory, error) {
+// URLCategory returns a list of all custom URL category objects. You can (optionally) specify a location, such as a
+// device-group when ran against a Panorama device. If no location is specified, then all objects are returned.
+func (p *PaloAlto) URLCategory(location ...Location) (*URLCategory, error) {
+	return p.URLCategoryContext(context.Background(), location...)
+}
+
+// URLCategoryContext is the same as URLCategory, but uses ctx for all of its API requests.
+func (p *PaloAlto) URLCategoryContext(ctx context.Context, location ...Location) (*URLCategory, error) {
 	var urls URLCategory
 	xpath := "/config/devices/entry//custom-url-category"
 
-	if p.DeviceType != "panorama" && len(devicegroup) > 0 {
-		return nil, errors.New("you must be connected to a Panorama device when specifying a device-group")
-	}
-
 	if p.DeviceType == "panos" && p.Panorama == true {
 		xpath = "/config/panorama//custom-url-category"
 	}
 
 	if p.DeviceType == "panos" && p.Panorama == false {
-		xpath = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/profiles/custom-url-category"
+		xpath = p.vsysXpath() + "/profiles/custom-url-category"
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/profiles/custom-url-category", devicegroup[0])
-	}
+	if len(location) > 0 {
+		base, err := p.locationXpath(location...)
+		if err != nil {
+			return nil, err
+		}
 
-	_, urlData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+		xpath = base + "/profiles/custom-url-category"
 	}
 
-	if err := xml.Unmarshal([]byte(urlData), &urls); err != nil {
+	urlData, err := p.getConfig(ctx, xpath)
+	if err != nil {
 		return nil, err
 	}
 
-	if urls.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", urls.Code, errorCodes[urls.Code])
+	if err := xml.Unmarshal([]byte(urlData), &urls); err != nil {
+		return nil, err
 	}
 
 	return &urls, nil
@@ -178,2071 +180,658 @@ func (p *PaloAlto) URLCategory(devicegroup ...string) (*URLCategory, error) {
 
 // CreateURLCategory creates a custom URL category to be used in a policy. When specifying multiple URL's, use a
 // []string variable for the url parameter (e.g. members := []string{"www.*.com", "*.somesite.net"}). If creating a
-// URL category on a Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) CreateURLCategory(name string, urls []string, description string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
+// URL category on a Panorama device, specify its location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) CreateURLCategory(name string, urls []string, description string, location ...Location) error {
+	return p.CreateURLCategoryContext(context.Background(), name, urls, description, location...)
+}
+
+// CreateURLCategoryContext is the same as CreateURLCategory, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateURLCategoryContext(ctx context.Context, name string, urls []string, description string, location ...Location) error {
 
 	xmlBody := "<list>"
 	for _, m := range urls {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(m))
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(m)))
 	}
 	xmlBody += "</list>"
 
 	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
-	}
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/profiles/custom-url-category/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == true {
-		xpath = fmt.Sprintf("/config/shared/profiles/custom-url-category/entry[@name='%s']", name)
+		xmlBody += fmt.Sprintf("<description>%s</description>", xmlEscape(description))
 	}
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/profiles/custom-url-category/entry[@name='%s']", devicegroup[0], name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when creating a URL category on a Panorama device")
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("%s/profiles/custom-url-category/entry[@name=%s]", base, xpathQuote(name))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // EditURLCategory adds or removes URL's from the given custom URL category. Action must be add or remove If editing
-// a URL category on a Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) EditURLCategory(action, url, name string, devicegroup ...string) error {
-	var xpath string
-	var xmlBody string
-	var reqError requestError
-
-	query := fmt.Sprintf("type=config&key=%s", p.Key)
-
-	if p.DeviceType == "panos" {
-		if action == "add" {
-			xmlBody += fmt.Sprintf("<member>%s</member>", url)
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/profiles/custom-url-category/entry[@name='%s']/list", name)
-
-			query += fmt.Sprintf("&action=set&xpath=%s&element=%s", xpath, xmlBody)
-		}
-
-		if action == "remove" {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/profiles/custom-url-category/entry[@name='%s']/list/member[text()='%s']", name, url)
+// a URL category on a Panorama device, specify its location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) EditURLCategory(action, url, name string, location ...Location) error {
+	return p.EditURLCategoryContext(context.Background(), action, url, name, location...)
+}
 
-			query += fmt.Sprintf("&action=delete&xpath=%s", xpath)
-		}
+// EditURLCategoryContext is the same as EditURLCategory, but uses ctx for all of its API requests.
+func (p *PaloAlto) EditURLCategoryContext(ctx context.Context, action, url, name string, location ...Location) error {
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && p.Shared == true {
-		if action == "add" {
-			xmlBody = fmt.Sprintf("<member>%s</member>", url)
-			xpath = fmt.Sprintf("/config/shared/profiles/custom-url-category/entry[@name='%s']/list", name)
-
-			query += fmt.Sprintf("&action=set&xpath=%s&element=%s", xpath, xmlBody)
-		}
-
-		if action == "remove" {
-			xpath = fmt.Sprintf("/config/shared/profiles/custom-url-category/entry[@name='%s']/list/member[text()='%s']", name, url)
+	xpath := fmt.Sprintf("%s/profiles/custom-url-category/entry[@name=%s]/list", base, xpathQuote(name))
 
-			query += fmt.Sprintf("&action=delete&xpath=%s", xpath)
-		}
+	switch action {
+	case "add":
+		return p.setConfig(ctx, xpath, fmt.Sprintf("<member>%s</member>", xmlEscape(url)))
+	case "remove":
+		return p.deleteConfig(ctx, fmt.Sprintf("%s/member[text()=%s]", xpath, xpathQuote(url)))
 	}
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-		if action == "add" {
-			xmlBody = fmt.Sprintf("<member>%s</member>", url)
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/profiles/custom-url-category/entry[@name='%s']/list", devicegroup[0], name)
-
-			query += fmt.Sprintf("&action=set&xpath=%s&element=%s", xpath, xmlBody)
-		}
+	return fmt.Errorf("invalid action %q - action must be add or remove", action)
+}
 
-		if action == "remove" {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/profiles/custom-url-category/entry[@name='%s']/list/member[text()='%s']", devicegroup[0], name, url)
+// DeleteURLCategory removes a custom URL category from the device. If deleting a URL category on a
+// Panorama device, specify its location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) DeleteURLCategory(name string, location ...Location) error {
+	return p.DeleteURLCategoryContext(context.Background(), name, location...)
+}
 
-			query += fmt.Sprintf("&action=delete&xpath=%s", xpath)
-		}
-	}
+// DeleteURLCategoryContext is the same as DeleteURLCategory, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteURLCategoryContext(ctx context.Context, name string, location ...Location) error {
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when editing a URL category on a Panorama device")
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(query).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("%s/profiles/custom-url-category/entry[@name=%s]", base, xpathQuote(name))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
-// DeleteURLCategory removes a custom URL category from the device. If deleting a URL category on a
-// Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) DeleteURLCategory(name string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
+// EditGroup will add or remove objects from the specified group type (e.g., "address" or "service"). Action must be
+// add or remove. If editing a group on a Panorama device, specify its location (e.g. DeviceGroupLocation("name") or
+// SharedLocation()) as the last parameter.
+func (p *PaloAlto) EditGroup(objecttype, action, object, group string, location ...Location) error {
+	return p.EditGroupContext(context.Background(), objecttype, action, object, group, location...)
+}
 
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/profiles/custom-url-category/entry[@name='%s']", name)
+// EditGroupContext is the same as EditGroup, but uses ctx for all of its API requests.
+func (p *PaloAlto) EditGroupContext(ctx context.Context, objecttype, action, object, group string, location ...Location) error {
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && p.Shared == true {
-		xpath = fmt.Sprintf("/config/shared/profiles/custom-url-category/entry[@name='%s']", name)
+	xpath := fmt.Sprintf("%s/address-group/entry[@name=%s]/static", base, xpathQuote(group))
+	if objecttype == "service" {
+		xpath = fmt.Sprintf("%s/service-group/entry[@name=%s]/members", base, xpathQuote(group))
 	}
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/profiles/custom-url-category/entry[@name='%s']", devicegroup[0], name)
+	switch action {
+	case "add":
+		return p.setConfig(ctx, xpath, fmt.Sprintf("<member>%s</member>", xmlEscape(object)))
+	case "remove":
+		return p.deleteConfig(ctx, fmt.Sprintf("%s/member[text()=%s]", xpath, xpathQuote(object)))
 	}
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when deleting a URL category on a Panorama device")
-	}
+	return fmt.Errorf("invalid action %q - action must be add or remove", action)
+}
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
+// RenameObject will rename the given object from oldname to the newname. You can rename the following
+// object types:
+//
+// address, address-groups, service, service-groups, tags.
+//
+// If renaming objects on a Panorama device, specify its location (e.g. DeviceGroupLocation("name") or
+// SharedLocation()) as the last parameter.
+func (p *PaloAlto) RenameObject(oldname, newname string, location ...Location) error {
+	return p.RenameObjectContext(context.Background(), oldname, newname, location...)
+}
+
+// RenameObjectContext is the same as RenameObject, but uses ctx for all of its API requests.
+func (p *PaloAlto) RenameObjectContext(ctx context.Context, oldname, newname string, location ...Location) error {
+	objtype, err := p.objectType(ctx, oldname, "address", "address-group", "service", "service-group", "tag")
+	if err != nil || objtype == "" {
+		return err
 	}
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	base, err := p.locationXpath(location...)
+	if err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+	xpath := fmt.Sprintf("%s/%s/entry[@name=%s]", base, objtype, xpathQuote(oldname))
+
+	if err := p.renameConfig(ctx, xpath, newname); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-// EditGroup will add or remove objects from the specified group type (e.g., "address" or "service"). Action must be
-// add or remove. If editing a group on a Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) EditGroup(objecttype, action, object, group string, devicegroup ...string) error {
-	var xmlBody string
-	var xpath string
-	var reqError requestError
-
-	query := fmt.Sprintf("type=config&key=%s", p.Key)
+// objectType returns which of the given object types (address, address-group, service, service-group or tag) the
+// object with the given name is, checking each type in order. If there is no such object, a blank string is returned.
+func (p *PaloAlto) objectType(ctx context.Context, name string, types ...string) (string, error) {
+	for _, objtype := range types {
+		var names []string
 
-	if p.DeviceType == "panos" {
-		if action == "add" {
-			xmlBody = fmt.Sprintf("<member>%s</member>", object)
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']/static", group)
-			if objecttype == "service" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']/members", group)
+		switch objtype {
+		case "address":
+			objs, err := p.AddressesContext(ctx)
+			if err != nil {
+				return "", err
 			}
 
-			query += fmt.Sprintf("&action=set&xpath=%s&element=%s", xpath, xmlBody)
-		}
+			for _, o := range objs.Addresses {
+				names = append(names, o.Name)
+			}
+		case "address-group":
+			objs, err := p.AddressGroupsContext(ctx)
+			if err != nil {
+				return "", err
+			}
 
-		if action == "remove" {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']/static/member[text()='%s']", group, object)
-			if objecttype == "service" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']/members/member[text()='%s']", group, object)
+			for _, o := range objs.Groups {
+				names = append(names, o.Name)
+			}
+		case "service":
+			objs, err := p.ServicesContext(ctx)
+			if err != nil {
+				return "", err
 			}
 
-			query += fmt.Sprintf("&action=delete&xpath=%s", xpath)
-		}
-	}
+			for _, o := range objs.Services {
+				names = append(names, o.Name)
+			}
+		case "service-group":
+			objs, err := p.ServiceGroupsContext(ctx)
+			if err != nil {
+				return "", err
+			}
 
-	if p.DeviceType == "panorama" && p.Shared == true {
-		if action == "add" {
-			xmlBody = fmt.Sprintf("<member>%s</member>", object)
-			xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']/static", group)
-			if objecttype == "service" {
-				xpath = fmt.Sprintf("/config/shared/service-group/entry[@name='%s']/members", group)
+			for _, o := range objs.Groups {
+				names = append(names, o.Name)
+			}
+		case "tag":
+			objs, err := p.TagsContext(ctx)
+			if err != nil {
+				return "", err
 			}
 
-			query += fmt.Sprintf("&action=set&xpath=%s&element=%s", xpath, xmlBody)
+			for _, o := range objs.Tags {
+				names = append(names, o.Name)
+			}
 		}
 
-		if action == "remove" {
-			xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']/static/member[text()='%s']", group, object)
-			if objecttype == "service" {
-				xpath = fmt.Sprintf("/config/shared/service-group/entry[@name='%s']/members/member[text()='%s']", group, object)
+		for _, n := range names {
+			if n == name {
+				return objtype, nil
 			}
-
-			query += fmt.Sprintf("&action=delete&xpath=%s", xpath)
 		}
 	}
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-		if action == "add" {
-			xmlBody = fmt.Sprintf("<member>%s</member>", object)
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']/static", devicegroup[0], group)
-			if objecttype == "service" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']/members", devicegroup[0], group)
-			}
+	return "", nil
+}
 
-			query += fmt.Sprintf("&action=set&xpath=%s&element=%s", xpath, xmlBody)
-		}
+// CreateExternalDynamicList will create an external dynamic list on the device. Listtype must be one of:
+//
+// ip, domain, or url
+//
+// Configuring the recurrance requires you to use the `Recurrance` struct when passing the configuration for this
+// parameter - please see the documentation for that struct. If creating an EDL on a Panorama device, specify
+// its location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) CreateExternalDynamicList(listtype string, name string, url string, recurrance *Recurrance, location ...Location) error {
+	return p.CreateExternalDynamicListContext(context.Background(), listtype, name, url, recurrance, location...)
+}
 
-		if action == "remove" {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']/static/member[text()='%s']", devicegroup[0], group, object)
-			if objecttype == "service" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']/members/member[text()='%s']", devicegroup[0], group, object)
-			}
+// CreateExternalDynamicListContext is the same as CreateExternalDynamicList, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateExternalDynamicListContext(ctx context.Context, listtype string, name string, url string, recurrance *Recurrance, location ...Location) error {
+	var xmlBody string
+	var recurring string
 
-			query += fmt.Sprintf("&action=delete&xpath=%s", xpath)
-		}
+	switch recurrance.Method {
+	case "hourly":
+		recurring = "<hourly/>"
+	case "five-minute":
+		recurring = "<five-minute/>"
+	case "daily":
+		recurring = fmt.Sprintf("<daily><at>%s</at></daily>", xmlEscape(recurrance.Hour))
+	case "weekly":
+		recurring = fmt.Sprintf("<weekly><day-of-week>%s</day-of-week><at>%s</at></weekly>", xmlEscape(recurrance.DayOfWeek), xmlEscape(recurrance.Hour))
+	case "monthly":
+		recurring = fmt.Sprintf("<monthly><day-of-month>%d</day-of-month><at>%s</at></monthly>", recurrance.DayOfMonth, xmlEscape(recurrance.Hour))
 	}
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when editing a shared group on a Panorama device")
-	}
+	xmlBody = fmt.Sprintf("<type><%s><recurring>%s</recurring><url>%s</url></%s></type>", listtype, recurring, xmlEscape(url), listtype)
 
-	_, resp, errs := r.Post(p.URI).Query(query).End()
-	if errs != nil {
-		return errs[0]
+	if p.olderThan(8, 0) {
+		xmlBody = fmt.Sprintf("<recurring>%s</recurring><url>%s</url><type>%s</type>", recurring, xmlEscape(url), xmlEscape(listtype))
 	}
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	base, err := p.locationXpath(location...)
+	if err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+	xpath := fmt.Sprintf("%s/external-list/entry[@name=%s]", base, xpathQuote(name))
+
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-// RenameObject will rename the given object from oldname to the newname. You can rename the following
-// object types:
-//
-// address, address-groups, service, service-groups, tags.
-//
-// If renaming objects on a Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) RenameObject(oldname, newname string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-	adObj, _ := p.Addresses()
-	agObj, _ := p.AddressGroups()
-	sObj, _ := p.Services()
-	sgObj, _ := p.ServiceGroups()
-	tags, _ := p.Tags()
-
-	for _, a := range adObj.Addresses {
-		if oldname == a.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='%s']", oldname)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=rename&xpath=%s&newname=%s&key=%s", xpath, newname, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" {
-				if p.Shared == true {
-					xpath = fmt.Sprintf("/config/shared/address/entry[@name='%s']", oldname)
-				}
-
-				if len(devicegroup) > 0 && devicegroup[0] == "shared" {
-					xpath = fmt.Sprintf("/config/shared/address/entry[@name='%s']", oldname)
-				}
-
-				if p.Shared == false && len(devicegroup) > 0 && devicegroup[0] != "shared" {
-					xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address/entry[@name='%s']", devicegroup[0], oldname)
-				}
-
-				if p.Shared == false && len(devicegroup) <= 0 {
-					return errors.New("you must specify a device-group when renaming objects on a Panorama device")
-				}
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=rename&xpath=%s&newname=%s&key=%s", xpath, newname, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
+// DeleteExternalDynamicList removes an external dynamic list from the device. If deleting an EDL on a
+// Panorama device, specify its location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) DeleteExternalDynamicList(name string, location ...Location) error {
+	return p.DeleteExternalDynamicListContext(context.Background(), name, location...)
+}
 
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+// DeleteExternalDynamicListContext is the same as DeleteExternalDynamicList, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteExternalDynamicListContext(ctx context.Context, name string, location ...Location) error {
 
-				return nil
-			}
-		}
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	for _, ag := range agObj.Groups {
-		if oldname == ag.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']", oldname)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=rename&xpath=%s&newname=%s&key=%s", xpath, newname, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" {
-				if p.Shared == true {
-					xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']", oldname)
-				}
-
-				if len(devicegroup) > 0 && devicegroup[0] == "shared" {
-					xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']", oldname)
-				}
-
-				if p.Shared == false && len(devicegroup) > 0 && devicegroup[0] != "shared" {
-					xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']", devicegroup[0], oldname)
-				}
-
-				if p.Shared == false && len(devicegroup) <= 0 {
-					return errors.New("you must specify a device-group when renaming objects on a Panorama device")
-				}
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=rename&xpath=%s&newname=%s&key=%s", xpath, newname, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+	xpath := fmt.Sprintf("%s/external-list/entry[@name=%s]", base, xpathQuote(name))
 
-				return nil
-			}
-		}
+	if err := p.deleteConfig(ctx, xpath); err != nil {
+		return err
 	}
 
-	for _, s := range sObj.Services {
-		if oldname == s.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service/entry[@name='%s']", oldname)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=rename&xpath=%s&newname=%s&key=%s", xpath, newname, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
+	return nil
+}
 
-			if p.DeviceType == "panorama" {
-				if p.Shared == true {
-					xpath = fmt.Sprintf("/config/shared/service/entry[@name='%s']", oldname)
-				}
+// Tags returns information about all tags on the system. You can (optionally) specify a location, such as
+// a device-group when ran against a Panorama device. If no location is specified, then all tags are returned, including
+// shared objects if run against a Panorama device.
+func (p *PaloAlto) Tags(location ...Location) (*Tags, error) {
+	return p.TagsContext(context.Background(), location...)
+}
 
-				if len(devicegroup) > 0 && devicegroup[0] == "shared" {
-					xpath = fmt.Sprintf("/config/shared/service/entry[@name='%s']", oldname)
-				}
+// TagsContext is the same as Tags, but uses ctx for all of its API requests.
+func (p *PaloAlto) TagsContext(ctx context.Context, location ...Location) (*Tags, error) {
+	var parsedTags xmlTags
+	var tags Tags
+	var tcolor string
+	xpath := "/config//tag"
 
-				if p.Shared == false && len(devicegroup) > 0 && devicegroup[0] != "shared" {
-					xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service/entry[@name='%s']", devicegroup[0], oldname)
-				}
+	if p.DeviceType == "panos" && p.Panorama == false {
+		xpath = p.vsysXpath() + "/tag"
+	}
 
-				if p.Shared == false && len(devicegroup) <= 0 {
-					return errors.New("you must specify a device-group when renaming objects on a Panorama device")
-				}
+	if len(location) > 0 {
+		base, err := p.locationXpath(location...)
+		if err != nil {
+			return nil, err
+		}
 
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=rename&xpath=%s&newname=%s&key=%s", xpath, newname, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
+		xpath = base + "/tag"
+	}
 
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
+	tData, err := p.getConfig(ctx, xpath)
+	if err != nil {
+		return nil, err
+	}
 
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+	if err := xml.Unmarshal([]byte(tData), &parsedTags); err != nil {
+		return nil, err
+	}
 
-				return nil
+	for _, t := range parsedTags.Tags {
+		tname := t.Name
+		for k, v := range tagColors {
+			if t.Color == v {
+				tcolor = k
 			}
 		}
+		tcomments := t.Comments
+
+		tags.Tags = append(tags.Tags, Tag{Name: tname, Color: tcolor, Comments: tcomments})
 	}
 
-	for _, sg := range sgObj.Groups {
-		if oldname == sg.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']", oldname)
+	return &tags, nil
 
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=rename&xpath=%s&newname=%s&key=%s", xpath, newname, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
+}
 
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
+// CreateTag will add a new tag to the device. You can use the following colors:
+//
+// Red, Green, Blue, Yellow, Copper, Orange, Purple, Gray, Light Green, Cyan, Light Gray,
+// Blue Gray, Lime, Black, Gold, Brown.
+//
+// If creating a tag on a Panorama device, specify its location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) CreateTag(name, color, comments string, location ...Location) error {
+	return p.CreateTagContext(context.Background(), name, color, comments, location...)
+}
 
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+// CreateTagContext is the same as CreateTag, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateTagContext(ctx context.Context, name, color, comments string, location ...Location) error {
+	var xmlBody string
 
-				return nil
-			}
+	xmlBody = fmt.Sprintf("<color>%s</color>", xmlEscape(tagColors[color]))
 
-			if p.DeviceType == "panorama" {
-				if p.Shared == true {
-					xpath = fmt.Sprintf("/config/shared/service-group/entry[@name='%s']", oldname)
-				}
+	if comments != "" {
+		xmlBody += fmt.Sprintf("<comments>%s</comments>", xmlEscape(comments))
+	}
 
-				if len(devicegroup) > 0 && devicegroup[0] == "shared" {
-					xpath = fmt.Sprintf("/config/shared/service-group/entry[@name='%s']", oldname)
-				}
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
+	}
 
-				if p.Shared == false && len(devicegroup) > 0 && devicegroup[0] != "shared" {
-					xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']", devicegroup[0], oldname)
-				}
+	xpath := fmt.Sprintf("%s/tag/entry[@name=%s]", base, xpathQuote(name))
 
-				if p.Shared == false && len(devicegroup) <= 0 {
-					return errors.New("you must specify a device-group when renaming objects on a Panorama device")
-				}
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
+		return err
+	}
 
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=rename&xpath=%s&newname=%s&key=%s", xpath, newname, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
+	return nil
+}
 
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
+// DeleteTag will remove a tag from the device. If deleting a tag on a Panorama device, specify its
+// location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) DeleteTag(name string, location ...Location) error {
+	return p.DeleteTagContext(context.Background(), name, location...)
+}
 
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+// DeleteTagContext is the same as DeleteTag, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteTagContext(ctx context.Context, name string, location ...Location) error {
 
-				return nil
-			}
-		}
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	for _, t := range tags.Tags {
-		if oldname == t.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/tag/entry[@name='%s']", oldname)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=rename&xpath=%s&newname=%s&key=%s", xpath, newname, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+	xpath := fmt.Sprintf("%s/tag/entry[@name=%s]", base, xpathQuote(name))
 
-				return nil
-			}
-
-			if p.DeviceType == "panorama" {
-				if p.Shared == true {
-					xpath = fmt.Sprintf("/config/shared/tag/entry[@name='%s']", oldname)
-				}
-
-				if len(devicegroup) > 0 && devicegroup[0] == "shared" {
-					xpath = fmt.Sprintf("/config/shared/tag/entry[@name='%s']", oldname)
-				}
-
-				if p.Shared == false && len(devicegroup) > 0 && devicegroup[0] != "shared" {
-					xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/tag/entry[@name='%s']", devicegroup[0], oldname)
-				}
-
-				if p.Shared == false && len(devicegroup) <= 0 {
-					return errors.New("you must specify a device-group when renaming objects on a Panorama device")
-				}
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=rename&xpath=%s&newname=%s&key=%s", xpath, newname, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-		}
-	}
-
-	return nil
-}
-
-// CreateExternalDynamicList will create an external dynamic list on the device. Listtype must be one of:
-//
-// ip, domain, or url
-//
-// Configuring the recurrance requires you to use the `Recurrance` struct when passing the configuration for this
-// parameter - please see the documentation for that struct. If creating an EDL on a Panorama device, specify
-// the device-group as the last parameter.
-func (p *PaloAlto) CreateExternalDynamicList(listtype string, name string, url string, recurrance *Recurrance, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-	var xmlBody string
-	var recurring string
-
-	ver := splitSWVersion(p.SoftwareVersion)
-
-	switch recurrance.Method {
-	case "hourly":
-		recurring = "<hourly/>"
-	case "five-minute":
-		recurring = "<five-minute/>"
-	case "daily":
-		recurring = fmt.Sprintf("<daily><at>%s</at></daily>", recurrance.Hour)
-	case "weekly":
-		recurring = fmt.Sprintf("<weekly><day-of-week>%s</day-of-week><at>%s</at></weekly>", recurrance.DayOfWeek, recurrance.Hour)
-	case "monthly":
-		recurring = fmt.Sprintf("<monthly><day-of-month>%d</day-of-month><at>%s</at></monthly>", recurrance.DayOfMonth, recurrance.Hour)
-	}
-
-	if ver[0] >= 8 {
-		xmlBody = fmt.Sprintf("<type><%s><recurring>%s</recurring><url>%s</url></%s></type>", listtype, recurring, url, listtype)
-	}
-
-	if ver[0] <= 7 {
-		xmlBody = fmt.Sprintf("<recurring>%s</recurring><url>%s</url><type>%s</type>", recurring, url, listtype)
-	}
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/external-list/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == true {
-		xpath = fmt.Sprintf("/config/shared/external-list/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/external-list/entry[@name='%s']", devicegroup[0], name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when creating an external dynamic list on a Panorama device")
-	}
-
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-		return err
-	}
-
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
-	return nil
-}
-
-// DeleteExternalDynamicList removes an external dynamic list from the device. If deleting an EDL on a
-// Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) DeleteExternalDynamicList(name string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/external-list/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == true {
-		xpath = fmt.Sprintf("/config/shared/external-list/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/external-list/entry[@name='%s']", devicegroup[0], name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when deleting a external dynamic list on a Panorama device")
-	}
-
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-		return err
-	}
-
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
-	return nil
-}
-
-// Tags returns information about all tags on the system. You can (optionally) specify a device-group
-// when ran against a Panorama device. If no device-group is specified, then all tags are returned, including
-// shared objects if run against a Panorama device.
-func (p *PaloAlto) Tags(devicegroup ...string) (*Tags, error) {
-	var parsedTags xmlTags
-	var tags Tags
-	var tcolor string
-	xpath := "/config//tag"
-
-	if p.DeviceType == "panos" {
-		if p.Panorama == true {
-			xpath = "/config//tag"
-		}
-
-		if p.Panorama == false {
-			xpath = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/tag"
-		}
-
-		if len(devicegroup) > 0 && len(devicegroup[0]) > 0 {
-			return nil, errors.New("you must be connected to a Panorama device when specifying a device-group")
-		}
-	}
-
-	if p.DeviceType == "panorama" {
-		if len(devicegroup) > 0 && len(devicegroup[0]) > 0 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/tag", devicegroup[0])
-		}
-	}
-
-	_, tData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(tData), &parsedTags); err != nil {
-		return nil, err
-	}
-
-	if parsedTags.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", parsedTags.Code, errorCodes[parsedTags.Code])
-	}
-
-	for _, t := range parsedTags.Tags {
-		tname := t.Name
-		for k, v := range tagColors {
-			if t.Color == v {
-				tcolor = k
-			}
-		}
-		tcomments := t.Comments
-
-		tags.Tags = append(tags.Tags, Tag{Name: tname, Color: tcolor, Comments: tcomments})
-	}
-
-	return &tags, nil
-
-}
-
-// CreateTag will add a new tag to the device. You can use the following colors:
-//
-// Red, Green, Blue, Yellow, Copper, Orange, Purple, Gray, Light Green, Cyan, Light Gray,
-// Blue Gray, Lime, Black, Gold, Brown.
-//
-// If creating a tag on a Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) CreateTag(name, color, comments string, devicegroup ...string) error {
-	var xmlBody string
-	var xpath string
-	var reqError requestError
-
-	xmlBody = fmt.Sprintf("<color>%s</color>", tagColors[color])
-
-	if comments != "" {
-		xmlBody += fmt.Sprintf("<comments>%s</comments>", comments)
-	}
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/tag/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panos" && len(devicegroup) > 0 {
-		return errors.New("you do not need to specify a device-group on a non-Panorama device")
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == true {
-		xpath = fmt.Sprintf("/config/shared/tag/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/tag/entry[@name='%s']", devicegroup[0], name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when creating a tag on a Panorama device")
-	}
-
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-		return err
-	}
-
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
-	return nil
-}
-
-// DeleteTag will remove a tag from the device. If deleting a tag on a Panorama device, specify the
-// device-group as the last parameter.
-func (p *PaloAlto) DeleteTag(name string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/tag/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == true {
-		xpath = fmt.Sprintf("/config/shared/tag/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/tag/entry[@name='%s']", devicegroup[0], name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when deleting a tag on a Panorama device")
-	}
-
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-		return err
-	}
-
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
-	return nil
-}
-
-// TagObject will apply the given tag to the specified address or service object(s). To apply multiple tags,
-// separate them by a comma e.g.: "tag1, tag2". If you have address/service objects with the same name,
-// then the tag(s) will be applied to all that match. If tagging objects on a Panorama device,
-// specify the device-group as the last parameter.
-func (p *PaloAlto) TagObject(tag, object string, devicegroup ...string) error {
-	var xpath, xmlBody string
-	var reqError requestError
-	tags := stringToSlice(tag)
-	adObj, _ := p.Addresses()
-	agObj, _ := p.AddressGroups()
-	sObj, _ := p.Services()
-	sgObj, _ := p.ServiceGroups()
-
-	for _, t := range tags {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(t))
-	}
-
-	for _, a := range adObj.Addresses {
-		if object == a.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='%s']/tag", object)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == true {
-				xpath = fmt.Sprintf("/config/shared/address/entry[@name='%s']/tag", object)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-				if devicegroup[0] == "shared" {
-					xpath = fmt.Sprintf("/config/shared/address/entry[@name='%s']/tag", object)
-				}
-
-				if devicegroup[0] != "shared" {
-					xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address/entry[@name='%s']/tag", devicegroup[0], object)
-				}
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when tagging objects on a Panorama device")
-			}
-		}
-	}
-
-	for _, ag := range agObj.Groups {
-		if object == ag.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']/tag", object)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == true {
-				xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']/tag", object)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-				if devicegroup[0] == "shared" {
-					xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']/tag", object)
-				}
-
-				if devicegroup[0] != "shared" {
-					xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']/tag", devicegroup[0], object)
-				}
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when tagging objects on a Panorama device")
-			}
-		}
-	}
-
-	for _, s := range sObj.Services {
-		if object == s.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service/entry[@name='%s']/tag", object)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == true {
-				xpath = fmt.Sprintf("/config/shared/service/entry[@name='%s']/tag", object)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-				if devicegroup[0] == "shared" {
-					xpath = fmt.Sprintf("/config/shared/service/entry[@name='%s']/tag", object)
-				}
-
-				if devicegroup[0] != "shared" {
-					xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service/entry[@name='%s']/tag", devicegroup[0], object)
-				}
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when tagging objects on a Panorama device")
-			}
-		}
-	}
-
-	for _, sg := range sgObj.Groups {
-		if object == sg.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']/tag", object)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == true {
-				xpath = fmt.Sprintf("/config/shared/service-group/entry[@name='%s']/tag", object)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-				if devicegroup[0] == "shared" {
-					xpath = fmt.Sprintf("/config/shared/service-group/entry[@name='%s']/tag", object)
-				}
-
-				if devicegroup[0] != "shared" {
-					xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']/tag", devicegroup[0], object)
-				}
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when tagging objects on a Panorama device")
-			}
-		}
-	}
-
-	return nil
-}
-
-// RemoveTagFromObject will remove a single tag from an address/service object. If removing a tag on a
-// Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) RemoveTagFromObject(tag, object string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-	adObj, _ := p.Addresses()
-	agObj, _ := p.AddressGroups()
-	sObj, _ := p.Services()
-	sgObj, _ := p.ServiceGroups()
-
-	for _, a := range adObj.Addresses {
-		if object == a.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='%s']/tag/member[text()='%s']", object, tag)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == true {
-				xpath = fmt.Sprintf("/config/shared/address/entry[@name='%s']/tag/member[text()='%s']", object, tag)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address/entry[@name='%s']/tag/member[text()='%s']", devicegroup[0], object, tag)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when removing tags on a Panorama device")
-			}
-		}
-	}
-
-	for _, ag := range agObj.Groups {
-		if object == ag.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']/tag/member[text()='%s']", object, tag)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == true {
-				xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']/tag/member[text()='%s']", object, tag)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']/tag/member[text()='%s']", devicegroup[0], object, tag)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when removing tags on a Panorama device")
-			}
-		}
-	}
-
-	for _, s := range sObj.Services {
-		if object == s.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service/entry[@name='%s']/tag/member[text()='%s']", object, tag)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == true {
-				xpath = fmt.Sprintf("/config/shared/service/entry[@name='%s']/tag/member[text()='%s']", object, tag)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service/entry[@name='%s']/tag/member[text()='%s']", devicegroup[0], object, tag)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when removing tags on a Panorama device")
-			}
-		}
-	}
-
-	for _, sg := range sgObj.Groups {
-		if object == sg.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']/tag/member[text()='%s']", object, tag)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == true {
-				xpath = fmt.Sprintf("/config/shared/service-group/entry[@name='%s']/tag/member[text()='%s']", object, tag)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']/tag/member[text()='%s']", devicegroup[0], object, tag)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when removing tags on a Panorama device")
-			}
-		}
-	}
-
-	return nil
-}
-
-// TagRule will apply the given tag to the specified rule. To apply multiple tags,
-// separate them by a comma e.g.: "tag1, tag2". If tagging objects on a
-// Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) TagRule(tag, rule string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-	tags := stringToSlice(tag)
-
-	xmlBody := "<tag>"
-	for _, t := range tags {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(t))
-	}
-	xmlBody += "</tag>"
-
-	xmlBody = fmt.Sprintf("<member>%s</member>", strings.TrimSpace(tag))
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/rulebase/security/rules/entry[@name='%s']/tag", rule)
-
-		_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-		if errs != nil {
-			return errs[0]
-		}
-
-		if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-			return err
-		}
-
-		if reqError.Status != "success" {
-			return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-		}
-
-		return nil
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) >= 0 {
-		policies, _ := p.Policy(devicegroup[0])
-
-		if len(policies.Pre) > 0 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/pre-rulebase/security/rules/entry[@name='%s']/tag", devicegroup[0], rule)
-
-			_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-			if errs != nil {
-				return errs[0]
-			}
-
-			if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-				return err
-			}
-
-			if reqError.Status != "success" {
-				return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-			}
-		}
-
-		if len(policies.Post) > 0 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/post-rulebase/security/rules/entry[@name='%s']/tag", devicegroup[0], rule)
-
-			_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-			if errs != nil {
-				return errs[0]
-			}
-
-			if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-				return err
-			}
-
-			if reqError.Status != "success" {
-				return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-			}
-		}
-
-		return nil
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when tagging rules on a Panorama device")
-	}
-
-	return nil
-}
-
-// RemoveTagFromRule will remove a single tag from an rule. If removing a tag on a
-// Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) RemoveTagFromRule(tag, rule string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/rulebase/security/rules/entry[@name='%s']/tag/member[text()='%s']", rule, tag)
-
-		_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-		if errs != nil {
-			return errs[0]
-		}
-
-		if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-			return err
-		}
-
-		if reqError.Status != "success" {
-			return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-		}
-
-		return nil
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) >= 0 {
-		policies, _ := p.Policy(devicegroup[0])
-
-		if len(policies.Pre) > 0 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/pre-rulebase/security/rules/entry[@name='%s']/tag/member[text()='%s']", devicegroup[0], rule, tag)
-
-			_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-			if errs != nil {
-				return errs[0]
-			}
-
-			if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-				return err
-			}
-
-			if reqError.Status != "success" {
-				return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-			}
-		}
-
-		if len(policies.Post) > 0 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/post-rulebase/security/rules/entry[@name='%s']/tag/member[text()='%s']", devicegroup[0], rule, tag)
-
-			_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-			if errs != nil {
-				return errs[0]
-			}
-
-			if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-				return err
-			}
-
-			if reqError.Status != "success" {
-				return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-			}
-		}
-
-		return nil
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when untagging rules on a Panorama device")
-	}
-
-	return nil
-}
-
-// LogForwardingProfiles returns a list of all of the log forwarding profiles on the device.
-func (p *PaloAlto) LogForwardingProfiles() (*LogForwarding, error) {
-	var profiles LogForwarding
-
-	xpath := "/config//log-settings/profiles"
-
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &profiles); err != nil {
-		return nil, err
-	}
-
-	if profiles.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", profiles.Code, errorCodes[profiles.Code])
-	}
-
-	return &profiles, nil
-}
-
-// SecurityProfileGroups returns a list of all of the security profile groups on the device.
-func (p *PaloAlto) SecurityProfileGroups() (*SecurityGroups, error) {
-	var profiles SecurityGroups
-
-	xpath := "/config//profile-group"
-
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &profiles); err != nil {
-		return nil, err
-	}
-
-	if profiles.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", profiles.Code, errorCodes[profiles.Code])
-	}
-
-	return &profiles, nil
-}
-
-// ApplyLogForwardingProfile will apply a Log Forwarding profile to every rule in the policy for the given device-group.
-// If you wish to apply it to a single rule, instead of every rule in the policy, you can (optionally) specify the rule name as the last parameter.
-// For policies with a large number of rules, this process may take a few minutes to complete.
-func (p *PaloAlto) ApplyLogForwardingProfile(logprofile, devicegroup string, rule ...string) error {
-	if p.DeviceType != "panorama" {
-		return errors.New("log forwarding profiles can only be applied on a Panorama device")
-	}
-
-	rules, err := p.Policy(devicegroup)
-	if err != nil {
-		return err
-	}
-
-	if len(rule) <= 0 {
-		// rules, err := p.Policy(devicegroup)
-		// if err != nil {
-		// 	return err
-		// }
-
-		if len(rules.Pre) > 0 {
-			for _, rule := range rules.Pre {
-				var reqError requestError
-				xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/pre-rulebase/security/rules/entry[@name='%s']", devicegroup, rule.Name)
-				xmlBody := fmt.Sprintf("<log-setting>%s</log-setting>", logprofile)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				time.Sleep(10 * time.Millisecond)
-			}
-		}
-
-		if len(rules.Post) > 0 {
-			for _, rule := range rules.Post {
-				var reqError requestError
-				xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/post-rulebase/security/rules/entry[@name='%s']", devicegroup, rule.Name)
-				xmlBody := fmt.Sprintf("<log-setting>%s</log-setting>", logprofile)
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				time.Sleep(10 * time.Millisecond)
-			}
-		}
-
-		// for _, rule := range rules.Rules {
-		// 	var reqError requestError
-		// 	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/pre-rulebase/security/rules/entry[@name='%s']", devicegroup, rule.Name)
-		// 	xmlBody := fmt.Sprintf("<log-setting>%s</log-setting>", logprofile)
-
-		// 	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-		// 	if errs != nil {
-		// 		return errs[0]
-		// 	}
-
-		// 	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-		// 		return err
-		// 	}
-
-		// 	if reqError.Status != "success" {
-		// 		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-		// 	}
-
-		// 	time.Sleep(10 * time.Millisecond)
-		// }
-	}
-
-	if len(rule) > 0 {
-		if len(rules.Pre) > 0 {
-			var reqError requestError
-			xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/pre-rulebase/security/rules/entry[@name='%s']", devicegroup, rule[0])
-			xmlBody := fmt.Sprintf("<log-setting>%s</log-setting>", logprofile)
-
-			_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-			if errs != nil {
-				return errs[0]
-			}
-
-			if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-				return err
-			}
-
-			if reqError.Status != "success" {
-				return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-			}
-
-			time.Sleep(10 * time.Millisecond)
-		}
-
-		if len(rules.Post) > 0 {
-			var reqError requestError
-			xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/post-rulebase/security/rules/entry[@name='%s']", devicegroup, rule[0])
-			xmlBody := fmt.Sprintf("<log-setting>%s</log-setting>", logprofile)
-
-			_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-			if errs != nil {
-				return errs[0]
-			}
-
-			if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-				return err
-			}
-
-			if reqError.Status != "success" {
-				return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-			}
-
-			time.Sleep(10 * time.Millisecond)
-		}
-
-		// var reqError requestError
-		// xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/pre-rulebase/security/rules/entry[@name='%s']", devicegroup, rule[0])
-		// xmlBody := fmt.Sprintf("<log-setting>%s</log-setting>", logprofile)
-
-		// _, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-		// if errs != nil {
-		// 	return errs[0]
-		// }
-
-		// if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-		// 	return err
-		// }
-
-		// if reqError.Status != "success" {
-		// 	return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-		// }
-
-		// time.Sleep(10 * time.Millisecond)
-	}
-
-	return nil
-}
-
-// ApplySecurityProfile will apply the following security profiles to every rule in teh policy for the given
-// device-group:
-//
-// URL Filtering, File-Blocking, Antivirus, Anti-Spyware, Vulnerability, Wildfire
-//
-// If you wish to apply it to a single rule, instead of every rule in the policy, you can (optionally) specify
-// the rule name as the last parameter. You can also specify a security group profile instead of individual profiles.
-// This is done by ONLY populating the Group field in the SecurityProfiles struct. For policies with a large number of rules,
-// this process may take a few minutes to complete.
-func (p *PaloAlto) ApplySecurityProfile(secprofiles *SecurityProfiles, devicegroup string, rule ...string) error {
-	if p.DeviceType != "panorama" {
-		return errors.New("security profiles can only be applied on a Panorama device")
-	}
-
-	rules, err := p.Policy(devicegroup)
-	if err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
-	}
-
-	if len(rule) <= 0 {
-		// rules, err := p.Policy(devicegroup)
-		// if err != nil {
-		// 	return err
-		// }
-
-		if len(rules.Pre) > 0 {
-			for _, rule := range rules.Pre {
-				var reqError requestError
-				var xmlBody string
-				xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/pre-rulebase/security/rules/entry[@name='%s']", devicegroup, rule.Name)
-
-				if len(secprofiles.Group) > 0 {
-					xmlBody = fmt.Sprintf("<profile-setting><group><member>%s</member></group></profile-setting>", secprofiles.Group)
-				} else {
-					xmlBody = "<profile-setting><profiles>"
-
-					if len(secprofiles.URLFiltering) > 0 {
-						xmlBody += fmt.Sprintf("<url-filtering><member>%s</member></url-filtering>", secprofiles.URLFiltering)
-					}
-
-					if len(secprofiles.FileBlocking) > 0 {
-						xmlBody += fmt.Sprintf("<file-blocking><member>%s</member></file-blocking>", secprofiles.FileBlocking)
-					}
-
-					if len(secprofiles.AntiVirus) > 0 {
-						xmlBody += fmt.Sprintf("<virus><member>%s</member></virus>", secprofiles.AntiVirus)
-					}
-
-					if len(secprofiles.AntiSpyware) > 0 {
-						xmlBody += fmt.Sprintf("<spyware><member>%s</member></spyware>", secprofiles.AntiSpyware)
-					}
-
-					if len(secprofiles.Vulnerability) > 0 {
-						xmlBody += fmt.Sprintf("<vulnerability><member>%s</member></vulnerability>", secprofiles.Vulnerability)
-					}
-
-					if len(secprofiles.Wildfire) > 0 {
-						xmlBody += fmt.Sprintf("<wildfire-analysis><member>%s</member></wildfire-analysis>", secprofiles.Wildfire)
-					}
-
-					xmlBody += "</profiles></profile-setting>"
-				}
-
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
-
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				time.Sleep(10 * time.Millisecond)
-			}
-		}
-
-		if len(rules.Post) > 0 {
-			for _, rule := range rules.Post {
-				var reqError requestError
-				var xmlBody string
-				xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/post-rulebase/security/rules/entry[@name='%s']", devicegroup, rule.Name)
+	}
 
-				if len(secprofiles.Group) > 0 {
-					xmlBody = fmt.Sprintf("<profile-setting><group><member>%s</member></group></profile-setting>", secprofiles.Group)
-				} else {
-					xmlBody = "<profile-setting><profiles>"
+	return nil
+}
 
-					if len(secprofiles.URLFiltering) > 0 {
-						xmlBody += fmt.Sprintf("<url-filtering><member>%s</member></url-filtering>", secprofiles.URLFiltering)
-					}
+// TagObject will apply the given tag to the specified address or service object(s). To apply multiple tags,
+// separate them by a comma e.g.: "tag1, tag2". If tagging objects on a Panorama device, specify its location
+// (e.g. DeviceGroupLocation("name") or SharedLocation()) as the last parameter.
+func (p *PaloAlto) TagObject(tag, object string, location ...Location) error {
+	return p.TagObjectContext(context.Background(), tag, object, location...)
+}
 
-					if len(secprofiles.FileBlocking) > 0 {
-						xmlBody += fmt.Sprintf("<file-blocking><member>%s</member></file-blocking>", secprofiles.FileBlocking)
-					}
+// TagObjectContext is the same as TagObject, but uses ctx for all of its API requests.
+func (p *PaloAlto) TagObjectContext(ctx context.Context, tag, object string, location ...Location) error {
+	var xmlBody string
+	tags := stringToSlice(tag)
 
-					if len(secprofiles.AntiVirus) > 0 {
-						xmlBody += fmt.Sprintf("<virus><member>%s</member></virus>", secprofiles.AntiVirus)
-					}
+	for _, t := range tags {
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(t)))
+	}
 
-					if len(secprofiles.AntiSpyware) > 0 {
-						xmlBody += fmt.Sprintf("<spyware><member>%s</member></spyware>", secprofiles.AntiSpyware)
-					}
+	objtype, err := p.objectType(ctx, object, "address", "address-group", "service", "service-group")
+	if err != nil || objtype == "" {
+		return err
+	}
 
-					if len(secprofiles.Vulnerability) > 0 {
-						xmlBody += fmt.Sprintf("<vulnerability><member>%s</member></vulnerability>", secprofiles.Vulnerability)
-					}
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
+	}
 
-					if len(secprofiles.Wildfire) > 0 {
-						xmlBody += fmt.Sprintf("<wildfire-analysis><member>%s</member></wildfire-analysis>", secprofiles.Wildfire)
-					}
+	xpath := fmt.Sprintf("%s/%s/entry[@name=%s]/tag", base, objtype, xpathQuote(object))
 
-					xmlBody += "</profiles></profile-setting>"
-				}
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
+		return err
+	}
 
-				_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-				if errs != nil {
-					return errs[0]
-				}
+	return nil
+}
 
-				if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-					return err
-				}
+// RemoveTagFromObject will remove a single tag from an address/service object. If removing a tag on a
+// Panorama device, specify its location (e.g. DeviceGroupLocation("name") or SharedLocation()) as the last parameter.
+func (p *PaloAlto) RemoveTagFromObject(tag, object string, location ...Location) error {
+	return p.RemoveTagFromObjectContext(context.Background(), tag, object, location...)
+}
 
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+// RemoveTagFromObjectContext is the same as RemoveTagFromObject, but uses ctx for all of its API requests.
+func (p *PaloAlto) RemoveTagFromObjectContext(ctx context.Context, tag, object string, location ...Location) error {
+	objtype, err := p.objectType(ctx, object, "address", "address-group", "service", "service-group")
+	if err != nil || objtype == "" {
+		return err
+	}
 
-				time.Sleep(10 * time.Millisecond)
-			}
-		}
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
+	}
 
-		// for _, rule := range rules.Rules {
-		// 	var reqError requestError
-		// 	var xmlBody string
-		// 	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/pre-rulebase/security/rules/entry[@name='%s']", devicegroup, rule.Name)
+	xpath := fmt.Sprintf("%s/%s/entry[@name=%s]/tag/member[text()=%s]", base, objtype, xpathQuote(object), xpathQuote(tag))
 
-		// 	if len(secprofiles.Group) > 0 {
-		// 		xmlBody = fmt.Sprintf("<profile-setting><group><member>%s</member></group></profile-setting>", secprofiles.Group)
-		// 	} else {
-		// 		xmlBody = "<profile-setting><profiles>"
+	if err := p.deleteConfig(ctx, xpath); err != nil {
+		return err
+	}
 
-		// 		if len(secprofiles.AntiVirus) > 0 {
-		// 			xmlBody += fmt.Sprintf("<virus><member>%s</member></virus>", secprofiles.AntiVirus)
-		// 		}
+	return nil
+}
 
-		// 		if len(secprofiles.AntiSpyware) > 0 {
-		// 			xmlBody += fmt.Sprintf("<spyware><member>%s</member></spyware>", secprofiles.AntiSpyware)
-		// 		}
+// TagRule will apply the given tag to the specified rule. To apply multiple tags,
+// separate them by a comma e.g.: "tag1, tag2". If tagging rules on a Panorama device, specify
+// its location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) TagRule(tag, rule string, location ...Location) error {
+	return p.TagRuleContext(context.Background(), tag, rule, location...)
+}
 
-		// 		if len(secprofiles.Vulnerability) > 0 {
-		// 			xmlBody += fmt.Sprintf("<vulnerability><member>%s</member></vulnerability>", secprofiles.AntiSpyware)
-		// 		}
+// TagRuleContext is the same as TagRule, but uses ctx for all of its API requests.
+func (p *PaloAlto) TagRuleContext(ctx context.Context, tag, rule string, location ...Location) error {
+	var xmlBody string
+	tags := stringToSlice(tag)
 
-		// 		if len(secprofiles.Wildfire) > 0 {
-		// 			xmlBody += fmt.Sprintf("<wildfire-analysis><member>%s</member></wildfire-analysis>", secprofiles.Wildfire)
-		// 		}
+	for _, t := range tags {
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(t)))
+	}
 
-		// 		xmlBody += "</profiles></profile-setting>"
-		// 	}
+	xpath, err := p.ruleXpath(ctx, rule, location...)
+	if err != nil {
+		return err
+	}
 
-		// 	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-		// 	if errs != nil {
-		// 		return errs[0]
-		// 	}
+	if err := p.setConfig(ctx, xpath+"/tag", xmlBody); err != nil {
+		return err
+	}
 
-		// 	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-		// 		return err
-		// 	}
+	return nil
+}
 
-		// 	if reqError.Status != "success" {
-		// 		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-		// 	}
+// RemoveTagFromRule will remove a single tag from an rule. If removing a tag on a Panorama device,
+// specify its location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) RemoveTagFromRule(tag, rule string, location ...Location) error {
+	return p.RemoveTagFromRuleContext(context.Background(), tag, rule, location...)
+}
 
-		// 	time.Sleep(10 * time.Millisecond)
-		// }
+// RemoveTagFromRuleContext is the same as RemoveTagFromRule, but uses ctx for all of its API requests.
+func (p *PaloAlto) RemoveTagFromRuleContext(ctx context.Context, tag, rule string, location ...Location) error {
+	xpath, err := p.ruleXpath(ctx, rule, location...)
+	if err != nil {
+		return err
 	}
 
-	if len(rule) > 0 {
-		if len(rules.Pre) > 0 {
-			var reqError requestError
-			var xmlBody string
-			xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/pre-rulebase/security/rules/entry[@name='%s']", devicegroup, rule[0])
-
-			if len(secprofiles.Group) > 0 {
-				xmlBody = fmt.Sprintf("<profile-setting><group><member>%s</member></group></profile-setting>", secprofiles.Group)
-			} else {
-				xmlBody = "<profile-setting><profiles>"
+	if err := p.deleteConfig(ctx, fmt.Sprintf("%s/tag/member[text()=%s]", xpath, xpathQuote(tag))); err != nil {
+		return err
+	}
 
-				if len(secprofiles.URLFiltering) > 0 {
-					xmlBody += fmt.Sprintf("<url-filtering><member>%s</member></url-filtering>", secprofiles.URLFiltering)
-				}
+	return nil
+}
 
-				if len(secprofiles.FileBlocking) > 0 {
-					xmlBody += fmt.Sprintf("<file-blocking><member>%s</member></file-blocking>", secprofiles.FileBlocking)
-				}
+// LogForwardingProfiles returns a list of all of the log forwarding profiles on the device.
+func (p *PaloAlto) LogForwardingProfiles() (*LogForwarding, error) {
+	return p.LogForwardingProfilesContext(context.Background())
+}
 
-				if len(secprofiles.AntiVirus) > 0 {
-					xmlBody += fmt.Sprintf("<virus><member>%s</member></virus>", secprofiles.AntiVirus)
-				}
+// LogForwardingProfilesContext is the same as LogForwardingProfiles, but uses ctx for all of its API requests.
+func (p *PaloAlto) LogForwardingProfilesContext(ctx context.Context) (*LogForwarding, error) {
+	var profiles LogForwarding
 
-				if len(secprofiles.AntiSpyware) > 0 {
-					xmlBody += fmt.Sprintf("<spyware><member>%s</member></spyware>", secprofiles.AntiSpyware)
-				}
+	xpath := "/config//log-settings/profiles"
 
-				if len(secprofiles.Vulnerability) > 0 {
-					xmlBody += fmt.Sprintf("<vulnerability><member>%s</member></vulnerability>", secprofiles.Vulnerability)
-				}
+	resp, err := p.getConfig(ctx, xpath)
+	if err != nil {
+		return nil, err
+	}
 
-				if len(secprofiles.Wildfire) > 0 {
-					xmlBody += fmt.Sprintf("<wildfire-analysis><member>%s</member></wildfire-analysis>", secprofiles.Wildfire)
-				}
+	if err := xml.Unmarshal([]byte(resp), &profiles); err != nil {
+		return nil, err
+	}
 
-				xmlBody += "</profiles></profile-setting>"
-			}
+	return &profiles, nil
+}
 
-			_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-			if errs != nil {
-				return errs[0]
-			}
+// SecurityProfileGroups returns a list of all of the security profile groups on the device.
+func (p *PaloAlto) SecurityProfileGroups() (*SecurityGroups, error) {
+	return p.SecurityProfileGroupsContext(context.Background())
+}
 
-			if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-				return err
-			}
+// SecurityProfileGroupsContext is the same as SecurityProfileGroups, but uses ctx for all of its API requests.
+func (p *PaloAlto) SecurityProfileGroupsContext(ctx context.Context) (*SecurityGroups, error) {
+	var profiles SecurityGroups
 
-			if reqError.Status != "success" {
-				return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-			}
+	xpath := "/config//profile-group"
 
-			time.Sleep(10 * time.Millisecond)
-		}
+	resp, err := p.getConfig(ctx, xpath)
+	if err != nil {
+		return nil, err
+	}
 
-		if len(rules.Post) > 0 {
-			var reqError requestError
-			var xmlBody string
-			xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/post-rulebase/security/rules/entry[@name='%s']", devicegroup, rule[0])
+	if err := xml.Unmarshal([]byte(resp), &profiles); err != nil {
+		return nil, err
+	}
 
-			if len(secprofiles.Group) > 0 {
-				xmlBody = fmt.Sprintf("<profile-setting><group><member>%s</member></group></profile-setting>", secprofiles.Group)
-			} else {
-				xmlBody = "<profile-setting><profiles>"
+	return &profiles, nil
+}
 
-				if len(secprofiles.URLFiltering) > 0 {
-					xmlBody += fmt.Sprintf("<url-filtering><member>%s</member></url-filtering>", secprofiles.URLFiltering)
-				}
+// ApplyLogForwardingProfile will apply a Log Forwarding profile to every rule in the policy at the given location
+// (e.g. DeviceGroupLocation("name")).
+// If you wish to apply it to a single rule, instead of every rule in the policy, you can (optionally) specify the rule name as the last parameter.
+// For policies with a large number of rules, this process may take a few minutes to complete.
+func (p *PaloAlto) ApplyLogForwardingProfile(logprofile string, location Location, rule ...string) error {
+	return p.ApplyLogForwardingProfileContext(context.Background(), logprofile, location, rule...)
+}
 
-				if len(secprofiles.FileBlocking) > 0 {
-					xmlBody += fmt.Sprintf("<file-blocking><member>%s</member></file-blocking>", secprofiles.FileBlocking)
-				}
+// ApplyLogForwardingProfileContext is the same as ApplyLogForwardingProfile, but uses ctx for all of its API requests.
+func (p *PaloAlto) ApplyLogForwardingProfileContext(ctx context.Context, logprofile string, location Location, rule ...string) error {
+	if p.DeviceType != "panorama" {
+		return errors.New("log forwarding profiles can only be applied on a Panorama device")
+	}
 
-				if len(secprofiles.AntiVirus) > 0 {
-					xmlBody += fmt.Sprintf("<virus><member>%s</member></virus>", secprofiles.AntiVirus)
-				}
+	xmlBody := fmt.Sprintf("<log-setting>%s</log-setting>", xmlEscape(logprofile))
 
-				if len(secprofiles.AntiSpyware) > 0 {
-					xmlBody += fmt.Sprintf("<spyware><member>%s</member></spyware>", secprofiles.AntiSpyware)
-				}
+	return p.setOnRules(ctx, location, xmlBody, rule...)
+}
 
-				if len(secprofiles.Vulnerability) > 0 {
-					xmlBody += fmt.Sprintf("<vulnerability><member>%s</member></vulnerability>", secprofiles.Vulnerability)
-				}
+// ApplySecurityProfile will apply the following security profiles to every rule in teh policy at the given
+// location (e.g. DeviceGroupLocation("name")):
+//
+// # URL Filtering, File-Blocking, Antivirus, Anti-Spyware, Vulnerability, Wildfire
+//
+// If you wish to apply it to a single rule, instead of every rule in the policy, you can (optionally) specify
+// the rule name as the last parameter. You can also specify a security group profile instead of individual profiles.
+// This is done by ONLY populating the Group field in the SecurityProfiles struct. For policies with a large number of rules,
+// this process may take a few minutes to complete.
+func (p *PaloAlto) ApplySecurityProfile(secprofiles *SecurityProfiles, location Location, rule ...string) error {
+	return p.ApplySecurityProfileContext(context.Background(), secprofiles, location, rule...)
+}
 
-				if len(secprofiles.Wildfire) > 0 {
-					xmlBody += fmt.Sprintf("<wildfire-analysis><member>%s</member></wildfire-analysis>", secprofiles.Wildfire)
-				}
+// ApplySecurityProfileContext is the same as ApplySecurityProfile, but uses ctx for all of its API requests.
+func (p *PaloAlto) ApplySecurityProfileContext(ctx context.Context, secprofiles *SecurityProfiles, location Location, rule ...string) error {
+	var xmlBody string
 
-				xmlBody += "</profiles></profile-setting>"
-			}
+	if p.DeviceType != "panorama" {
+		return errors.New("security profiles can only be applied on a Panorama device")
+	}
 
-			_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-			if errs != nil {
-				return errs[0]
-			}
+	if len(secprofiles.Group) > 0 {
+		xmlBody = fmt.Sprintf("<profile-setting><group><member>%s</member></group></profile-setting>", xmlEscape(secprofiles.Group))
+	} else {
+		xmlBody = "<profile-setting><profiles>"
 
-			if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-				return err
-			}
+		if len(secprofiles.URLFiltering) > 0 {
+			xmlBody += fmt.Sprintf("<url-filtering><member>%s</member></url-filtering>", xmlEscape(secprofiles.URLFiltering))
+		}
 
-			if reqError.Status != "success" {
-				return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-			}
+		if len(secprofiles.FileBlocking) > 0 {
+			xmlBody += fmt.Sprintf("<file-blocking><member>%s</member></file-blocking>", xmlEscape(secprofiles.FileBlocking))
+		}
 
-			time.Sleep(10 * time.Millisecond)
+		if len(secprofiles.AntiVirus) > 0 {
+			xmlBody += fmt.Sprintf("<virus><member>%s</member></virus>", xmlEscape(secprofiles.AntiVirus))
 		}
 
-		// var reqError requestError
-		// var xmlBody string
-		// xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/pre-rulebase/security/rules/entry[@name='%s']", devicegroup, rule[0])
+		if len(secprofiles.AntiSpyware) > 0 {
+			xmlBody += fmt.Sprintf("<spyware><member>%s</member></spyware>", xmlEscape(secprofiles.AntiSpyware))
+		}
 
-		// if len(secprofiles.Group) > 0 {
-		// 	xmlBody = fmt.Sprintf("<profile-setting><group><member>%s</member></group></profile-setting>", secprofiles.Group)
-		// } else {
-		// 	xmlBody = "<profile-setting><profiles>"
+		if len(secprofiles.Vulnerability) > 0 {
+			xmlBody += fmt.Sprintf("<vulnerability><member>%s</member></vulnerability>", xmlEscape(secprofiles.Vulnerability))
+		}
 
-		// 	if len(secprofiles.AntiVirus) > 0 {
-		// 		xmlBody += fmt.Sprintf("<virus><member>%s</member></virus>", secprofiles.AntiVirus)
-		// 	}
+		if len(secprofiles.Wildfire) > 0 {
+			xmlBody += fmt.Sprintf("<wildfire-analysis><member>%s</member></wildfire-analysis>", xmlEscape(secprofiles.Wildfire))
+		}
 
-		// 	if len(secprofiles.AntiSpyware) > 0 {
-		// 		xmlBody += fmt.Sprintf("<spyware><member>%s</member></spyware>", secprofiles.AntiSpyware)
-		// 	}
+		xmlBody += "</profiles></profile-setting>"
+	}
 
-		// 	if len(secprofiles.Vulnerability) > 0 {
-		// 		xmlBody += fmt.Sprintf("<vulnerability><member>%s</member></vulnerability>", secprofiles.AntiSpyware)
-		// 	}
+	return p.setOnRules(ctx, location, xmlBody, rule...)
+}
 
-		// 	if len(secprofiles.Wildfire) > 0 {
-		// 		xmlBody += fmt.Sprintf("<wildfire-analysis><member>%s</member></wildfire-analysis>", secprofiles.Wildfire)
-		// 	}
+// setOnRules sets element on the named security rule in the pre- and post-rulebase at location, or on every
+// security rule there if no name is given. The rules are updated in parallel, using a Bulk.
+func (p *PaloAlto) setOnRules(ctx context.Context, location Location, element string, rule ...string) error {
+	base, err := p.locationXpath(location)
+	if err != nil {
+		return err
+	}
 
-		// 	xmlBody += "</profiles></profile-setting>"
-		// }
+	rules, err := p.PolicyContext(ctx, location)
+	if err != nil {
+		return err
+	}
 
-		// _, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-		// if errs != nil {
-		// 	return errs[0]
-		// }
+	bulk := p.NewBulk(0)
+	add := func(rulebase string, rules []Rule) {
+		for _, r := range rules {
+			if len(rule) > 0 && r.Name != rule[0] {
+				continue
+			}
 
-		// if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-		// 	return err
-		// }
+			xpath := fmt.Sprintf("%s/%s/security/rules/entry[@name=%s]", base, rulebase, xpathQuote(r.Name))
+			bulk.Add(func(ctx context.Context) error {
+				return p.setConfig(ctx, xpath, element)
+			})
+		}
+	}
 
-		// if reqError.Status != "success" {
-		// 	return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-		// }
+	add("pre-rulebase", rules.Pre)
+	add("post-rulebase", rules.Post)
 
-		// time.Sleep(10 * time.Millisecond)
+	if len(rule) > 0 && bulk.Len() == 0 {
+		return fmt.Errorf("rule %s does not exist", rule[0])
 	}
 
-	return nil
+	return bulk.Run(ctx)
 }
 
 // CreateObjectsFromCsv takes a CSV file and creates the given address or service objects, and
@@ -2250,17 +839,31 @@ func (p *PaloAlto) ApplySecurityProfile(secprofiles *SecurityProfiles, devicegro
 //
 // name,type,value,description (optional),tag (optional),device-group
 //
+// The address and service objects are created in parallel (see WithParallelism). Once they all exist, the
+// groups are created one at a time, in the order they appear in the file, so a group can include a group that
+// comes before it.
+//
 // See https://github.com/scottdware/go-panos#creating-objects-from-a-csv-file
 // for complete documentation and examples.
 func (p *PaloAlto) CreateObjectsFromCsv(file string) error {
+	return p.CreateObjectsFromCsvContext(context.Background(), file)
+}
+
+// CreateObjectsFromCsvContext is the same as CreateObjectsFromCsv, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateObjectsFromCsvContext(ctx context.Context, file string) error {
 	c, err := easycsv.Open(file)
 	if err != nil {
 		return err
 	}
 
+	objects := p.NewBulk(0)
+
+	// Groups may include groups from earlier in the file, so they are created one at a time, in order.
+	groups := p.NewBulk(1)
+
 	for _, line := range c {
-		var tagged bool
-		var description, tag, dg string
+		var description, tag string
+		var location []Location
 		linelen := len(line)
 		name := line[0]
 		objtype := line[1]
@@ -2272,175 +875,61 @@ func (p *PaloAlto) CreateObjectsFromCsv(file string) error {
 
 		if linelen > 4 && len(line[4]) > 0 {
 			tag = line[4]
-			tagged = true
 		}
 
 		if linelen > 5 && len(line[5]) > 0 {
-			dg = line[5]
+			location = []Location{csvLocation(line[5])}
 		}
 
+		var create func(ctx context.Context) error
+		bulk := objects
+
 		switch objtype {
 		case "ip", "range", "fqdn":
-			if len(description) > 0 && len(dg) > 0 {
-				err = p.CreateAddress(name, objtype, value, description, dg)
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(description) == 0 && len(dg) == 0 {
-				err = p.CreateAddress(name, objtype, value, "")
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(description) > 0 && len(dg) == 0 {
-				err = p.CreateAddress(name, objtype, value, description)
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(description) == 0 && len(dg) > 0 {
-				err = p.CreateAddress(name, objtype, value, "", dg)
-				if err != nil {
-					return err
-				}
+			create = func(ctx context.Context) error {
+				return p.CreateAddressContext(ctx, name, objtype, value, description, location...)
 			}
 		case "tcp", "udp":
-			if len(description) > 0 && len(dg) > 0 {
-				err = p.CreateService(name, objtype, value, description, dg)
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(description) == 0 && len(dg) == 0 {
-				err = p.CreateService(name, objtype, value, "")
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(description) > 0 && len(dg) == 0 {
-				err = p.CreateService(name, objtype, value, description)
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(description) == 0 && len(dg) > 0 {
-				err = p.CreateService(name, objtype, value, "", dg)
-				if err != nil {
-					return err
-				}
+			create = func(ctx context.Context) error {
+				return p.CreateServiceContext(ctx, name, objtype, value, description, location...)
 			}
 		case "service":
-			groupMembers := stringToSlice(value)
-
-			if len(dg) > 0 {
-				err = p.CreateServiceGroup(name, groupMembers, dg)
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(dg) == 0 {
-				err = p.CreateServiceGroup(name, groupMembers)
-				if err != nil {
-					return err
-				}
+			bulk = groups
+			create = func(ctx context.Context) error {
+				return p.CreateServiceGroupContext(ctx, name, stringToSlice(value), location...)
 			}
 		case "static":
-			groupMembers := stringToSlice(value)
-
-			if len(description) > 0 && len(dg) > 0 {
-				err = p.CreateAddressGroup(name, "static", groupMembers, description, dg)
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(description) == 0 && len(dg) == 0 {
-				err = p.CreateAddressGroup(name, "static", groupMembers, "")
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(description) > 0 && len(dg) == 0 {
-				err = p.CreateAddressGroup(name, "static", groupMembers, description)
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(description) == 0 && len(dg) > 0 {
-				err = p.CreateAddressGroup(name, "static", groupMembers, "", dg)
-				if err != nil {
-					return err
-				}
+			bulk = groups
+			create = func(ctx context.Context) error {
+				return p.CreateAddressGroupContext(ctx, name, "static", stringToSlice(value), description, location...)
 			}
 		case "dynamic":
-			criteria := fmt.Sprintf("%s", value)
-
-			if len(description) > 0 && len(dg) > 0 {
-				err = p.CreateAddressGroup(name, "dynamic", criteria, description, dg)
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(description) == 0 && len(dg) == 0 {
-				err = p.CreateAddressGroup(name, "dynamic", criteria, "")
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(description) > 0 && len(dg) == 0 {
-				err = p.CreateAddressGroup(name, "dynamic", criteria, description)
-				if err != nil {
-					return err
-				}
-			}
-
-			if len(description) == 0 && len(dg) > 0 {
-				err = p.CreateAddressGroup(name, "dynamic", criteria, "", dg)
-				if err != nil {
-					return err
-				}
+			bulk = groups
+			create = func(ctx context.Context) error {
+				return p.CreateAddressGroupContext(ctx, name, "dynamic", value, description, location...)
 			}
+		default:
+			continue
 		}
 
-		time.Sleep(10 * time.Millisecond)
-
-		if tagged {
-			if dg != "" && dg != "shared" {
-				err = p.TagObject(tag, name, dg)
-				if err != nil {
-					return err
-				}
+		bulk.Add(func(ctx context.Context) error {
+			if err := create(ctx); err != nil {
+				return err
 			}
 
-			if dg != "" && dg == "shared" {
-				err = p.TagObject(tag, name, "shared")
-				if err != nil {
-					return err
-				}
+			if len(tag) > 0 {
+				return p.TagObjectContext(ctx, tag, name, location...)
 			}
 
-			if dg == "" {
-				err = p.TagObject(tag, name)
-				if err != nil {
-					return err
-				}
-			}
-		}
+			return nil
+		})
 	}
 
-	return nil
+	if err := objects.Run(ctx); err != nil {
+		return err
+	}
+
+	return groups.Run(ctx)
 }
 
 // ModifyGroupsFromCsv takes a CSV file and modifies the given address or service groups with the
@@ -2451,6 +940,11 @@ func (p *PaloAlto) CreateObjectsFromCsv(file string) error {
 // See https://github.com/scottdware/go-panos#modifying-object-groups-from-a-csv-file
 // for complete documentation and examples.
 func (p *PaloAlto) ModifyGroupsFromCsv(file string) error {
+	return p.ModifyGroupsFromCsvContext(context.Background(), file)
+}
+
+// ModifyGroupsFromCsvContext is the same as ModifyGroupsFromCsv, but uses ctx for all of its API requests.
+func (p *PaloAlto) ModifyGroupsFromCsvContext(ctx context.Context, file string) error {
 	c, err := easycsv.Open(file)
 	if err != nil {
 		return err
@@ -2471,36 +965,44 @@ func (p *PaloAlto) ModifyGroupsFromCsv(file string) error {
 		switch grouptype {
 		case "address":
 			if len(dg) == 0 {
-				err = p.EditGroup("address", action, object, group)
+				err = p.EditGroupContext(ctx, "address", action, object, group)
 				if err != nil {
 					return err
 				}
 			}
 
 			if len(dg) > 0 {
-				err = p.EditGroup("address", action, object, group, dg)
+				err = p.EditGroupContext(ctx, "address", action, object, group, csvLocation(dg))
 				if err != nil {
 					return err
 				}
 			}
 		case "service":
 			if len(dg) == 0 {
-				err = p.EditGroup("service", action, object, group)
+				err = p.EditGroupContext(ctx, "service", action, object, group)
 				if err != nil {
 					return err
 				}
 			}
 
 			if len(dg) > 0 {
-				err = p.EditGroup("service", action, object, group, dg)
+				err = p.EditGroupContext(ctx, "service", action, object, group, csvLocation(dg))
 				if err != nil {
 					return err
 				}
 			}
 		}
-
-		time.Sleep(10 * time.Millisecond)
 	}
 
 	return nil
 }
+
+// csvLocation returns the location given in the device-group column of a CSV file, which is either the name of a
+// device-group, or "shared".
+func csvLocation(dg string) Location {
+	if dg == "shared" {
+		return SharedLocation()
+	}
+
+	return DeviceGroupLocation(dg)
+}
@@ -0,0 +1,278 @@
+package panos
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scottdware/go-panos/panostest"
+)
+
+func TestCreateAndDelete(t *testing.T) {
+	tests := []struct {
+		name     string
+		panorama bool
+		object   string
+		create   func(p *PaloAlto) error
+		delete   func(p *PaloAlto) error
+	}{
+		{
+			name:   "address",
+			object: "web-1",
+			create: func(p *PaloAlto) error { return p.CreateAddress("web-1", "ip", "10.1.1.1/32", "Web server") },
+			delete: func(p *PaloAlto) error { return p.DeleteAddress("web-1") },
+		},
+		{
+			name:   "address group",
+			object: "web-servers",
+			create: func(p *PaloAlto) error {
+				if err := p.CreateAddress("web-2", "fqdn", "web.example.com", ""); err != nil {
+					return err
+				}
+
+				return p.CreateAddressGroup("web-servers", "static", []string{"web-2"}, "")
+			},
+			delete: func(p *PaloAlto) error { return p.DeleteAddressGroup("web-servers") },
+		},
+		{
+			name:   "service",
+			object: "tcp-8080",
+			create: func(p *PaloAlto) error { return p.CreateService("tcp-8080", "tcp", "8080", "") },
+			delete: func(p *PaloAlto) error { return p.DeleteService("tcp-8080") },
+		},
+		{
+			name:   "service group",
+			object: "web-ports",
+			create: func(p *PaloAlto) error {
+				if err := p.CreateService("tcp-8443", "tcp", "8443", ""); err != nil {
+					return err
+				}
+
+				return p.CreateServiceGroup("web-ports", []string{"tcp-8443"})
+			},
+			delete: func(p *PaloAlto) error { return p.DeleteServiceGroup("web-ports") },
+		},
+		{
+			name:   "tag",
+			object: "production",
+			create: func(p *PaloAlto) error { return p.CreateTag("production", "color1", "") },
+			delete: func(p *PaloAlto) error { return p.DeleteTag("production") },
+		},
+		{
+			name:   "URL category",
+			object: "blocked-sites",
+			create: func(p *PaloAlto) error {
+				return p.CreateURLCategory("blocked-sites", []string{"example.com"}, "")
+			},
+			delete: func(p *PaloAlto) error { return p.DeleteURLCategory("blocked-sites") },
+		},
+		{
+			name:   "external dynamic list",
+			object: "bad-ips",
+			create: func(p *PaloAlto) error {
+				return p.CreateExternalDynamicList("ip", "bad-ips", "https://example.com/ips.txt", &Recurrance{Method: "hourly"})
+			},
+			delete: func(p *PaloAlto) error { return p.DeleteExternalDynamicList("bad-ips") },
+		},
+		{
+			name:   "zone",
+			object: "dmz",
+			create: func(p *PaloAlto) error { return p.CreateZone("dmz", "layer3", false) },
+			delete: func(p *PaloAlto) error { return p.DeleteZone("dmz") },
+		},
+		{
+			name:   "virtual router",
+			object: "vr-branch",
+			create: func(p *PaloAlto) error { return p.CreateVirtualRouter("vr-branch") },
+			delete: func(p *PaloAlto) error { return p.DeleteVirtualRouter("vr-branch") },
+		},
+		{
+			name:   "static route",
+			object: "default-route",
+			create: func(p *PaloAlto) error {
+				return p.CreateStaticRoute("default", "default-route", "0.0.0.0/0", "10.0.0.1")
+			},
+			delete: func(p *PaloAlto) error { return p.DeleteStaticRoute("default", "default-route") },
+		},
+		{
+			name:   "interface",
+			object: "ethernet1/5",
+			create: func(p *PaloAlto) error { return p.CreateInterface("layer3", "ethernet1/5", "", "10.5.5.1/24") },
+			delete: func(p *PaloAlto) error { return p.DeleteInterface("layer3", "ethernet1/5") },
+		},
+		{
+			name:     "device group",
+			panorama: true,
+			object:   "branch-offices",
+			create:   func(p *PaloAlto) error { return p.CreateDeviceGroup("branch-offices", "", nil) },
+			delete:   func(p *PaloAlto) error { return p.DeleteDeviceGroup("branch-offices") },
+		},
+		{
+			name:     "device group address",
+			panorama: true,
+			object:   "branch-dns",
+			create: func(p *PaloAlto) error {
+				if err := p.CreateDeviceGroup("branch", "", nil); err != nil {
+					return err
+				}
+
+				return p.CreateAddress("branch-dns", "ip", "10.9.9.9/32", "", DeviceGroupLocation("branch"))
+			},
+			delete: func(p *PaloAlto) error { return p.DeleteAddress("branch-dns", DeviceGroupLocation("branch")) },
+		},
+		{
+			name:     "template",
+			panorama: true,
+			object:   "branch-template",
+			create:   func(p *PaloAlto) error { return p.CreateTemplate("branch-template", "") },
+			delete:   func(p *PaloAlto) error { return p.DeleteTemplate("branch-template", false) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []panostest.Option
+			if tt.panorama {
+				options = append(options, panostest.WithPanorama())
+			}
+
+			pan, srv := newTestSession(t, options...)
+			defer srv.Close()
+
+			entry := fmt.Sprintf("<entry name=\"%s\"", tt.object)
+
+			if err := tt.create(pan); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+
+			if !strings.Contains(srv.Candidate(), entry) {
+				t.Fatalf("%s is not in the candidate configuration after creating it", tt.object)
+			}
+
+			if err := tt.delete(pan); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+
+			if strings.Contains(srv.Candidate(), entry) {
+				t.Fatalf("%s is still in the candidate configuration after deleting it", tt.object)
+			}
+		})
+	}
+}
+
+func TestCreateObjectsFromCsvGroupOrder(t *testing.T) {
+	srv := newFakeDevice(nil)
+	defer srv.Close()
+
+	pan := srv.session(t)
+
+	dir, err := ioutil.TempDir("", "panos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lines := []string{"web-1,ip,10.1.1.1/32", "web-2,ip,10.1.1.2/32"}
+	var groups []string
+	for i := 1; i <= 10; i++ {
+		group := fmt.Sprintf("group-%d", i)
+		members := "web-1, web-2"
+		if i > 1 {
+			members = groups[i-2]
+		}
+
+		groups = append(groups, group)
+		lines = append(lines, fmt.Sprintf("%s,static,\"%s\"", group, members))
+	}
+
+	file := filepath.Join(dir, "objects.csv")
+	if err := ioutil.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pan.CreateObjectsFromCsv(file); err != nil {
+		t.Fatal(err)
+	}
+
+	var created []string
+	for _, r := range srv.Requests() {
+		xpath := r.Get("xpath")
+		if i := strings.Index(xpath, "/address-group/entry[@name='"); i >= 0 {
+			created = append(created, strings.TrimSuffix(xpath[i+len("/address-group/entry[@name='"):], "']"))
+		}
+	}
+
+	if strings.Join(created, ",") != strings.Join(groups, ",") {
+		t.Fatalf("the groups were created in the order %q, want %q", created, groups)
+	}
+}
+
+func TestSessionConcurrentUse(t *testing.T) {
+	var running, most int32
+
+	srv := newFakeDevice(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			m := atomic.LoadInt32(&most)
+			if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+
+		if r.Form.Get("action") == "get" {
+			fmt.Fprint(w, `<response status="success"><result><address/></result></response>`)
+			return
+		}
+
+		fmt.Fprint(w, `<response status="success" code="20"><msg>command succeeded</msg></response>`)
+	})
+	defer srv.Close()
+
+	pan := srv.session(t)
+
+	bulk := pan.NewBulk(8)
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("host-%d", i)
+		address := fmt.Sprintf("10.0.0.%d/32", i)
+
+		bulk.Add(func(ctx context.Context) error {
+			if err := pan.CreateAddressContext(ctx, name, "ip", address, ""); err != nil {
+				return err
+			}
+
+			_, err := pan.AddressesContext(ctx)
+
+			return err
+		})
+	}
+
+	if err := bulk.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	created := map[string]bool{}
+	for _, r := range srv.Requests() {
+		if r.Get("action") == "set" {
+			created[r.Get("xpath")] = true
+		}
+	}
+
+	if len(created) != 50 {
+		t.Fatalf("got %d addresses, want 50", len(created))
+	}
+
+	if n := atomic.LoadInt32(&most); n < 2 || n > 8 {
+		t.Fatalf("got up to %d requests at once, want between 2 and 8", n)
+	}
+}
@@ -1,6 +1,7 @@
 package panos
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -73,37 +74,58 @@ type VsysEntry struct {
 	SharedPolicyMD5Sum string `xml:"shared-policy-md5sum"`
 }
 
-// SetShared will set Panorama's device-group to shared for all subsequent configuration changes. For example, if you set this
-// to "true" and then create address or service objects, they will all be shared objects. Set this back to "false" to return to normal mode.
-func (p *PaloAlto) SetShared(shared bool) {
-	if p.DeviceType == "panos" {
-		panic(errors.New("you can only set the shared option on a Panorama device"))
+// Target returns a session that works against the managed firewall with the given serial number, such as one from
+// Devices, by having Panorama forward each request to it. Firewall-only functions, such as Routes, Sessions,
+// ARPTable, InterfaceInfo and TestRouteLookup, can then be used through a single Panorama login. The returned
+// session's fields, such as Model and SoftwareVersion, describe the firewall. It shares the original session's API
+// key, HTTP client and rate limit, and the original session is left unchanged.
+func (p *PaloAlto) Target(serial string) (*PaloAlto, error) {
+	return p.TargetContext(context.Background(), serial)
+}
+
+// TargetContext is the same as Target, but uses ctx for all of its API requests.
+func (p *PaloAlto) TargetContext(ctx context.Context, serial string) (*PaloAlto, error) {
+	if p.DeviceType != "panorama" || p.target != "" {
+		return nil, errors.New("you can only target a managed firewall from a Panorama device")
+	}
+
+	if serial == "" {
+		return nil, errors.New("you must specify the serial number of the firewall")
+	}
+
+	s := *p
+	s.target = serial
+	s.vsys = ""
+
+	if err := s.RefreshContext(ctx); err != nil {
+		return nil, err
 	}
 
-	p.Shared = shared
+	return &s, nil
 }
 
 // Devices returns information about all of the devices that are managed by Panorama.
 func (p *PaloAlto) Devices() (*Devices, error) {
+	return p.DevicesContext(context.Background())
+}
+
+// DevicesContext is the same as Devices, but uses ctx for all of its API requests.
+func (p *PaloAlto) DevicesContext(ctx context.Context) (*Devices, error) {
 	var devices Devices
 
 	if p.DeviceType != "panorama" {
 		return nil, errors.New("devices can only be listed from a Panorama device")
 	}
 
-	_, devData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=op&cmd=<show><devices><all></all></devices></show>&key=%s", p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	devData, err := p.op(ctx, "<show><devices><all></all></devices></show>")
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(devData), &devices); err != nil {
 		return nil, err
 	}
 
-	if devices.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", devices.Code, errorCodes[devices.Code])
-	}
-
 	return &devices, nil
 }
 
@@ -111,6 +133,11 @@ func (p *PaloAlto) Devices() (*Devices, error) {
 // linked to them, along with detailed information about each device. You can (optionally) specify a specific device-group
 // if you wish.
 func (p *PaloAlto) DeviceGroups(devicegroup ...string) (*DeviceGroups, error) {
+	return p.DeviceGroupsContext(context.Background(), devicegroup...)
+}
+
+// DeviceGroupsContext is the same as DeviceGroups, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeviceGroupsContext(ctx context.Context, devicegroup ...string) (*DeviceGroups, error) {
 	var devices DeviceGroups
 	// xpath := "/config/devices/entry//device-group"
 	command := "<show><devicegroups></devicegroups></show>"
@@ -120,32 +147,32 @@ func (p *PaloAlto) DeviceGroups(devicegroup ...string) (*DeviceGroups, error) {
 	}
 
 	if len(devicegroup) > 0 {
-		command = fmt.Sprintf("<show><devicegroups><name>%s</name></devicegroups></show>", devicegroup[0])
+		command = fmt.Sprintf("<show><devicegroups><name>%s</name></devicegroups></show>", xmlEscape(devicegroup[0]))
 	}
 
 	// _, devData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	_, devData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=op&cmd=%s&key=%s", command, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	devData, err := p.op(ctx, command)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(devData), &devices); err != nil {
 		return nil, err
 	}
 
-	if devices.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", devices.Code, errorCodes[devices.Code])
-	}
-
 	return &devices, nil
 }
 
 // CreateDeviceGroup will create a new device-group on a Panorama device. You can add devices as well by
 // specifying the serial numbers in a string slice ([]string). Specify "nil" if you do not wish to add any.
 func (p *PaloAlto) CreateDeviceGroup(name, description string, devices []string) error {
+	return p.CreateDeviceGroupContext(context.Background(), name, description, devices)
+}
+
+// CreateDeviceGroupContext is the same as CreateDeviceGroup, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateDeviceGroupContext(ctx context.Context, name, description string, devices []string) error {
 	var xmlBody string
 	var xpath string
-	var reqError requestError
 
 	if p.DeviceType == "panos" || p.DeviceType != "panorama" {
 		return errors.New("you must be connected to a Panorama device when creating a device-group")
@@ -153,72 +180,62 @@ func (p *PaloAlto) CreateDeviceGroup(name, description string, devices []string)
 
 	if p.DeviceType == "panorama" {
 		xpath = "/config/devices/entry[@name='localhost.localdomain']/device-group"
-		xmlBody = fmt.Sprintf("<entry name=\"%s\">", name)
+		xmlBody = fmt.Sprintf("<entry name=\"%s\">", xmlEscape(name))
 	}
 
 	if devices != nil {
 		xmlBody += "<devices>"
 		for _, s := range devices {
-			xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", strings.TrimSpace(s))
+			xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", xmlEscape(strings.TrimSpace(s)))
 		}
 		xmlBody += "</devices>"
 	}
 
 	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
+		xmlBody += fmt.Sprintf("<description>%s</description>", xmlEscape(description))
 	}
 
 	xmlBody += "</entry>"
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // DeleteDeviceGroup will delete the given device-group from Panorama.
 func (p *PaloAlto) DeleteDeviceGroup(name string) error {
+	return p.DeleteDeviceGroupContext(context.Background(), name)
+}
+
+// DeleteDeviceGroupContext is the same as DeleteDeviceGroup, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteDeviceGroupContext(ctx context.Context, name string) error {
 	var xpath string
-	var reqError requestError
 
 	if p.DeviceType == "panos" || p.DeviceType != "panorama" {
 		return errors.New("you must be connected to a Panorama device when deleting a device-group")
 	}
 
 	if p.DeviceType == "panorama" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']", name)
-	}
-
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
+		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name=%s]", xpathQuote(name))
 	}
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // AddDevice will add a new device to a Panorama. If you specify the optional devicegroup parameter,
 // it will also add the device to the given device-group.
 func (p *PaloAlto) AddDevice(serial string, devicegroup ...string) error {
-	var reqError requestError
+	return p.AddDeviceContext(context.Background(), serial, devicegroup...)
+}
+
+// AddDeviceContext is the same as AddDevice, but uses ctx for all of its API requests.
+func (p *PaloAlto) AddDeviceContext(ctx context.Context, serial string, devicegroup ...string) error {
 
 	if p.DeviceType == "panos" || p.DeviceType != "panorama" {
 		return errors.New("you must be connected to Panorama when adding devices")
@@ -226,55 +243,28 @@ func (p *PaloAlto) AddDevice(serial string, devicegroup ...string) error {
 
 	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
 		xpath := "/config/mgt-config/devices"
-		xmlBody := fmt.Sprintf("<entry name=\"%s\"/>", serial)
+		xmlBody := fmt.Sprintf("<entry name=\"%s\"/>", xmlEscape(serial))
 
-		_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-		if errs != nil {
-			return errs[0]
-		}
-
-		if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+		if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 			return err
 		}
-
-		if reqError.Status != "success" {
-			return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-		}
 	}
 
 	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
 		deviceXpath := "/config/mgt-config/devices"
-		deviceXMLBody := fmt.Sprintf("<entry name=\"%s\"/>", serial)
-		xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']", devicegroup[0])
-		xmlBody := fmt.Sprintf("<devices><entry name=\"%s\"/></devices>", serial)
-
-		_, addResp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", deviceXpath, deviceXMLBody, p.Key)).End()
-		if errs != nil {
-			return errs[0]
-		}
+		deviceXMLBody := fmt.Sprintf("<entry name=\"%s\"/>", xmlEscape(serial))
+		xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name=%s]", xpathQuote(devicegroup[0]))
+		xmlBody := fmt.Sprintf("<devices><entry name=\"%s\"/></devices>", xmlEscape(serial))
 
-		if err := xml.Unmarshal([]byte(addResp), &reqError); err != nil {
+		if err := p.setConfig(ctx, deviceXpath, deviceXMLBody); err != nil {
 			return err
 		}
 
-		if reqError.Status != "success" {
-			return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-		}
-
 		time.Sleep(200 * time.Millisecond)
 
-		_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-		if errs != nil {
-			return errs[0]
-		}
-
-		if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+		if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 			return err
 		}
-
-		if reqError.Status != "success" {
-			return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-		}
 	}
 
 	return nil
@@ -283,64 +273,54 @@ func (p *PaloAlto) AddDevice(serial string, devicegroup ...string) error {
 // SetPanoramaServer will configure a device to be managed by the given Panorama server's primary IP address.
 // You can optionally add a second Panorama server by specifying an IP address for the "secondary" parameter.
 func (p *PaloAlto) SetPanoramaServer(primary string, secondary ...string) error {
-	var reqError requestError
+	return p.SetPanoramaServerContext(context.Background(), primary, secondary...)
+}
+
+// SetPanoramaServerContext is the same as SetPanoramaServer, but uses ctx for all of its API requests.
+func (p *PaloAlto) SetPanoramaServerContext(ctx context.Context, primary string, secondary ...string) error {
 	xpath := "/config/devices/entry[@name='localhost.localdomain']/deviceconfig/system"
-	xmlBody := fmt.Sprintf("<panorama-server>%s</panorama-server>", primary)
+	xmlBody := fmt.Sprintf("<panorama-server>%s</panorama-server>", xmlEscape(primary))
 
 	if len(secondary) > 0 {
-		xmlBody = fmt.Sprintf("<panorama-server>%s</panorama-server><panorama-server-2>%s</panorama-server-2>", primary, secondary[0])
+		xmlBody = fmt.Sprintf("<panorama-server>%s</panorama-server><panorama-server-2>%s</panorama-server-2>", xmlEscape(primary), xmlEscape(secondary[0]))
 	}
 
 	if p.DeviceType == "panorama" && p.Panorama == true {
 		return errors.New("you must be connected to a non-Panorama device in order to configure a Panorama server")
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // RemoveDevice will remove a device from Panorama. If you specify the optional devicegroup parameter,
 // it will only remove the device from the given device-group.
 func (p *PaloAlto) RemoveDevice(serial string, devicegroup ...string) error {
+	return p.RemoveDeviceContext(context.Background(), serial, devicegroup...)
+}
+
+// RemoveDeviceContext is the same as RemoveDevice, but uses ctx for all of its API requests.
+func (p *PaloAlto) RemoveDeviceContext(ctx context.Context, serial string, devicegroup ...string) error {
 	var xpath string
-	var reqError requestError
 
 	if p.DeviceType == "panos" || p.DeviceType != "panorama" {
 		return errors.New("you must be connected to Panorama when removing devices")
 	}
 
 	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		xpath = fmt.Sprintf("/config/mgt-config/devices/entry[@name='%s']", serial)
+		xpath = fmt.Sprintf("/config/mgt-config/devices/entry[@name=%s]", xpathQuote(serial))
 	}
 
 	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/devices/entry[@name='%s']", devicegroup[0], serial)
+		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name=%s]/devices/entry[@name=%s]", xpathQuote(devicegroup[0]), xpathQuote(serial))
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
@@ -0,0 +1,97 @@
+package panos
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/scottdware/go-panos/panostest"
+)
+
+func TestTarget(t *testing.T) {
+	const serial = "000000000002"
+
+	firewall := panostest.NewServer(panostest.WithSystemInfo("serial", serial), panostest.WithSystemInfo("sw-version", "10.2.4"))
+	defer firewall.Close()
+
+	pan, srv := newTestSession(t, panostest.WithPanorama(), panostest.WithManagedDevice(firewall))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var targets []string
+
+	handler := srv.Config.Handler
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		targets = append(targets, r.FormValue("target"))
+		mu.Unlock()
+
+		handler.ServeHTTP(w, r)
+	})
+
+	parent := pan.WithVsys("vsys2")
+
+	child, err := parent.Target(serial)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := child.CreateAddress("web-1", "ip", "10.1.1.1/32", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := child.ImportConfig("backup.xml", strings.NewReader(firewall.Running())); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := child.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	childTargets := targets
+	targets = nil
+	mu.Unlock()
+
+	if len(childTargets) == 0 {
+		t.Fatal("the child session made no requests")
+	}
+
+	for i, target := range childTargets {
+		if target != serial {
+			t.Errorf("request %d of the child session was sent with target %q, want %q", i+1, target, serial)
+		}
+	}
+
+	if child.DeviceType != "panos" || child.Serial != serial || child.SoftwareVersion != "10.2.4" {
+		t.Errorf("got device type %q, serial %q, version %q for the child session", child.DeviceType, child.Serial, child.SoftwareVersion)
+	}
+
+	if child.vsys != "" {
+		t.Errorf("got vsys %q for the child session, want it cleared", child.vsys)
+	}
+
+	if !strings.Contains(firewall.Running(), `<entry name="web-1">`) || strings.Contains(srv.Candidate(), `<entry name="web-1">`) {
+		t.Error("web-1 was not created on the firewall alone")
+	}
+
+	if parent.DeviceType != "panorama" || parent.target != "" || parent.vsys != "vsys2" || parent.Serial == serial {
+		t.Errorf("the parent session changed: device type %q, target %q, vsys %q, serial %q", parent.DeviceType, parent.target, parent.vsys, parent.Serial)
+	}
+
+	if _, err := parent.Devices(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(targets) != 1 || targets[0] != "" {
+		t.Errorf("the parent session sent targets %q, want none", targets)
+	}
+
+	if _, err := child.Target(serial); err == nil {
+		t.Error("targeted a firewall from a session that already targets one")
+	}
+}
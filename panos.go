@@ -2,22 +2,24 @@
 package panos
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
-
-	"github.com/parnurzeal/gorequest"
-	"gopkg.in/resty.v1"
 )
 
 // PaloAlto is a container for our session state. It also holds information about the device
 // that is gathered upon a successful connection to it.
+//
+// A session is safe to use from multiple goroutines at once. None of its methods change the session, other than
+// Refresh; where a change is made is chosen on each call with a Location, or by creating a new session with
+// WithVsys.
 type PaloAlto struct {
 	Host                       string
 	Key                        string
@@ -28,7 +30,6 @@ type PaloAlto struct {
 	SoftwareVersion            string
 	DeviceType                 string
 	Panorama                   bool
-	Shared                     bool
 	IPAddress                  string
 	Netmask                    string
 	DefaultGateway             string
@@ -53,11 +54,29 @@ type PaloAlto struct {
 	LogDBVersion               string
 	MultiVsys                  string
 	OperationalMode            string
+
+	// Version is SoftwareVersion, parsed so that it can be compared.
+	Version Version
+
+	// AppContent, ThreatContent and AntiVirusContent are AppVersion, ThreatVersion and AntiVirusVersion, parsed so
+	// that they can be compared.
+	AppContent       ContentVersion
+	ThreatContent    ContentVersion
+	AntiVirusContent ContentVersion
+
+	client      *http.Client
+	keyHeader   bool
+	vsys        string
+	parallelism int
+	retry       RetryPolicy
+	limiter     *rateLimiter
+	target      string
+	lazy        bool
 }
 
 // AuthMethod defines how we want to authenticate to the device. If using a
 // username and password to authenticate, the Credentials field must contain the username and password
-//, respectively (e.g. []string{"admin", "password"}). If you are using the API key for
+// , respectively (e.g. []string{"admin", "password"}). If you are using the API key for
 // authentication, provide the entire key for the APIKey field.
 type AuthMethod struct {
 	Credentials []string
@@ -85,7 +104,7 @@ type Job struct {
 	QueuePosition int      `xml:"positionInQ"`
 	Progress      string   `xml:"progress"`
 	Details       []string `xml:"details>line"`
-	Warnings      string   `xml:"warnings,omitempty"`
+	Warnings      []string `xml:"warnings>line"`
 	StartTime     string   `xml:"tdeq"`
 	EndTime       string   `xml:"tfin"`
 }
@@ -100,6 +119,9 @@ type Logs struct {
 	JobStatus string   `xml:"result>job>status"`
 	JobID     int      `xml:"result>job>id"`
 	Logs      []Log    `xml:"result>log>logs>entry"`
+
+	// Records holds the same logs as Logs, as the type that matches each log's type, such as *TrafficLog.
+	Records []LogRecord `xml:"-"`
 }
 
 // Log holds information about each individual log retrieved for the following log-types:
@@ -131,13 +153,13 @@ type Log struct {
 	NATSourcePort              int    `xml:"natsport,omitempty"`
 	NATDestinationIP           string `xml:"natdst,omitempty"`
 	NATDestinationPort         int    `xml:"natdport,omitempty"`
-	Packets                    int    `xml:"packets,omitempty"`
-	PacketsSent                int    `xml:"pkts_sent,omitempty"`
-	PacketsReceived            int    `xml:"pkts_received,omitempty"`
-	Bytes                      int    `xml:"bytes,omitempty"`
-	BytesSent                  int    `xml:"bytes_sent,omitempty"`
-	BytesReceived              int    `xml:"bytes_received,omitempty"`
-	SessionID                  int    `xml:"sessionid,omitempty"`
+	Packets                    int64  `xml:"packets,omitempty"`
+	PacketsSent                int64  `xml:"pkts_sent,omitempty"`
+	PacketsReceived            int64  `xml:"pkts_received,omitempty"`
+	Bytes                      int64  `xml:"bytes,omitempty"`
+	BytesSent                  int64  `xml:"bytes_sent,omitempty"`
+	BytesReceived              int64  `xml:"bytes_received,omitempty"`
+	SessionID                  int64  `xml:"sessionid,omitempty"`
 	SessionEndReason           string `xml:"session_end_reason,omitempty"`
 	RepeatCount                int    `xml:"repeatcnt,omitempty"`
 	Start                      string `xml:"start,omitempty"`
@@ -145,13 +167,13 @@ type Log struct {
 	Category                   string `xml:"category,omitempty"`
 	ThreatCategory             string `xml:"thr_category,omitempty"`
 	ThreatName                 string `xml:"threatid,omitempty"`
-	ThreatID                   int    `xml:"tid,omitempty"`
+	ThreatID                   int64  `xml:"tid,omitempty"`
 	Misc                       string `xml:"misc,omitempty"`
 	Severity                   string `xml:"severity,omitempty"`
 	Direction                  string `xml:"direction,omitempty"`
 	InboundInterface           string `xml:"inbound_if,omitempty"`
 	OutboundInterface          string `xml:"outbound_if,omitempty"`
-	ID                         int    `xml:"logid,attr"`
+	ID                         int64  `xml:"logid,attr"`
 	Domain                     int    `xml:"domain,omitempty"`
 	ReceiveTime                string `xml:"receive_time,omitempty"`
 	SequenceNumber             string `xml:"seqno,omitempty"`
@@ -161,7 +183,7 @@ type Log struct {
 	Logset                     string `xml:"logset,omitempty"`
 	Flags                      string `xml:"flags,omitempty"`
 	Pcap                       string `xml:"flag-pcap,omitempty"`
-	PcapID                     int    `xml:"pcap_id,omitempty"`
+	PcapID                     int64  `xml:"pcap_id,omitempty"`
 	Flagged                    string `xml:"flag-flagged,omitempty"`
 	Proxy                      string `xml:"flag-proxy,omitempty"`
 	URLDenied                  string `xml:"flag-url-denied,omitempty"`
@@ -182,10 +204,10 @@ type Log struct {
 	TunnelType                 string `xml:"tunnel,omitempty"`
 	TPadding                   int    `xml:"tpadding,omitempty"`
 	CPadding                   int    `xml:"cpadding,omitempty"`
-	TunnelIMSI                 int    `xml:"tunnelid_imsi,omitempty"`
+	TunnelIMSI                 int64  `xml:"tunnelid_imsi,omitempty"`
 	VsysID                     int    `xml:"vsys_id,omitempty"`
-	ParentSessionID            int    `xml:"parent_session_id,omitempty"`
-	ReportID                   int    `xml:"reportid,omitempty"`
+	ParentSessionID            int64  `xml:"parent_session_id,omitempty"`
+	ReportID                   int64  `xml:"reportid,omitempty"`
 	URLIndex                   int    `xml:"url_idx,omitempty"`
 	HTTPMethod                 string `xml:"http_method,omitempty"`
 	XForwardedFor              string `xml:"xff,omitempty"`
@@ -201,7 +223,7 @@ type Log struct {
 	Cloud                      string `xml:"cloud,omitempty"`
 	Padding                    int    `xml:"padding,omitempty"`
 	ActionSource               string `xml:"action_source,omitempty"`
-	TunnelID                   int    `xml:"tunnelid,omitempty"`
+	TunnelID                   int64  `xml:"tunnelid,omitempty"`
 	IMSI                       string `xml:"imsi,omitempty"`
 	MonitorTag                 string `xml:"monitortag,omitempty"`
 	IMEI                       string `xml:"imei,omitempty"`
@@ -226,9 +248,13 @@ type Log struct {
 // LogParameters specifies additional parameters that can be used when retrieving logs. These are all optional.
 type LogParameters struct {
 	// Query specifies the match criteria for the logs. This is similar to the query provided in the web interface under the Monitor
-	// tab when viewing the logs. The query must be URL encoded.
+	// tab when viewing the logs.
 	Query string
 
+	// Filter is the query, built with typed conditions such as LogField.Addr.Src.In("10.1.1.1").And(LogField.App.Eq("ssl"))
+	// rather than written by hand. Only one of Query and Filter can be set.
+	Filter *LogFilter
+
 	// NLogs specifies the number of logs to retrieve. The default is 20 when the parameter is not specified. The maximum is 5000.
 	NLogs int
 
@@ -316,14 +342,6 @@ type commandOutput struct {
 	Data    string   `xml:"result"`
 }
 
-// requestError contains information about any error we get from a request.
-type requestError struct {
-	XMLName xml.Name `xml:"response"`
-	Status  string   `xml:"status,attr"`
-	Code    string   `xml:"code,attr"`
-	Message string   `xml:"result>msg,omitempty"`
-}
-
 // testURL contains the results of the operational command test url.
 type testURL struct {
 	XMLName xml.Name `xml:"response"`
@@ -533,8 +551,6 @@ type SingleSession struct {
 }
 
 var (
-	r = gorequest.New().TLSClientConfig(&tls.Config{InsecureSkipVerify: true})
-
 	errorCodes = map[string]string{
 		"400": "Bad request - Returned when a required parameter is missing, an illegal parameter value is used",
 		"403": "Forbidden - Returned for authentication or authorization errors including invalid key, insufficient admin access rights",
@@ -563,17 +579,6 @@ var (
 	}
 )
 
-// splitSWVersion breaks up the version numbering into a []int.
-func splitSWVersion(version string) []int {
-	re := regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
-	match := re.FindStringSubmatch(version)
-	maj, _ := strconv.Atoi(match[1])
-	min, _ := strconv.Atoi(match[2])
-	rel, _ := strconv.Atoi(match[3])
-
-	return []int{maj, min, rel}
-}
-
 // sliceToString converts a slice to a string, separated by a comma.
 func sliceToString(slice []string) string {
 	var str string
@@ -600,28 +605,104 @@ func stringToSlice(str string) []string {
 // NewSession sets up our connection to the Palo Alto firewall or Panorama device. The authmethod parameter
 // is used to define two ways of authenticating to the device. One is via username/password, the other is with
 // the API key if you already have generated it. Please see the documentation for the AuthMethod struct for further
-// details.
-func NewSession(host string, authmethod *AuthMethod) (*PaloAlto, error) {
+// details. You can (optionally) specify one or more options, such as WithHTTPClient, WithTimeout or WithRootCAs, to
+// change how the session talks to the device. The device's certificate is verified against the host's root CA set
+// unless WithRootCAs, WithPinnedCertificate or WithInsecureSkipVerify say otherwise. Use NewSessionWithOptions to
+// create the session without contacting the device.
+func NewSession(host string, authmethod *AuthMethod, options ...Option) (*PaloAlto, error) {
+	return NewSessionContext(context.Background(), host, authmethod, options...)
+}
+
+// NewSessionContext is the same as NewSession, but uses ctx for all of its API requests.
+func NewSessionContext(ctx context.Context, host string, authmethod *AuthMethod, options ...Option) (*PaloAlto, error) {
+	return NewSessionWithOptionsContext(ctx, host, authmethod, nil, options...)
+}
+
+// SessionOptions describes what is already known about a device, for NewSessionWithOptions.
+type SessionOptions struct {
+	// Lazy skips asking the device for its system information and Panorama status when the session is created, so
+	// creating a session with an API key makes no requests at all. DeviceType must be set as well, since many
+	// functions depend on it. The rest of the session's fields stay blank until Refresh is called; functions that
+	// need them ask the device on each call until then.
+	Lazy bool
+
+	// DeviceType is the kind of device: "panos" for a firewall, or "panorama". A lazy session takes it as given.
+	// Otherwise, NewSessionWithOptions returns an error if the device turns out to be a different kind.
+	DeviceType string
+
+	// Panorama is whether a firewall is managed by Panorama, for a lazy session. It is ignored by other sessions,
+	// which ask the device.
+	Panorama bool
+
+	// SoftwareVersion is the PAN-OS version of the device (e.g. "10.1.6"), for a lazy session. Without it, functions
+	// that behave differently on older releases assume a current one, and the API key is sent in the form body
+	// rather than the X-PAN-KEY header. It is ignored by other sessions, which ask the device.
+	SoftwareVersion string
+}
+
+// NewSessionWithOptions is the same as NewSession, but takes what is already known about the device as a
+// *SessionOptions, such as for a lazy session that doesn't talk to the device until it is used. Any other options,
+// such as WithHTTPClient, can still be given after it. If session is nil, it is the same as NewSession.
+func NewSessionWithOptions(host string, authmethod *AuthMethod, session *SessionOptions, options ...Option) (*PaloAlto, error) {
+	return NewSessionWithOptionsContext(context.Background(), host, authmethod, session, options...)
+}
+
+// NewSessionWithOptionsContext is the same as NewSessionWithOptions, but uses ctx for all of its API requests.
+func NewSessionWithOptionsContext(ctx context.Context, host string, authmethod *AuthMethod, session *SessionOptions, options ...Option) (*PaloAlto, error) {
 	var keygen authKey
 	var key string
-	var info systemInfo
-	var pan commandOutput
-	status := false
-	deviceType := "panos"
+
+	if session == nil {
+		session = &SessionOptions{}
+	}
+
+	var opts sessionOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
+	switch session.DeviceType {
+	case "":
+		if session.Lazy {
+			return nil, errors.New("you must specify the device type for a lazy session")
+		}
+	case "panos", "panorama":
+	default:
+		return nil, fmt.Errorf("%s is not a valid device type; it must be one of: panos or panorama", session.DeviceType)
+	}
+
+	client, err := opts.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PaloAlto{
+		Host:        host,
+		URI:         fmt.Sprintf("https://%s/api/?", host),
+		client:      client,
+		parallelism: opts.parallelism,
+		retry:       opts.retry,
+	}
+
+	if opts.rateLimit > 0 {
+		p.limiter = newRateLimiter(opts.rateLimit, opts.burst)
+	}
 
 	if len(authmethod.Credentials) > 0 {
-		_, body, errs := r.Get(fmt.Sprintf("https://%s/api/?type=keygen&user=%s&password=%s", host, authmethod.Credentials[0], authmethod.Credentials[1])).End()
-		if errs != nil {
-			return nil, fmt.Errorf("unable to connect to %s - %s", host, errs[0])
+		creds := url.Values{
+			"type":     {"keygen"},
+			"user":     {authmethod.Credentials[0]},
+			"password": {authmethod.Credentials[1]},
 		}
 
-		err := xml.Unmarshal([]byte(body), &keygen)
+		body, err := p.request(ctx, creds)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("unable to connect to %s - %w", host, err)
 		}
 
-		if keygen.Status != "success" {
-			return nil, fmt.Errorf("error code %s: %s (keygen)", keygen.Code, errorCodes[keygen.Code])
+		err = xml.Unmarshal([]byte(body), &keygen)
+		if err != nil {
+			return nil, err
 		}
 
 		key = keygen.Key
@@ -631,153 +712,172 @@ func NewSession(host string, authmethod *AuthMethod) (*PaloAlto, error) {
 		key = authmethod.APIKey
 	}
 
-	uri := fmt.Sprintf("https://%s/api/?", host)
-	_, getInfo, errs := r.Get(fmt.Sprintf("%s&key=%s&type=op&cmd=<show><system><info></info></system></show>", uri, key)).End()
-	if errs != nil {
-		return nil, fmt.Errorf("unable to get system info for %s - %s", host, errs[0])
-	}
+	p.Key = key
 
-	err := xml.Unmarshal([]byte(getInfo), &info)
-	if err != nil {
-		return nil, err
-	}
+	if session.Lazy {
+		p.lazy = true
+		p.DeviceType = session.DeviceType
+		p.Panorama = session.Panorama
+		p.SoftwareVersion = session.SoftwareVersion
+		p.Version, _ = ParseVersion(session.SoftwareVersion)
+		p.keyHeader = keyHeaderSupported(p.Version)
 
-	if info.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s (show system info)", info.Code, errorCodes[info.Code])
+		return p, nil
 	}
 
-	_, panStatus, errs := r.Get(fmt.Sprintf("%s&key=%s&type=op&cmd=<show><panorama-status></panorama-status></show>", uri, key)).End()
-	if errs != nil {
-		return nil, fmt.Errorf("unable to get Panorama status for %s - %s", host, errs[0])
-	}
-
-	err = xml.Unmarshal([]byte(panStatus), &pan)
-	if err != nil {
+	if err := p.RefreshContext(ctx); err != nil {
 		return nil, err
 	}
 
-	if info.Platform == "m" || info.Model == "Panorama" {
-		deviceType = "panorama"
-	}
-
-	if strings.Contains(pan.Data, ": yes") {
-		status = true
+	if session.DeviceType != "" && session.DeviceType != p.DeviceType {
+		return nil, fmt.Errorf("%s is a %s device, not %s", host, p.DeviceType, session.DeviceType)
 	}
 
-	return &PaloAlto{
-		Host:                       host,
-		Key:                        key,
-		URI:                        fmt.Sprintf("https://%s/api/?", host),
-		Platform:                   info.Platform,
-		Model:                      info.Model,
-		Serial:                     info.Serial,
-		SoftwareVersion:            info.SoftwareVersion,
-		DeviceType:                 deviceType,
-		Panorama:                   status,
-		Shared:                     false,
-		IPAddress:                  info.IPAddress,
-		Netmask:                    info.Netmask,
-		DefaultGateway:             info.DefaultGateway,
-		MACAddress:                 info.MACAddress,
-		Time:                       strings.Trim(info.Time, "[\r\n]"),
-		Uptime:                     info.Uptime,
-		GPClientPackageVersion:     info.GPClientPackageVersion,
-		GPDatafileVersion:          info.GPDatafileVersion,
-		GPDatafileReleaseDate:      info.GPDatafileReleaseDate,
-		GPClientlessVPNVersion:     info.GPClientlessVPNVersion,
-		GPClientlessVPNReleaseDate: info.GPClientlessVPNReleaseDate,
-		AppVersion:                 info.AppVersion,
-		AppReleaseDate:             info.AppReleaseDate,
-		AntiVirusVersion:           info.AntiVirusVersion,
-		AntiVirusReleaseDate:       info.AntiVirusReleaseDate,
-		ThreatVersion:              info.ThreatVersion,
-		ThreatReleaseDate:          info.ThreatReleaseDate,
-		WildfireVersion:            info.WildfireVersion,
-		WildfireReleaseDate:        info.WildfireReleaseDate,
-		URLDB:                      info.URLDB,
-		URLFilteringVersion:        info.URLFilteringVersion,
-		LogDBVersion:               info.LogDBVersion,
-		MultiVsys:                  info.MultiVsys,
-		OperationalMode:            info.OperationalMode,
-	}, nil
+	return p, nil
 }
 
-// Commit issues a commit on the device. When issuing a commit against a Panorama device,
-// the configuration will only be committed to Panorama, and not an individual device-group.
-func (p *PaloAlto) Commit() error {
-	var reqError requestError
-
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=commit&cmd=<commit></commit>&key=%s", p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+// Refresh asks the device for its system information and Panorama status again, and updates the session's fields,
+// such as SoftwareVersion, Version and the content versions. Use it to fill in the fields of a lazy session, or to
+// pick up a software or content update.
+//
+// Refresh is the one method that changes the session, so unlike the others, it must not be called while the session
+// is being used from other goroutines.
+func (p *PaloAlto) Refresh() error {
+	return p.RefreshContext(context.Background())
+}
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+// RefreshContext is the same as Refresh, but uses ctx for all of its API requests.
+func (p *PaloAlto) RefreshContext(ctx context.Context) error {
+	if err := p.discover(ctx); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+	p.lazy = false
+
+	// Requests from a Target session go to Panorama, so its version decides how the key is sent.
+	if p.target == "" {
+		p.keyHeader = keyHeaderSupported(p.Version)
 	}
 
 	return nil
 }
 
-// CommitAll issues a commit to a Panorama device, for the given devicegroup. If you wish to push to specific
-// firewalls within the specified device group only, add each firewalls serial number as an additional parameter,
-// (e.g. CommitAll("Some-DeviceGroup", "000000000001", "000000000002")).
-func (p *PaloAlto) CommitAll(devicegroup string, devices ...string) error {
-	var reqError requestError
-	var cmd string
+// discovered returns the session, with the fields that describe the device filled in. A lazy session that hasn't
+// been refreshed doesn't know them, so they are asked for on each call, and filled in on a copy of the session,
+// since the session itself must not change while it is in use.
+func (p *PaloAlto) discovered(ctx context.Context) (*PaloAlto, error) {
+	if !p.lazy {
+		return p, nil
+	}
 
-	if p.DeviceType == "panorama" && len(devices) <= 0 {
-		cmd = fmt.Sprintf("<commit-all><shared-policy><device-group><entry name=\"%s\"/></device-group></shared-policy></commit-all>", devicegroup)
+	s := *p
+	if err := s.discover(ctx); err != nil {
+		return nil, err
 	}
 
-	if p.DeviceType == "panorama" && len(devices) > 0 {
-		cmd = fmt.Sprintf("<commit-all><shared-policy><device-group><entry name=\"%s\"/>><devices>", devicegroup)
+	return &s, nil
+}
 
-		for _, d := range devices {
-			cmd += fmt.Sprintf("<entry name=\"%s\"/>", d)
-		}
+// discover asks the device for its system information and Panorama status, and fills in the session's fields with
+// them.
+func (p *PaloAlto) discover(ctx context.Context) error {
+	var info systemInfo
+	var pan commandOutput
+	status := false
+	deviceType := "panos"
 
-		cmd += "</devices></entry></device-group></shared-policy></commit-all>"
+	getInfo, err := p.op(ctx, "<show><system><info></info></system></show>")
+	if err != nil {
+		return fmt.Errorf("unable to get system info for %s - %w", p.name(), err)
+	}
+
+	err = xml.Unmarshal([]byte(getInfo), &info)
+	if err != nil {
+		return err
 	}
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=commit&action=all&cmd=%s&key=%s", cmd, p.Key)).End()
-	if errs != nil {
-		return errs[0]
+	// Devices that can't report a Panorama status (e.g. Panorama itself) respond with an error, which just
+	// means they are not managed by Panorama.
+	var apiErr *APIError
+	panStatus, err := p.op(ctx, "<show><panorama-status></panorama-status></show>")
+	if err != nil && !errors.As(err, &apiErr) {
+		return fmt.Errorf("unable to get Panorama status for %s - %w", p.name(), err)
 	}
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-		return err
+	if err == nil {
+		if err := xml.Unmarshal([]byte(panStatus), &pan); err != nil {
+			return err
+		}
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+	if info.Platform == "m" || info.Model == "Panorama" {
+		deviceType = "panorama"
 	}
 
+	if strings.Contains(pan.Data, ": yes") {
+		status = true
+	}
+
+	p.Platform = info.Platform
+	p.Model = info.Model
+	p.Serial = info.Serial
+	p.SoftwareVersion = info.SoftwareVersion
+	p.Version, _ = ParseVersion(info.SoftwareVersion)
+	p.DeviceType = deviceType
+	p.Panorama = status
+	p.IPAddress = info.IPAddress
+	p.Netmask = info.Netmask
+	p.DefaultGateway = info.DefaultGateway
+	p.MACAddress = info.MACAddress
+	p.Time = strings.Trim(info.Time, "[\r\n]")
+	p.Uptime = info.Uptime
+	p.GPClientPackageVersion = info.GPClientPackageVersion
+	p.GPDatafileVersion = info.GPDatafileVersion
+	p.GPDatafileReleaseDate = info.GPDatafileReleaseDate
+	p.GPClientlessVPNVersion = info.GPClientlessVPNVersion
+	p.GPClientlessVPNReleaseDate = info.GPClientlessVPNReleaseDate
+	p.AppVersion = info.AppVersion
+	p.AppContent, _ = ParseContentVersion(info.AppVersion)
+	p.AppReleaseDate = info.AppReleaseDate
+	p.AntiVirusVersion = info.AntiVirusVersion
+	p.AntiVirusContent, _ = ParseContentVersion(info.AntiVirusVersion)
+	p.AntiVirusReleaseDate = info.AntiVirusReleaseDate
+	p.ThreatVersion = info.ThreatVersion
+	p.ThreatContent, _ = ParseContentVersion(info.ThreatVersion)
+	p.ThreatReleaseDate = info.ThreatReleaseDate
+	p.WildfireVersion = info.WildfireVersion
+	p.WildfireReleaseDate = info.WildfireReleaseDate
+	p.URLDB = info.URLDB
+	p.URLFilteringVersion = info.URLFilteringVersion
+	p.LogDBVersion = info.LogDBVersion
+	p.MultiVsys = info.MultiVsys
+	p.OperationalMode = info.OperationalMode
+
 	return nil
 }
 
+// name returns the device that the session talks to, for use in error messages.
+func (p *PaloAlto) name() string {
+	if p.target != "" {
+		return fmt.Sprintf("%s (through %s)", p.target, p.Host)
+	}
+
+	return p.Host
+}
+
 // RestartSystem will issue a system restart to the device.
 func (p *PaloAlto) RestartSystem() error {
-	var reqError requestError
-	command := "<request><restart><system></system></restart></request>"
+	return p.RestartSystemContext(context.Background())
+}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=op&cmd=%s&key=%s", command, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+// RestartSystemContext is the same as RestartSystem, but uses ctx for all of its API requests.
+func (p *PaloAlto) RestartSystemContext(ctx context.Context) error {
+	command := "<request><restart><system></system></restart></request>"
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if _, err := p.op(ctx, command); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -785,27 +885,28 @@ func (p *PaloAlto) RestartSystem() error {
 // first one is from the Base db categorization, and the second is from the Cloud db categorization. If you specify a URL
 // with a wildcard, such as *.paloaltonetworks.com, it will not return a result.
 func (p *PaloAlto) TestURL(url string) ([]string, error) {
+	return p.TestURLContext(context.Background(), url)
+}
+
+// TestURLContext is the same as TestURL, but uses ctx for all of its API requests.
+func (p *PaloAlto) TestURLContext(ctx context.Context, url string) ([]string, error) {
 	var urlResults testURL
 	rex := regexp.MustCompile(`(?m)^([\d\.a-zA-Z-]+)\s([\w-]+)\s.*seconds\s([\d\.a-zA-Z-]+)\s([\w-]+)\s`)
-	command := fmt.Sprintf("<test><url>%s</url></test>", url)
+	command := fmt.Sprintf("<test><url>%s</url></test>", xmlEscape(url))
 
 	if p.DeviceType == "panorama" {
 		return nil, errors.New("you can only test URL's from a local device")
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=op&cmd=%s&key=%s", command, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	resp, err := p.op(ctx, command)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(resp), &urlResults); err != nil {
 		return nil, err
 	}
 
-	if urlResults.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", urlResults.Code, errorCodes[urlResults.Code])
-	}
-
 	categorization := rex.FindStringSubmatch(urlResults.Result)
 
 	if len(categorization) == 0 {
@@ -822,26 +923,27 @@ func (p *PaloAlto) TestURL(url string) ([]string, error) {
 
 // TestRouteLookup will lookup the given destination IP in the virtual-router "vr" and return the results.
 func (p *PaloAlto) TestRouteLookup(vr, destination string) (*RouteLookup, error) {
+	return p.TestRouteLookupContext(context.Background(), vr, destination)
+}
+
+// TestRouteLookupContext is the same as TestRouteLookup, but uses ctx for all of its API requests.
+func (p *PaloAlto) TestRouteLookupContext(ctx context.Context, vr, destination string) (*RouteLookup, error) {
 	var testRouteLookup routeLookupResults
-	command := fmt.Sprintf("<test><routing><fib-lookup><virtual-router>%s</virtual-router><ip>%s</ip></fib-lookup></routing></test>", vr, destination)
+	command := fmt.Sprintf("<test><routing><fib-lookup><virtual-router>%s</virtual-router><ip>%s</ip></fib-lookup></routing></test>", xmlEscape(vr), xmlEscape(destination))
 
 	if p.DeviceType == "panorama" {
 		return nil, errors.New("you can only test route lookups from a local device")
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=op&cmd=%s&key=%s", command, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	resp, err := p.op(ctx, command)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(resp), &testRouteLookup); err != nil {
 		return nil, err
 	}
 
-	if testRouteLookup.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", testRouteLookup.Code, errorCodes[testRouteLookup.Code])
-	}
-
 	return &RouteLookup{
 		NextHop:   testRouteLookup.NextHop,
 		Source:    testRouteLookup.Source,
@@ -855,6 +957,11 @@ func (p *PaloAlto) TestRouteLookup(vr, destination string) (*RouteLookup, error)
 // Jobs returns information about every job on the device. Status can be one of: all, pending, or processed. If you want
 // information about a specific job, specify the job ID instead of one of the other options.
 func (p *PaloAlto) Jobs(status interface{}) (*Jobs, error) {
+	return p.JobsContext(context.Background(), status)
+}
+
+// JobsContext is the same as Jobs, but uses ctx for all of its API requests.
+func (p *PaloAlto) JobsContext(ctx context.Context, status interface{}) (*Jobs, error) {
 	var jobs Jobs
 	var cmd string
 
@@ -875,12 +982,12 @@ func (p *PaloAlto) Jobs(status interface{}) (*Jobs, error) {
 		cmd += fmt.Sprintf("<show><jobs><id>%d</id></jobs></show>", status)
 	}
 
-	_, res, errs := r.Get(fmt.Sprintf("%s&key=%s&type=op&cmd=%s", p.URI, p.Key, cmd)).End()
-	if errs != nil {
-		return nil, errs[0]
+	res, err := p.op(ctx, cmd)
+	if err != nil {
+		return nil, err
 	}
 
-	err := xml.Unmarshal([]byte(res), &jobs)
+	err = xml.Unmarshal([]byte(res), &jobs)
 	if err != nil {
 		return nil, err
 	}
@@ -897,33 +1004,46 @@ func (p *PaloAlto) Jobs(status interface{}) (*Jobs, error) {
 // description of options. If you do not wish to use any of the optional parameters, just specify nil. The job ID is
 // returned from the query, and should be passed to RetrieveLogs().
 func (p *PaloAlto) QueryLogs(logtype string, parameters *LogParameters) (int, error) {
+	return p.QueryLogsContext(context.Background(), logtype, parameters)
+}
+
+// QueryLogsContext is the same as QueryLogs, but uses ctx for all of its API requests.
+func (p *PaloAlto) QueryLogsContext(ctx context.Context, logtype string, parameters *LogParameters) (int, error) {
 	var id logID
-	req := fmt.Sprintf("%s&key=%s&type=log&log-type=%s", p.URI, p.Key, logtype)
+	req := url.Values{"type": {"log"}, "log-type": {logtype}}
 
 	if parameters != nil {
+		if parameters.Query != "" && parameters.Filter != nil {
+			return 0, errors.New("you can only specify one of Query or Filter")
+		}
+
 		if parameters.Query != "" {
-			req += fmt.Sprintf("&query=%s", url.QueryEscape(parameters.Query))
+			req.Set("query", parameters.Query)
+		}
+
+		if parameters.Filter != nil {
+			req.Set("query", parameters.Filter.String())
 		}
 
 		if parameters.NLogs > 0 {
-			req += fmt.Sprintf("&nlogs=%d", parameters.NLogs)
+			req.Set("nlogs", strconv.Itoa(parameters.NLogs))
 		}
 
 		if parameters.Direction != "" {
-			req += fmt.Sprintf("&dir=%s", parameters.Direction)
+			req.Set("dir", parameters.Direction)
 		}
 
 		if parameters.Skip > 0 {
-			req += fmt.Sprintf("&skip=%d", parameters.Skip)
+			req.Set("skip", strconv.Itoa(parameters.Skip))
 		}
 	}
 
-	_, res, errs := r.Get(req).End()
-	if errs != nil {
-		return 0, errs[0]
+	res, err := p.request(ctx, req)
+	if err != nil {
+		return 0, err
 	}
 
-	err := xml.Unmarshal([]byte(res), &id)
+	err = xml.Unmarshal([]byte(res), &id)
 	if err != nil {
 		return 0, err
 	}
@@ -933,16 +1053,27 @@ func (p *PaloAlto) QueryLogs(logtype string, parameters *LogParameters) (int, er
 
 // RetrieveLogs will return the log data as specified in the QueryLogs() function, given the job ID. If the job
 // status is not FIN, then you will have to query the job ID until it has finished and then it will return the
-// results.
+// results. Each log is returned in the Logs field, and again in the Records field as the type that matches its log
+// type (e.g. *TrafficLog or *SystemLog).
 func (p *PaloAlto) RetrieveLogs(id int) (*Logs, error) {
+	return p.RetrieveLogsContext(context.Background(), id)
+}
+
+// RetrieveLogsContext is the same as RetrieveLogs, but uses ctx for all of its API requests.
+func (p *PaloAlto) RetrieveLogsContext(ctx context.Context, id int) (*Logs, error) {
 	var logs Logs
 
-	_, res, errs := r.Get(fmt.Sprintf("%s&key=%s&type=log&action=get&job-id=%d", p.URI, p.Key, id)).End()
-	if errs != nil {
-		return nil, errs[0]
+	res, err := p.request(ctx, url.Values{"type": {"log"}, "action": {"get"}, "job-id": {strconv.Itoa(id)}})
+	if err != nil {
+		return nil, err
+	}
+
+	err = xml.Unmarshal([]byte(res), &logs)
+	if err != nil {
+		return nil, err
 	}
 
-	err := xml.Unmarshal([]byte(res), &logs)
+	logs.Records, err = logRecords([]byte(res))
 	if err != nil {
 		return nil, err
 	}
@@ -962,8 +1093,12 @@ func (p *PaloAlto) RetrieveLogs(id int) (*Logs, error) {
 //
 // See https://goo.gl/G1vzJT for details regarding all of the actions available.
 func (p *PaloAlto) XpathConfig(action, xpath string, element ...string) error {
-	var reqError requestError
-	var query string
+	return p.XpathConfigContext(context.Background(), action, xpath, element...)
+}
+
+// XpathConfigContext is the same as XpathConfig, but uses ctx for all of its API requests.
+func (p *PaloAlto) XpathConfigContext(ctx context.Context, action, xpath string, element ...string) error {
+	var params url.Values
 
 	switch action {
 	case "set", "edit", "override":
@@ -978,33 +1113,22 @@ func (p *PaloAlto) XpathConfig(action, xpath string, element ...string) error {
 			}
 
 			xmlcontents := string(c)
-			query = fmt.Sprintf("type=config&action=%s&xpath=%s&element=%s&key=%s", action, xpath, xmlcontents, p.Key)
+			params = url.Values{"element": {xmlcontents}}
 		} else {
-			query = fmt.Sprintf("type=config&action=%s&xpath=%s&element=%s&key=%s", action, xpath, element[0], p.Key)
+			params = url.Values{"element": {element[0]}}
 		}
 	case "rename":
 		if len(element) <= 0 {
 			return errors.New("you must specify the element parameter when renaming an object")
 		}
 
-		query = fmt.Sprintf("type=config&action=%s&xpath=%s&newname=%s&key=%s", action, xpath, element[0], p.Key)
-	case "delete":
-		query = fmt.Sprintf("type=config&action=%s&xpath=%s&key=%s", action, xpath, p.Key)
+		params = url.Values{"newname": {element[0]}}
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(query).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if _, err := p.config(ctx, action, xpath, params); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -1014,23 +1138,17 @@ func (p *PaloAlto) XpathConfig(action, xpath string, element ...string) error {
 //
 // See https://goo.gl/ZfmBB6 for details.
 func (p *PaloAlto) XpathClone(xpath, from, newname string) error {
-	var reqError requestError
+	return p.XpathCloneContext(context.Background(), xpath, from, newname)
+}
 
-	query := fmt.Sprintf("type=config&action=clone&xpath=%s&from=%s&newname=%s&key=%s", xpath, from, newname, p.Key)
+// XpathCloneContext is the same as XpathClone, but uses ctx for all of its API requests.
+func (p *PaloAlto) XpathCloneContext(ctx context.Context, xpath, from, newname string) error {
+	params := url.Values{"from": {from}, "newname": {newname}}
 
-	_, resp, errs := r.Post(p.URI).Query(query).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if _, err := p.config(ctx, "clone", xpath, params); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -1040,28 +1158,21 @@ func (p *PaloAlto) XpathClone(xpath, from, newname string) error {
 //
 // See https://goo.gl/LbkQDG for details.
 func (p *PaloAlto) XpathMove(xpath, where string, destination ...string) error {
-	var reqError requestError
-	var query string
+	return p.XpathMoveContext(context.Background(), xpath, where, destination...)
+}
 
-	query = fmt.Sprintf("type=config&action=move&xpath=%s&where=%s&key=%s", xpath, where, p.Key)
+// XpathMoveContext is the same as XpathMove, but uses ctx for all of its API requests.
+func (p *PaloAlto) XpathMoveContext(ctx context.Context, xpath, where string, destination ...string) error {
+	params := url.Values{"where": {where}}
 
 	if len(destination) > 0 {
-		query = fmt.Sprintf("type=config&action=move&xpath=%s&where=%s&dst=%s&key=%s", xpath, where, destination[0], p.Key)
-	}
-
-	_, resp, errs := r.Post(p.URI).Query(query).End()
-	if errs != nil {
-		return errs[0]
+		params.Set("dst", destination[0])
 	}
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if _, err := p.config(ctx, "move", xpath, params); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -1072,8 +1183,12 @@ func (p *PaloAlto) XpathMove(xpath, where string, destination ...string) error {
 //
 // See https://goo.gl/oeufnu for details.
 func (p *PaloAlto) XpathMulti(action, xpath, element string) error {
-	var reqError requestError
-	var query string
+	return p.XpathMultiContext(context.Background(), action, xpath, element)
+}
+
+// XpathMultiContext is the same as XpathMulti, but uses ctx for all of its API requests.
+func (p *PaloAlto) XpathMultiContext(ctx context.Context, action, xpath, element string) error {
+	params := url.Values{"element": {element}}
 
 	if strings.Contains(element, ".xml") {
 		c, err := ioutil.ReadFile(element)
@@ -1082,53 +1197,38 @@ func (p *PaloAlto) XpathMulti(action, xpath, element string) error {
 		}
 
 		xmlcontents := string(c)
-		query = fmt.Sprintf("type=config&action=multi-%s&xpath=%s&element=%s&key=%s", action, xpath, xmlcontents, p.Key)
-	} else {
-		query = fmt.Sprintf("type=config&action=multi-%s&xpath=%s&element=%s&key=%s", action, xpath, element, p.Key)
-	}
-
-	_, resp, errs := r.Post(p.URI).Query(query).End()
-	if errs != nil {
-		return errs[0]
+		params.Set("element", xmlcontents)
 	}
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if _, err := p.config(ctx, fmt.Sprintf("multi-%s", action), xpath, params); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // XpathGetConfig allows you to view the active or candidate configuration at the location specified in the
 // xpath parameter.
 func (p *PaloAlto) XpathGetConfig(configtype, xpath string) (string, error) {
-	var reqError requestError
-	var query string
+	return p.XpathGetConfigContext(context.Background(), configtype, xpath)
+}
+
+// XpathGetConfigContext is the same as XpathGetConfig, but uses ctx for all of its API requests.
+func (p *PaloAlto) XpathGetConfigContext(ctx context.Context, configtype, xpath string) (string, error) {
+	var action string
 
 	switch configtype {
 	case "active":
-		query = fmt.Sprintf("type=config&action=show&xpath=%s&key=%s", xpath, p.Key)
+		action = "show"
 	case "candidate":
-		query = fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)
-	}
-
-	_, resp, errs := r.Post(p.URI).Query(query).End()
-	if errs != nil {
-		return "", errs[0]
+		action = "get"
 	}
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	resp, err := p.config(ctx, action, xpath, nil)
+	if err != nil {
 		return "", err
 	}
 
-	if reqError.Status != "success" {
-		return "", fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return resp, nil
 }
 
@@ -1136,15 +1236,19 @@ func (p *PaloAlto) XpathGetConfig(configtype, xpath string) (string, error) {
 // must use the XML-formatted version of the command string as if you were calling the API yourself,
 // (e.g. "<show><running><ippool></ippool></running></show>")
 func (p *PaloAlto) Command(command string) (string, error) {
+	return p.CommandContext(context.Background(), command)
+}
+
+// CommandContext is the same as Command, but uses ctx for all of its API requests.
+func (p *PaloAlto) CommandContext(ctx context.Context, command string) (string, error) {
 	var output commandOutput
-	resty.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
 
-	resp, err := resty.R().Get(fmt.Sprintf("%s&key=%s&type=op&cmd=%s", p.URI, p.Key, command))
+	resp, err := p.op(ctx, command)
 	if err != nil {
-		return "", fmt.Errorf("unable to run command '%s' - %s", command, err)
+		return "", fmt.Errorf("unable to run command '%s' - %w", command, err)
 	}
 
-	err = xml.Unmarshal([]byte(resp.String()), &output)
+	err = xml.Unmarshal([]byte(resp), &output)
 	if err != nil {
 		return "", err
 	}
@@ -1155,33 +1259,31 @@ func (p *PaloAlto) Command(command string) (string, error) {
 // Routes will retrieve information about each route in the devices routing table(s). You can (optionally) specify
 // a specific virtual router to retrieve routes from.
 func (p *PaloAlto) Routes(vr ...string) (*RoutingTable, error) {
-	var rt RoutingTable
-	resty.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	return p.RoutesContext(context.Background(), vr...)
+}
 
-	query := fmt.Sprintf("%s&key=%s&type=op&cmd=<show><routing><route></route></routing></show>", p.URI, p.Key)
+// RoutesContext is the same as Routes, but uses ctx for all of its API requests.
+func (p *PaloAlto) RoutesContext(ctx context.Context, vr ...string) (*RoutingTable, error) {
+	var rt RoutingTable
+	cmd := "<show><routing><route></route></routing></show>"
 
 	if p.DeviceType != "panos" {
 		return nil, errors.New("you can only retrieve the routing table on a local firewall")
 	}
 
 	if len(vr) > 0 {
-		query = fmt.Sprintf("%s&key=%s&type=op&cmd=<show><routing><route><virtual-router>%s</virtual-router></route></routing></show>", p.URI, p.Key, vr[0])
+		cmd = fmt.Sprintf("<show><routing><route><virtual-router>%s</virtual-router></route></routing></show>", xmlEscape(vr[0]))
 	}
 
-	resp, err := resty.R().Get(query)
-	// _, resp, errs := r.Post(p.URI).Query(query).End()
+	resp, err := p.op(ctx, cmd)
 	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve routing table - %s", err)
+		return nil, fmt.Errorf("unable to retrieve routing table - %w", err)
 	}
 
-	if err := xml.Unmarshal([]byte(resp.String()), &rt); err != nil {
+	if err := xml.Unmarshal([]byte(resp), &rt); err != nil {
 		return nil, fmt.Errorf("cannot unmarshal XML from routing table - %s", err)
 	}
 
-	if rt.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", rt.Code, errorCodes[rt.Code])
-	}
-
 	return &rt, nil
 }
 
@@ -1195,8 +1297,13 @@ func (p *PaloAlto) Routes(vr ...string) (*RoutingTable, error) {
 //
 // "application=ssl, ssl-decrypt=yes, protocol=tcp"
 func (p *PaloAlto) Sessions(filter ...string) (*SessionTable, error) {
+	return p.SessionsContext(context.Background(), filter...)
+}
+
+// SessionsContext is the same as Sessions, but uses ctx for all of its API requests.
+func (p *PaloAlto) SessionsContext(ctx context.Context, filter ...string) (*SessionTable, error) {
 	var st SessionTable
-	query := fmt.Sprintf("%s&key=%s&type=op&cmd=<show><session><all></all></session></show>", p.URI, p.Key)
+	cmd := "<show><session><all></all></session></show>"
 
 	if len(filter) > 0 {
 		var filterString string
@@ -1214,82 +1321,78 @@ func (p *PaloAlto) Sessions(filter ...string) (*SessionTable, error) {
 				continue
 			}
 
-			filterString += fmt.Sprintf("<%s>%s</%s>", f[0], f[1], f[0])
+			filterString += fmt.Sprintf("<%s>%s</%s>", f[0], xmlEscape(f[1]), f[0])
 		}
 
-		query = fmt.Sprintf("%s&key=%s&type=op&cmd=<show><session><all><filter>%s</filter></all></session></show>", p.URI, p.Key, filterString)
+		cmd = fmt.Sprintf("<show><session><all><filter>%s</filter></all></session></show>", filterString)
 	}
 
 	if p.DeviceType != "panos" {
 		return nil, errors.New("you can only retrieve the session table on a local firewall")
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(query).End()
-	if errs != nil {
-		return nil, errs[0]
+	resp, err := p.op(ctx, cmd)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(resp), &st); err != nil {
 		return nil, err
 	}
 
-	if st.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", st.Code, errorCodes[st.Code])
-	}
-
 	return &st, nil
 }
 
 // SessionID will retrieve information about the given session on a firewall.
 func (p *PaloAlto) SessionID(id string) (*SessionID, error) {
+	return p.SessionIDContext(context.Background(), id)
+}
+
+// SessionIDContext is the same as SessionID, but uses ctx for all of its API requests.
+func (p *PaloAlto) SessionIDContext(ctx context.Context, id string) (*SessionID, error) {
 	var st SessionID
-	query := fmt.Sprintf("%s&key=%s&type=op&cmd=<show><session><id>%s</id></session></show>", p.URI, p.Key, id)
+	cmd := fmt.Sprintf("<show><session><id>%s</id></session></show>", xmlEscape(id))
 
 	if p.DeviceType != "panos" {
 		return nil, errors.New("you can only retrieve the session table on a local firewall")
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(query).End()
-	if errs != nil {
-		return nil, errs[0]
+	resp, err := p.op(ctx, cmd)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(resp), &st); err != nil {
 		return nil, err
 	}
 
-	if st.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", st.Code, errorCodes[st.Code])
-	}
-
 	return &st, nil
 }
 
 // ApplicationInfo gathers information about every pre-defined application on the device.
 func (p *PaloAlto) ApplicationInfo(name ...string) (*ApplicationInformation, error) {
+	return p.ApplicationInfoContext(context.Background(), name...)
+}
+
+// ApplicationInfoContext is the same as ApplicationInfo, but uses ctx for all of its API requests.
+func (p *PaloAlto) ApplicationInfoContext(ctx context.Context, name ...string) (*ApplicationInformation, error) {
 	var apps ApplicationInformation
 	var allApps allApplications
 	var singleApp singleApplication
 	xpath := "/config/predefined/application"
 
 	if len(name) > 0 {
-		xpath = fmt.Sprintf("/config/predefined/application/entry[@name='%s']", name[0])
-
-		query := fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)
+		xpath = fmt.Sprintf("/config/predefined/application/entry[@name=%s]", xpathQuote(name[0]))
 
-		_, resp, errs := r.Post(p.URI).Query(query).End()
-		if errs != nil {
-			return nil, errs[0]
+		resp, err := p.getConfig(ctx, xpath)
+		if err != nil {
+			return nil, err
 		}
 
 		if err := xml.Unmarshal([]byte(resp), &singleApp); err != nil {
 			return nil, err
 		}
 
-		if singleApp.Status != "success" {
-			return nil, fmt.Errorf("error code %s: %s", singleApp.Code, errorCodes[singleApp.Code])
-		}
-
 		for _, a := range singleApp.Applications {
 			apps.Applications = append(apps.Applications, a)
 		}
@@ -1297,21 +1400,15 @@ func (p *PaloAlto) ApplicationInfo(name ...string) (*ApplicationInformation, err
 		return &apps, nil
 	}
 
-	query := fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)
-
-	_, resp, errs := r.Post(p.URI).Query(query).End()
-	if errs != nil {
-		return nil, errs[0]
+	resp, err := p.getConfig(ctx, xpath)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(resp), &allApps); err != nil {
 		return nil, err
 	}
 
-	if allApps.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", allApps.Code, errorCodes[allApps.Code])
-	}
-
 	for _, a := range allApps.Applications {
 		apps.Applications = append(apps.Applications, a)
 	}
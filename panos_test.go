@@ -0,0 +1,203 @@
+package panos
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/scottdware/go-panos/panostest"
+)
+
+// newTestSession starts a fake device with the given options, and returns a session connected to it. Close the
+// server when the test is done.
+func newTestSession(t *testing.T, options ...panostest.Option) (*PaloAlto, *panostest.Server) {
+	t.Helper()
+
+	srv := panostest.NewServer(options...)
+
+	pan, err := NewSession(srv.Host(), &AuthMethod{APIKey: srv.APIKey()}, WithHTTPClient(srv.Client()))
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+
+	return pan, srv
+}
+
+func TestNewSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    []panostest.Option
+		auth       func(srv *panostest.Server) *AuthMethod
+		wantErr    bool
+		deviceType string
+		panorama   bool
+	}{
+		{
+			name:       "API key",
+			auth:       func(srv *panostest.Server) *AuthMethod { return &AuthMethod{APIKey: srv.APIKey()} },
+			deviceType: "panos",
+		},
+		{
+			name:       "credentials",
+			auth:       func(srv *panostest.Server) *AuthMethod { return &AuthMethod{Credentials: []string{"admin", "admin"}} },
+			deviceType: "panos",
+		},
+		{
+			name:    "custom credentials",
+			options: []panostest.Option{panostest.WithCredentials("jdoe", "s3cret&more")},
+			auth: func(srv *panostest.Server) *AuthMethod {
+				return &AuthMethod{Credentials: []string{"jdoe", "s3cret&more"}}
+			},
+			deviceType: "panos",
+		},
+		{
+			name:    "wrong credentials",
+			auth:    func(srv *panostest.Server) *AuthMethod { return &AuthMethod{Credentials: []string{"admin", "wrong"}} },
+			wantErr: true,
+		},
+		{
+			name:    "wrong API key",
+			auth:    func(srv *panostest.Server) *AuthMethod { return &AuthMethod{APIKey: "wrong"} },
+			wantErr: true,
+		},
+		{
+			name:       "Panorama",
+			options:    []panostest.Option{panostest.WithPanorama()},
+			auth:       func(srv *panostest.Server) *AuthMethod { return &AuthMethod{APIKey: srv.APIKey()} },
+			deviceType: "panorama",
+		},
+		{
+			name:       "managed by Panorama",
+			options:    []panostest.Option{panostest.WithManagedBy("10.0.0.5")},
+			auth:       func(srv *panostest.Server) *AuthMethod { return &AuthMethod{APIKey: srv.APIKey()} },
+			deviceType: "panos",
+			panorama:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := panostest.NewServer(tt.options...)
+			defer srv.Close()
+
+			pan, err := NewSession(srv.Host(), tt.auth(srv), WithHTTPClient(srv.Client()))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if pan.Key != srv.APIKey() {
+				t.Errorf("got key %q, want %q", pan.Key, srv.APIKey())
+			}
+
+			if pan.DeviceType != tt.deviceType {
+				t.Errorf("got device type %q, want %q", pan.DeviceType, tt.deviceType)
+			}
+
+			if pan.Panorama != tt.panorama {
+				t.Errorf("got Panorama %v, want %v", pan.Panorama, tt.panorama)
+			}
+
+			if pan.Serial == "" || pan.Version.IsZero() {
+				t.Errorf("system info was not read: serial %q, version %q", pan.Serial, pan.SoftwareVersion)
+			}
+		})
+	}
+}
+
+func TestNewSessionLazy(t *testing.T) {
+	srv := panostest.NewServer(panostest.WithSystemInfo("multi-vsys", "on"),
+		panostest.WithConfig(`<config><devices><entry name="localhost.localdomain"><vsys><entry name="vsys1"/><entry name="vsys2"/></vsys></entry></devices></config>`))
+	defer srv.Close()
+
+	pan, err := NewSessionWithOptions(srv.Host(), &AuthMethod{APIKey: srv.APIKey()},
+		&SessionOptions{Lazy: true, DeviceType: "panos", Panorama: true, SoftwareVersion: "9.1.3"}, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pan.Serial != "" || !pan.Panorama || !pan.Version.AtLeast(9, 1, 3) {
+		t.Fatalf("got serial %q, Panorama %v, version %s", pan.Serial, pan.Panorama, pan.Version)
+	}
+
+	vsys, err := pan.Vsys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vsys.Vsys) != 2 {
+		t.Fatalf("got %d virtual systems from a lazy session, want 2", len(vsys.Vsys))
+	}
+
+	dir, err := ioutil.TempDir("", "panos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := pan.Backup(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 1 || filepath.Base(filepath.Dir(files[0])) != "000000000001" {
+		t.Fatalf("got backups %v, want one under the serial number", files)
+	}
+
+	if pan.Serial != "" {
+		t.Fatal("using the lazy session changed it")
+	}
+
+	if err := pan.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	if pan.Serial != "000000000001" || pan.Panorama || pan.SoftwareVersion != "10.1.0" {
+		t.Fatalf("got serial %q, Panorama %v, version %s after Refresh", pan.Serial, pan.Panorama, pan.SoftwareVersion)
+	}
+}
+
+func TestNewSessionWithOptions(t *testing.T) {
+	srv := panostest.NewServer(panostest.WithPanorama())
+	defer srv.Close()
+
+	auth := &AuthMethod{APIKey: srv.APIKey()}
+
+	// Nothing is listening on the host, so creating the session fails if it makes any requests.
+	pan, err := NewSessionWithOptions("127.0.0.1:1", auth, &SessionOptions{Lazy: true, DeviceType: "panos", Panorama: true, SoftwareVersion: "10.1.6-h3"})
+	if err != nil {
+		t.Fatalf("a lazy session made a request: %v", err)
+	}
+
+	if pan.DeviceType != "panos" || !pan.Panorama || pan.Version != (Version{Major: 10, Minor: 1, Patch: 6, Hotfix: 3}) {
+		t.Fatalf("got device type %q, Panorama %v, version %+v", pan.DeviceType, pan.Panorama, pan.Version)
+	}
+
+	if _, err := NewSessionWithOptions(srv.Host(), auth, &SessionOptions{Lazy: true}); err == nil {
+		t.Fatal("a lazy session was created without a device type")
+	}
+
+	if _, err := NewSessionWithOptions(srv.Host(), auth, &SessionOptions{DeviceType: "panos"}, WithHTTPClient(srv.Client())); err == nil {
+		t.Fatal("a Panorama session was created with the panos device type")
+	}
+
+	for _, session := range []*SessionOptions{nil, {}, {DeviceType: "panorama"}} {
+		pan, err := NewSessionWithOptions(srv.Host(), auth, session, WithHTTPClient(srv.Client()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if pan.DeviceType != "panorama" || pan.Version.IsZero() {
+			t.Fatalf("got device type %q, version %q with %+v", pan.DeviceType, pan.SoftwareVersion, session)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package panostest
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// maxLogs is the most logs that a single log query can return.
+const maxLogs = 5000
+
+// logJob is a log query on the fake device.
+type logJob struct {
+	id       int
+	logs     []string
+	queued   time.Time
+	finished time.Time
+}
+
+// WithLogs adds logs of the given type (e.g. "traffic" or "system") that log queries return. Each entry is the
+// contents of a log's <entry> element, such as "<src>10.1.1.1</src><dst>10.2.2.2</dst>". Entries are given newest
+// first, and are numbered with a logid attribute in that order.
+func WithLogs(logtype string, entries ...string) Option {
+	return func(s *Server) {
+		s.logs[logtype] = append(s.logs[logtype], entries...)
+	}
+}
+
+// log answers a request to start a log query, or to get the results of one.
+func (s *Server) log(params url.Values) (string, *apiError) {
+	if params.Get("action") == "get" {
+		return s.logResults(params.Get("job-id"))
+	}
+
+	nlogs, skip := 20, 0
+	if v := params.Get("nlogs"); v != "" {
+		nlogs, _ = strconv.Atoi(v)
+		if nlogs < 1 || nlogs > maxLogs {
+			return "", &apiError{code: "17", msg: fmt.Sprintf("nlogs must be between 1 and %d", maxLogs)}
+		}
+	}
+
+	if v := params.Get("skip"); v != "" {
+		skip, _ = strconv.Atoi(v)
+	}
+
+	// Entries are numbered newest first, whichever order they are returned in.
+	var logs []string
+	for i, entry := range s.logs[params.Get("log-type")] {
+		logs = append(logs, fmt.Sprintf(`<entry logid="%d">%s</entry>`, i+1, entry))
+	}
+
+	if params.Get("dir") == "forward" {
+		for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+			logs[i], logs[j] = logs[j], logs[i]
+		}
+	}
+
+	if skip > len(logs) {
+		skip = len(logs)
+	}
+
+	logs = logs[skip:]
+	if len(logs) > nlogs {
+		logs = logs[:nlogs]
+	}
+
+	now := time.Now()
+	j := &logJob{id: len(s.logJobs) + 1, logs: logs, queued: now, finished: now.Add(s.jobDuration)}
+	s.logJobs = append(s.logJobs, j)
+
+	return fmt.Sprintf(`<response status="success" code="19"><result><msg><line>query job enqueued with jobid %d</line></msg><job>%d</job></result></response>`, j.id, j.id), nil
+}
+
+// logResults answers a request for the results of a log query. Until the query finishes, no logs are returned.
+func (s *Server) logResults(id string) (string, *apiError) {
+	n, _ := strconv.Atoi(id)
+	if n < 1 || n > len(s.logJobs) {
+		return "", &apiError{code: "17", msg: fmt.Sprintf("job %s not found", id)}
+	}
+
+	j := s.logJobs[n-1]
+	if time.Now().Before(j.finished) {
+		return success(fmt.Sprintf(`<job><tenq>%s</tenq><tdeq>%s</tdeq><tlast>%s</tlast><status>ACT</status><id>%d</id></job><log><logs count="0" progress="50"/></log>`,
+			j.queued.Format(timeFormat), j.queued.Format("15:04:05"), j.queued.Format("15:04:05"), j.id)), nil
+	}
+
+	var logs string
+	for _, entry := range j.logs {
+		logs += entry
+	}
+
+	return success(fmt.Sprintf(`<job><tenq>%s</tenq><tdeq>%s</tdeq><tlast>%s</tlast><status>FIN</status><id>%d</id><tfin>%s</tfin></job><log><logs count="%d" progress="100">%s</logs></log>`,
+		j.queued.Format(timeFormat), j.queued.Format("15:04:05"), j.finished.Format("15:04:05"), j.id, j.finished.Format("15:04:05"), len(j.logs), logs)), nil
+}
@@ -0,0 +1,906 @@
+// Package panostest provides a fake PAN-OS XML API server, for testing code that uses go-panos without a real
+// firewall or Panorama device.
+//
+// The server keeps a candidate and a running configuration in memory. It understands keygen requests, the
+// "show system info", "show panorama-status", "show jobs" and "validate" operational commands, config and commit
+// locks, reverting, saving and loading the candidate configuration, configuration requests (get, show, set, edit,
+// delete, rename, move and clone), commits, exporting and importing configuration files and certificates, and log
+// queries. A fake Panorama device can manage other fake servers, and forwards requests with a target parameter to
+// them. For example:
+//
+//	srv := panostest.NewServer()
+//	defer srv.Close()
+//
+//	pan, err := panos.NewSession(srv.Host(), &panos.AuthMethod{Credentials: []string{"admin", "admin"}},
+//		panos.WithHTTPClient(srv.Client()))
+//
+// Xpaths may use / and //, element names or *, and predicates that compare an attribute or text() to a string,
+// such as entry[@name='web-server'] or member[text()='web-server']. That covers every xpath that go-panos builds.
+//
+// The configuration is not validated against the PAN-OS schema, and renaming an object does not update the
+// places that refer to it. Log queries return the logs given to WithLogs, without applying the query filter.
+package panostest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timeFormat is how PAN-OS formats the times in job details.
+const timeFormat = "2006/01/02 15:04:05"
+
+// firewallConfig is the configuration that a fake firewall starts with.
+const firewallConfig = `<config><mgt-config/><shared/><devices><entry name="localhost.localdomain"><vsys><entry name="vsys1"/></vsys></entry></devices></config>`
+
+// panoramaConfig is the configuration that a fake Panorama device starts with.
+const panoramaConfig = `<config><mgt-config/><shared/><panorama/><devices><entry name="localhost.localdomain"><device-group/><template/><template-stack/></entry></devices></config>`
+
+// Option is used to configure a fake device when calling NewServer.
+type Option func(*Server)
+
+// WithPanorama makes the server act as a Panorama device, instead of a firewall.
+func WithPanorama() Option {
+	return func(s *Server) {
+		s.panorama = true
+		s.info["model"] = "Panorama"
+		s.info["platform-family"] = "m"
+		s.info["family"] = "m"
+	}
+}
+
+// WithManagedBy makes the fake firewall report that it is connected to the Panorama device at the given address.
+func WithManagedBy(panorama string) Option {
+	return func(s *Server) {
+		s.managedBy = panorama
+	}
+}
+
+// WithSystemInfo sets one of the fields returned by "show system info", such as "sw-version" or "multi-vsys".
+func WithSystemInfo(field, value string) Option {
+	return func(s *Server) {
+		s.info[field] = value
+	}
+}
+
+// WithManagedDevice makes the fake Panorama device manage the given fake firewall, which shows up in "show devices
+// all". Requests with a target parameter of the firewall's serial number are answered by the firewall, the same
+// way that a real Panorama device forwards them.
+func WithManagedDevice(device *Server) Option {
+	return func(s *Server) {
+		s.devices = append(s.devices, device)
+	}
+}
+
+// WithCredentials sets the username and password that keygen requests must use. The default is admin/admin.
+func WithCredentials(username, password string) Option {
+	return func(s *Server) {
+		s.username = username
+		s.password = password
+	}
+}
+
+// WithAPIKey sets the API key that the server hands out, and expects on every request.
+func WithAPIKey(key string) Option {
+	return func(s *Server) {
+		s.key = key
+	}
+}
+
+// WithConfig sets the configuration that the device starts with, as the full <config> element. It is used as
+// both the candidate and the running configuration.
+func WithConfig(config string) Option {
+	return func(s *Server) {
+		s.initial = config
+	}
+}
+
+// WithJobDuration sets how long each commit job and log query stays active before it finishes. By default, jobs
+// finish as soon as they are created.
+func WithJobDuration(d time.Duration) Option {
+	return func(s *Server) {
+		s.jobDuration = d
+	}
+}
+
+// Server is a fake PAN-OS device, listening on a local HTTPS address. It is safe to use from multiple goroutines.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	panorama    bool
+	managedBy   string
+	devices     []*Server
+	info        map[string]string
+	username    string
+	password    string
+	key         string
+	initial     string
+	jobDuration time.Duration
+	candidate   *node
+	running     *node
+	dirty       bool
+	jobs        []*job
+	locks       []*lock
+	saved       map[string]*node
+	certs       map[string]*certificate
+	logs        map[string][]string
+	logJobs     []*logJob
+}
+
+// certificate is a certificate that was imported to the fake device, along with its private key, if any.
+type certificate struct {
+	data       []byte
+	keyPair    bool
+	passphrase string
+}
+
+// lock is a config or commit lock on the fake device.
+type lock struct {
+	lockType string
+	scope    string
+	admin    string
+	comment  string
+	created  time.Time
+}
+
+// job is a commit job on the fake device.
+type job struct {
+	id       int
+	jobType  string
+	user     string
+	queued   time.Time
+	finished time.Time
+}
+
+// NewServer starts a fake device with the given options, and returns it. Call Close when you are done with it.
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		username: "admin",
+		password: "admin",
+		saved:    map[string]*node{},
+		certs:    map[string]*certificate{},
+		logs:     map[string][]string{},
+		info: map[string]string{
+			"hostname":         "fake-panos",
+			"ip-address":       "192.0.2.1",
+			"netmask":          "255.255.255.0",
+			"default-gateway":  "192.0.2.254",
+			"mac-address":      "00:00:5e:00:53:01",
+			"time":             time.Now().Format("Mon Jan _2 15:04:05 2006"),
+			"uptime":           "0 days, 0:00:01",
+			"family":           "vm",
+			"model":            "PA-VM",
+			"serial":           "000000000001",
+			"sw-version":       "10.1.0",
+			"app-version":      "8500-7000",
+			"threat-version":   "8500-7000",
+			"platform-family":  "vm",
+			"multi-vsys":       "off",
+			"operational-mode": "normal",
+		},
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if s.key == "" {
+		sum := sha256.Sum256([]byte(s.username + ":" + s.password))
+		s.key = base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	if s.initial == "" {
+		s.initial = firewallConfig
+		if s.panorama {
+			s.initial = panoramaConfig
+		}
+	}
+
+	root, err := parseFragment(s.initial)
+	if err != nil {
+		panic(fmt.Sprintf("panostest: invalid configuration: %s", err))
+	}
+
+	s.running = root
+	s.candidate = root.clone()
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// Host returns the address of the server, to pass to panos.NewSession.
+func (s *Server) Host() string {
+	return strings.TrimPrefix(s.URL, "https://")
+}
+
+// APIKey returns the API key that the server hands out, and expects on every request.
+func (s *Server) APIKey() string {
+	return s.key
+}
+
+// Candidate returns the candidate configuration, as XML.
+func (s *Server) Candidate() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return configXML(s.candidate)
+}
+
+// Running returns the running configuration, as XML.
+func (s *Server) Running() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return configXML(s.running)
+}
+
+// configXML returns every element in the given tree as XML.
+func configXML(root *node) string {
+	var config string
+	for _, c := range root.children {
+		config += c.String()
+	}
+
+	return config
+}
+
+// apiError is an error response, with its PAN-OS error code and HTTP status.
+type apiError struct {
+	status int
+	code   string
+	msg    string
+}
+
+// handle answers a single API request.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		reply(w, http.StatusBadRequest, errorResponse("400", err.Error()))
+		return
+	}
+
+	params := r.Form
+	if params.Get("type") == "keygen" {
+		s.keygen(w, r.PostForm)
+		return
+	}
+
+	key := r.Header.Get("X-PAN-KEY")
+	if key == "" {
+		key = params.Get("key")
+	}
+
+	if key != s.key {
+		reply(w, http.StatusForbidden, errorResponse("403", "Invalid Credential"))
+		return
+	}
+
+	if target := params.Get("target"); target != "" {
+		device := s.device(target)
+		if device == nil {
+			reply(w, http.StatusOK, errorResponse("17", fmt.Sprintf("device %s is not connected", target)))
+			return
+		}
+
+		device.serve(w, r)
+		return
+	}
+
+	s.serve(w, r)
+}
+
+// serve answers an API request that has been authenticated, either by the server itself, or by the Panorama device
+// that manages it.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	params := r.Form
+
+	var body string
+	var err *apiError
+
+	switch params.Get("type") {
+	case "op":
+		if params.Get("action") == "complete" {
+			body, err = s.complete(params.Get("xpath"))
+			break
+		}
+
+		body, err = s.op(params.Get("cmd"), params.Get("vsys"))
+	case "config":
+		body, err = s.config(params)
+	case "commit":
+		body, err = s.commit(params)
+	case "export":
+		body, err = s.export(params)
+	case "import":
+		body, err = s.importFile(r)
+	case "log":
+		body, err = s.log(params)
+	default:
+		err = &apiError{status: http.StatusBadRequest, code: "400", msg: "Invalid type"}
+	}
+
+	if err != nil {
+		status := err.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		reply(w, status, errorResponse(err.code, err.msg))
+		return
+	}
+
+	reply(w, http.StatusOK, body)
+}
+
+// device returns the managed firewall with the given serial number, or nil if there isn't one.
+func (s *Server) device(serial string) *Server {
+	for _, d := range s.devices {
+		if d.info["serial"] == serial {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// reply writes an XML response body.
+func reply(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/xml; charset=UTF-8")
+	w.WriteHeader(status)
+	fmt.Fprint(w, body)
+}
+
+// errorResponse returns an error response, with the given code and message.
+func errorResponse(code, msg string) string {
+	return fmt.Sprintf(`<response status="error" code="%s"><msg><line>%s</line></msg></response>`, code, escape(msg))
+}
+
+// success returns a successful response with the given result, which must already be XML.
+func success(result string) string {
+	return fmt.Sprintf(`<response status="success" code="19"><result>%s</result></response>`, result)
+}
+
+// escape returns s with its special XML characters escaped.
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+
+	return buf.String()
+}
+
+// keygen answers a request for an API key. Like PAN-OS, only credentials in the body of a POST request are
+// accepted, so they never end up in the URL.
+func (s *Server) keygen(w http.ResponseWriter, params url.Values) {
+	if params.Get("user") != s.username || params.Get("password") != s.password {
+		reply(w, http.StatusForbidden, errorResponse("403", "Invalid credentials."))
+		return
+	}
+
+	reply(w, http.StatusOK, success(fmt.Sprintf("<key>%s</key>", escape(s.key))))
+}
+
+// op answers an operational command. The vsys parameter is the location that the command applies to, if any.
+func (s *Server) op(cmd, vsys string) (string, *apiError) {
+	root, err := parseFragment(cmd)
+	if err != nil || len(root.children) != 1 {
+		return "", &apiError{code: "18", msg: "Malformed command"}
+	}
+
+	var words []string
+	n := root.children[0]
+	for {
+		words = append(words, n.name)
+		if len(n.children) == 0 {
+			break
+		}
+
+		n = n.children[0]
+	}
+
+	switch command := strings.Join(words, " "); command {
+	case "request config-lock add", "request config-lock add comment", "request commit-lock add", "request commit-lock add comment":
+		return s.addLock(words[1], vsys, n.text)
+	case "request config-lock remove", "request commit-lock remove":
+		return s.removeLock(words[1], vsys)
+	case "show config-locks", "show commit-locks":
+		return s.showLocks(strings.TrimSuffix(words[1], "s")), nil
+	case "revert config", "revert config partial admin member":
+		// Every change is made by the server's one administrator, so a partial revert throws away all of them, or
+		// none of them if that administrator is not listed.
+		steps, _ := parseXpath("/revert/config/partial/admin/member")
+		admins := find(root, steps)
+
+		revert := len(admins) == 0
+		for _, admin := range admins {
+			revert = revert || admin.text == s.username
+		}
+
+		if revert {
+			s.candidate = s.running.clone()
+			s.dirty = false
+		}
+
+		return success("Candidate configuration reverted"), nil
+	case "save config to":
+		if n.text == "" {
+			return "", &apiError{code: "17", msg: "save -> config -> to is missing a name"}
+		}
+
+		s.saved[n.text] = s.candidate.clone()
+
+		return success(fmt.Sprintf("Config saved to %s", escape(n.text))), nil
+	case "load config from":
+		saved, ok := s.saved[n.text]
+		if !ok {
+			return "", &apiError{code: "13", msg: fmt.Sprintf("%s does not exist", n.text)}
+		}
+
+		s.candidate = saved.clone()
+		s.dirty = true
+
+		return success(fmt.Sprintf("Config loaded from %s", escape(n.text))), nil
+	case "show system info":
+		var fields []string
+		for field := range s.info {
+			fields = append(fields, field)
+		}
+
+		sort.Strings(fields)
+
+		var info string
+		for _, field := range fields {
+			info += fmt.Sprintf("<%s>%s</%s>", field, escape(s.info[field]), field)
+		}
+
+		return success("<system>" + info + "</system>"), nil
+	case "show panorama-status":
+		if s.panorama {
+			return "", &apiError{code: "17", msg: "show -> panorama-status is unexpected"}
+		}
+
+		if s.managedBy == "" {
+			return success(""), nil
+		}
+
+		status := fmt.Sprintf("\n Panorama Server 1 : %s\n    Connected     : yes\n    HA state      : disconnected\n", s.managedBy)
+
+		return success(escape(status)), nil
+	case "show devices all":
+		if !s.panorama {
+			return "", &apiError{code: "17", msg: "show -> devices is unexpected"}
+		}
+
+		var devices string
+		for _, d := range s.devices {
+			devices += fmt.Sprintf("<entry name=\"%s\"><serial>%s</serial><connected>yes</connected>", escape(d.info["serial"]), escape(d.info["serial"]))
+			for _, field := range []string{"hostname", "ip-address", "model", "sw-version"} {
+				devices += fmt.Sprintf("<%s>%s</%s>", field, escape(d.info[field]), field)
+			}
+
+			devices += "</entry>"
+		}
+
+		return success("<devices>" + devices + "</devices>"), nil
+	case "validate full":
+		return s.enqueue("Validate"), nil
+	case "show jobs all":
+		return s.showJobs(func(*job) bool { return true }), nil
+	case "show jobs pending":
+		return s.showJobs(func(j *job) bool { return time.Now().Before(j.finished) }), nil
+	case "show jobs processed":
+		return s.showJobs(func(j *job) bool { return !time.Now().Before(j.finished) }), nil
+	case "show jobs id":
+		id, _ := strconv.Atoi(n.text)
+		if id < 1 || id > len(s.jobs) {
+			return "", &apiError{code: "17", msg: fmt.Sprintf("job %s not found", n.text)}
+		}
+
+		return s.showJobs(func(j *job) bool { return j.id == id }), nil
+	}
+
+	return "", &apiError{code: "17", msg: fmt.Sprintf("%s is unexpected", strings.Join(words, " -> "))}
+}
+
+// complete answers a request for the completions of an operational command. Only the saved configurations, for
+// "load config from", are supported.
+func (s *Server) complete(xpath string) (string, *apiError) {
+	if xpath != "/operations/load/config/from" {
+		return "", &apiError{code: "17", msg: fmt.Sprintf("no completions for %s", xpath)}
+	}
+
+	var names []string
+	for name := range s.saved {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	completions := `<completion value="running-config.xml" help-string="running-config.xml"/>`
+	for _, name := range names {
+		completions += fmt.Sprintf(`<completion value="%s" help-string="%s"/>`, escape(name), escape(name))
+	}
+
+	return fmt.Sprintf(`<response status="success"><completions>%s</completions></response>`, completions), nil
+}
+
+// addLock takes a lock of the given type (config-lock or commit-lock) on scope, for the administrator.
+func (s *Server) addLock(lockType, scope, comment string) (string, *apiError) {
+	if scope == "" {
+		scope = "shared"
+	}
+
+	for _, l := range s.locks {
+		if l.lockType == lockType && l.scope == scope {
+			return "", &apiError{code: "13", msg: fmt.Sprintf("%s is already held by %s", lockType, l.admin)}
+		}
+	}
+
+	s.locks = append(s.locks, &lock{lockType: lockType, scope: scope, admin: s.username, comment: comment, created: time.Now()})
+
+	return success(fmt.Sprintf("Successfully acquired lock. Other administrators will not be able to modify %s", strings.TrimSuffix(lockType, "-lock"))), nil
+}
+
+// removeLock releases the lock of the given type on scope.
+func (s *Server) removeLock(lockType, scope string) (string, *apiError) {
+	if scope == "" {
+		scope = "shared"
+	}
+
+	for i, l := range s.locks {
+		if l.lockType == lockType && l.scope == scope {
+			s.locks = append(s.locks[:i], s.locks[i+1:]...)
+			return success("Successfully released lock"), nil
+		}
+	}
+
+	return "", &apiError{code: "13", msg: fmt.Sprintf("no %s is held", lockType)}
+}
+
+// showLocks returns every lock of the given type.
+func (s *Server) showLocks(lockType string) string {
+	var locks string
+	for _, l := range s.locks {
+		if l.lockType == lockType {
+			locks += fmt.Sprintf(`<entry name="%s"><name>%s</name><type>%s</type><loggedin>yes</loggedin><comment>%s</comment><created>%s</created><vsys>%s</vsys></entry>`,
+				escape(l.admin), escape(l.admin), strings.TrimSuffix(lockType, "-lock"), escape(l.comment), l.created.Format(timeFormat), escape(l.scope))
+		}
+	}
+
+	return success(fmt.Sprintf("<%ss>%s</%ss>", lockType, locks, lockType))
+}
+
+// showJobs returns the details of every job that keep returns true for.
+func (s *Server) showJobs(keep func(*job) bool) string {
+	var jobs string
+
+	now := time.Now()
+	for _, j := range s.jobs {
+		if !keep(j) {
+			continue
+		}
+
+		status, result, progress, finished := "FIN", "OK", "100", j.finished.Format(timeFormat)
+		details := "<details><line>Configuration committed successfully</line></details>"
+		if j.jobType == "Validate" {
+			details = "<details><line>Configuration is valid</line></details>"
+		}
+
+		if now.Before(j.finished) {
+			done := now.Sub(j.queued) * 100 / j.finished.Sub(j.queued)
+			status, result, progress, finished, details = "ACT", "PEND", strconv.Itoa(int(done)), "Still Active", "<details/>"
+		}
+
+		jobs += fmt.Sprintf("<job><tenq>%s</tenq><tdeq>%s</tdeq><id>%d</id><user>%s</user><type>%s</type><status>%s</status>"+
+			"<queued>NO</queued><stoppable>no</stoppable><result>%s</result><tfin>%s</tfin><description/><positionInQ>0</positionInQ>"+
+			"<progress>%s</progress>%s<warnings/></job>", j.queued.Format(timeFormat), j.queued.Format("15:04:05"), j.id,
+			escape(j.user), j.jobType, status, result, finished, progress, details)
+	}
+
+	return success(jobs)
+}
+
+// commit answers a commit request. The candidate configuration becomes the running configuration straight away,
+// and a job is created to report on it.
+func (s *Server) commit(params url.Values) (string, *apiError) {
+	cmd, err := parseFragment(params.Get("cmd"))
+	if err != nil {
+		return "", &apiError{code: "18", msg: "Malformed command"}
+	}
+
+	if params.Get("action") == "all" {
+		if !s.panorama {
+			return "", &apiError{code: "17", msg: "commit-all is only supported on Panorama"}
+		}
+
+		steps, _ := parseXpath("/commit-all/shared-policy/validate-only")
+		if len(find(cmd, steps)) > 0 {
+			return s.enqueue("Validate"), nil
+		}
+
+		return s.enqueue("CommitAll"), nil
+	}
+
+	if !s.dirty {
+		return `<response status="success" code="19"><msg>There are no changes to commit.</msg></response>`, nil
+	}
+
+	s.running = s.candidate.clone()
+	s.dirty = false
+
+	return s.enqueue("Commit"), nil
+}
+
+// enqueue creates a job of the given type, and returns the response that tells the client its ID.
+func (s *Server) enqueue(jobType string) string {
+	now := time.Now()
+	j := &job{id: len(s.jobs) + 1, jobType: jobType, user: s.username, queued: now, finished: now.Add(s.jobDuration)}
+	s.jobs = append(s.jobs, j)
+
+	return fmt.Sprintf(`<response status="success" code="19"><result><msg><line>%s job enqueued with jobid %d</line></msg><job>%d</job></result></response>`, jobType, j.id, j.id)
+}
+
+// export answers a request to export the running configuration, or a certificate.
+func (s *Server) export(params url.Values) (string, *apiError) {
+	switch params.Get("category") {
+	case "configuration":
+		return configXML(s.running), nil
+	case "certificate":
+		cert, ok := s.certs[params.Get("certificate-name")]
+		if !ok {
+			return "", &apiError{code: "7", msg: fmt.Sprintf("certificate %s does not exist", params.Get("certificate-name"))}
+		}
+
+		if params.Get("include-key") == "yes" && (!cert.keyPair || params.Get("passphrase") == "") {
+			return "", &apiError{code: "13", msg: "the private key can't be exported"}
+		}
+
+		return string(cert.data), nil
+	}
+
+	return "", &apiError{code: "17", msg: fmt.Sprintf("unsupported export category %s", params.Get("category"))}
+}
+
+// importFile answers a request to import a configuration file, a certificate, or a certificate and its private
+// key. The file is sent in the "file" field of a multipart form.
+func (s *Server) importFile(r *http.Request) (string, *apiError) {
+	if r.MultipartForm == nil || len(r.MultipartForm.File["file"]) == 0 {
+		return "", &apiError{status: http.StatusBadRequest, code: "400", msg: "no file was uploaded"}
+	}
+
+	header := r.MultipartForm.File["file"][0]
+
+	f, err := header.Open()
+	if err != nil {
+		return "", &apiError{code: "13", msg: err.Error()}
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", &apiError{code: "13", msg: err.Error()}
+	}
+
+	params := r.MultipartForm.Value
+	get := func(key string) string {
+		if values := params[key]; len(values) > 0 {
+			return values[0]
+		}
+
+		return ""
+	}
+
+	switch category := get("category"); category {
+	case "configuration":
+		root, err := parseFragment(string(data))
+		if err != nil || len(root.children) != 1 || root.children[0].name != "config" {
+			return "", &apiError{code: "18", msg: "the file is not a valid configuration"}
+		}
+
+		s.saved[header.Filename] = root
+
+		return fmt.Sprintf(`<response status="success"><msg>%s saved</msg></response>`, escape(header.Filename)), nil
+	case "certificate", "keypair":
+		name := get("certificate-name")
+		if name == "" {
+			return "", &apiError{status: http.StatusBadRequest, code: "400", msg: "certificate-name is missing"}
+		}
+
+		if category == "keypair" && get("passphrase") == "" {
+			return "", &apiError{code: "13", msg: "a passphrase is needed to import a private key"}
+		}
+
+		s.certs[name] = &certificate{data: data, keyPair: category == "keypair", passphrase: get("passphrase")}
+
+		return fmt.Sprintf(`<response status="success"><msg>Successfully imported %s into candidate configuration</msg></response>`, escape(name)), nil
+	}
+
+	return "", &apiError{code: "17", msg: "unsupported import category"}
+}
+
+// config answers a configuration request.
+func (s *Server) config(params url.Values) (string, *apiError) {
+	action := params.Get("action")
+
+	steps, err := parseXpath(params.Get("xpath"))
+	if err != nil {
+		return "", &apiError{code: "6", msg: "Bad Xpath"}
+	}
+
+	switch action {
+	case "get", "show":
+		tree := s.candidate
+		if action == "show" {
+			tree = s.running
+		}
+
+		nodes := find(tree, steps)
+		if len(nodes) == 0 {
+			return `<response status="success" code="7"><result/></response>`, nil
+		}
+
+		var result string
+		for _, n := range nodes {
+			result += n.String()
+		}
+
+		return fmt.Sprintf(`<response status="success" code="19"><result total-count="%d" count="%d">%s</result></response>`, len(nodes), len(nodes), result), nil
+	case "set", "edit":
+		element, err := parseFragment(params.Get("element"))
+		if err != nil {
+			return "", &apiError{code: "18", msg: "Malformed element"}
+		}
+
+		target, err := ensure(s.candidate, steps)
+		if err != nil {
+			return "", &apiError{code: "6", msg: "Bad Xpath"}
+		}
+
+		if action == "set" {
+			target.merge(element)
+		} else {
+			if len(element.children) != 1 || element.children[0].name != target.name {
+				return "", &apiError{code: "12", msg: fmt.Sprintf("edit: the element must be a single <%s>", target.name)}
+			}
+
+			replacement := element.children[0].clone()
+			parent, i := target.parent, target.index()
+			parent.children[i] = replacement
+			replacement.parent = parent
+		}
+	case "delete":
+		nodes := find(s.candidate, steps)
+		if len(nodes) == 0 {
+			return `<response status="success" code="7"><msg>Object doesn't exist</msg></response>`, nil
+		}
+
+		for _, n := range nodes {
+			n.remove()
+		}
+	case "rename":
+		n, apiErr := s.single(steps)
+		if apiErr != nil {
+			return "", apiErr
+		}
+
+		newname := params.Get("newname")
+		if _, ok := n.attr("name"); !ok || newname == "" {
+			return "", &apiError{code: "12", msg: "rename: the xpath must select a named entry, and newname must be given"}
+		}
+
+		if sibling := namedChild(n.parent, n.name, newname); sibling != nil && sibling != n {
+			return "", &apiError{code: "12", msg: fmt.Sprintf("%s already exists", newname)}
+		}
+
+		n.setAttr("name", newname)
+	case "move":
+		n, apiErr := s.single(steps)
+		if apiErr != nil {
+			return "", apiErr
+		}
+
+		if apiErr := move(n, params.Get("where"), params.Get("dst")); apiErr != nil {
+			return "", apiErr
+		}
+	case "clone":
+		fromSteps, err := parseXpath(params.Get("from"))
+		if err != nil {
+			return "", &apiError{code: "6", msg: "Bad Xpath"}
+		}
+
+		from, apiErr := s.single(fromSteps)
+		if apiErr != nil {
+			return "", apiErr
+		}
+
+		parent, err := ensure(s.candidate, steps)
+		if err != nil {
+			return "", &apiError{code: "6", msg: "Bad Xpath"}
+		}
+
+		newname := params.Get("newname")
+		if newname == "" || namedChild(parent, from.name, newname) != nil {
+			return "", &apiError{code: "12", msg: fmt.Sprintf("clone: %q is missing or already exists", newname)}
+		}
+
+		c := from.clone()
+		c.setAttr("name", newname)
+		parent.appendChild(c)
+	default:
+		return "", &apiError{code: "1", msg: fmt.Sprintf("unknown action %s", action)}
+	}
+
+	s.dirty = true
+
+	return `<response status="success" code="20"><msg>command succeeded</msg></response>`, nil
+}
+
+// single returns the one node in the candidate configuration that the xpath selects.
+func (s *Server) single(steps []step) (*node, *apiError) {
+	nodes := find(s.candidate, steps)
+
+	switch {
+	case len(nodes) == 0:
+		return nil, &apiError{code: "7", msg: "Object doesn't exist"}
+	case len(nodes) > 1:
+		return nil, &apiError{code: "8", msg: "Object is not unique"}
+	}
+
+	return nodes[0], nil
+}
+
+// namedChild returns the child of parent with the given tag and name attribute, if there is one.
+func namedChild(parent *node, tag, name string) *node {
+	for _, c := range parent.children {
+		if value, ok := c.attr("name"); ok && c.name == tag && value == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// move moves n among its siblings: to the top or bottom, or before or after the sibling named dst.
+func move(n *node, where, dst string) *apiError {
+	parent := n.parent
+
+	var target *node
+	if where == "before" || where == "after" {
+		target = namedChild(parent, n.name, dst)
+		if target == nil || target == n {
+			return &apiError{code: "14", msg: fmt.Sprintf("move: %q is not a valid destination", dst)}
+		}
+	} else if where != "top" && where != "bottom" {
+		return &apiError{code: "17", msg: fmt.Sprintf("move: unknown where %q", where)}
+	}
+
+	n.remove()
+
+	i := len(parent.children)
+	switch where {
+	case "top":
+		i = 0
+	case "before":
+		i = target.index()
+	case "after":
+		i = target.index() + 1
+	}
+
+	parent.children = append(parent.children[:i], append([]*node{n}, parent.children[i:]...)...)
+	n.parent = parent
+
+	return nil
+}
@@ -0,0 +1,285 @@
+package panostest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// call sends an API request to srv, with params in the query string of a GET request, or in the body of a POST
+// request, and returns the response body.
+func call(t *testing.T, srv *Server, method string, params url.Values, header http.Header) string {
+	t.Helper()
+
+	u := srv.URL + "/api/"
+	var body *strings.Reader
+	if method == http.MethodGet {
+		u += "?" + params.Encode()
+		body = strings.NewReader("")
+	} else {
+		body = strings.NewReader(params.Encode())
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+// apiCall sends an authenticated POST request to srv.
+func apiCall(t *testing.T, srv *Server, params url.Values) string {
+	t.Helper()
+
+	return call(t, srv, http.MethodPost, params, http.Header{"X-Pan-Key": {srv.APIKey()}})
+}
+
+func TestKeygen(t *testing.T) {
+	srv := NewServer(WithCredentials("jdoe", "s3cret"))
+	defer srv.Close()
+
+	credentials := url.Values{"type": {"keygen"}, "user": {"jdoe"}, "password": {"s3cret"}}
+
+	tests := []struct {
+		name   string
+		send   func() string
+		wantOK bool
+	}{
+		{
+			name:   "credentials in the POST body",
+			send:   func() string { return call(t, srv, http.MethodPost, credentials, nil) },
+			wantOK: true,
+		},
+		{
+			name: "credentials in the query string",
+			send: func() string { return call(t, srv, http.MethodGet, credentials, nil) },
+		},
+		{
+			name: "credentials in the URL of a POST request",
+			send: func() string {
+				req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/?"+credentials.Encode(), strings.NewReader(""))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+				resp, err := srv.Client().Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+
+				data, _ := ioutil.ReadAll(resp.Body)
+
+				return string(data)
+			},
+		},
+		{
+			name: "wrong password",
+			send: func() string {
+				return call(t, srv, http.MethodPost, url.Values{"type": {"keygen"}, "user": {"jdoe"}, "password": {"wrong"}}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.send()
+			ok := strings.Contains(body, "<key>"+escape(srv.APIKey())+"</key>")
+			if ok != tt.wantOK {
+				t.Fatalf("got %s", body)
+			}
+		})
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	info := url.Values{"type": {"op"}, "cmd": {"<show><system><info></info></system></show>"}}
+	withKey := url.Values{"type": {"op"}, "cmd": {"<show><system><info></info></system></show>"}, "key": {srv.APIKey()}}
+
+	tests := []struct {
+		name   string
+		method string
+		params url.Values
+		header http.Header
+		wantOK bool
+	}{
+		{name: "key header", method: http.MethodPost, params: info, header: http.Header{"X-Pan-Key": {srv.APIKey()}}, wantOK: true},
+		{name: "key parameter", method: http.MethodGet, params: withKey, wantOK: true},
+		{name: "no key", method: http.MethodPost, params: info},
+		{name: "wrong key", method: http.MethodPost, params: info, header: http.Header{"X-Pan-Key": {"wrong"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := call(t, srv, tt.method, tt.params, tt.header)
+			if ok := strings.Contains(body, "<serial>000000000001</serial>"); ok != tt.wantOK {
+				t.Fatalf("got %s", body)
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	const addresses = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address"
+
+	steps := []struct {
+		name   string
+		params url.Values
+		want   string
+	}{
+		{
+			name:   "set",
+			params: url.Values{"action": {"set"}, "xpath": {addresses}, "element": {"<entry name='a'><fqdn>a.example.com</fqdn></entry><entry name='b'><fqdn>b.example.com</fqdn></entry>"}},
+			want:   `<entry name="a"><fqdn>a.example.com</fqdn></entry><entry name="b"><fqdn>b.example.com</fqdn></entry>`,
+		},
+		{
+			name:   "edit",
+			params: url.Values{"action": {"edit"}, "xpath": {addresses + "/entry[@name='a']"}, "element": {"<entry name='a'><ip-netmask>10.1.1.1/32</ip-netmask></entry>"}},
+			want:   `<entry name="a"><ip-netmask>10.1.1.1/32</ip-netmask></entry><entry name="b">`,
+		},
+		{
+			name:   "move",
+			params: url.Values{"action": {"move"}, "xpath": {addresses + "/entry[@name='b']"}, "where": {"top"}},
+			want:   `<entry name="b"><fqdn>b.example.com</fqdn></entry><entry name="a">`,
+		},
+		{
+			name:   "clone",
+			params: url.Values{"action": {"clone"}, "xpath": {addresses}, "from": {addresses + "/entry[@name='a']"}, "newname": {"c"}},
+			want:   `<entry name="c"><ip-netmask>10.1.1.1/32</ip-netmask></entry>`,
+		},
+		{
+			name:   "rename",
+			params: url.Values{"action": {"rename"}, "xpath": {addresses + "/entry[@name='c']"}, "newname": {"d"}},
+			want:   `<entry name="d">`,
+		},
+		{
+			name:   "delete",
+			params: url.Values{"action": {"delete"}, "xpath": {addresses + "/entry[@name='d']"}},
+			want:   `<entry name="a"><ip-netmask>10.1.1.1/32</ip-netmask></entry></address>`,
+		},
+	}
+
+	for _, step := range steps {
+		step.params.Set("type", "config")
+		if body := apiCall(t, srv, step.params); !strings.Contains(body, `status="success"`) {
+			t.Fatalf("%s: got %s", step.name, body)
+		}
+
+		if !strings.Contains(srv.Candidate(), step.want) {
+			t.Fatalf("%s: the candidate configuration is missing %s: %s", step.name, step.want, srv.Candidate())
+		}
+	}
+
+	body := apiCall(t, srv, url.Values{"type": {"config"}, "action": {"get"}, "xpath": {addresses + "/entry[@name='b']/fqdn"}})
+	if !strings.Contains(body, "<fqdn>b.example.com</fqdn>") {
+		t.Fatalf("get: got %s", body)
+	}
+
+	if strings.Contains(srv.Running(), `name="a"`) {
+		t.Fatal("the running configuration changed before a commit")
+	}
+
+	body = apiCall(t, srv, url.Values{"type": {"commit"}, "cmd": {"<commit></commit>"}})
+	if !strings.Contains(body, "<job>1</job>") {
+		t.Fatalf("commit: got %s", body)
+	}
+
+	if srv.Running() != srv.Candidate() {
+		t.Fatal("the running configuration does not match the candidate after a commit")
+	}
+
+	body = apiCall(t, srv, url.Values{"type": {"op"}, "cmd": {"<show><jobs><id>1</id></jobs></show>"}})
+	if !strings.Contains(body, "<status>FIN</status>") || !strings.Contains(body, "<result>OK</result>") {
+		t.Fatalf("show jobs: got %s", body)
+	}
+}
+
+func TestManagedDevice(t *testing.T) {
+	firewall := NewServer(WithSystemInfo("serial", "007051000000001"), WithSystemInfo("hostname", "branch-fw"))
+	defer firewall.Close()
+
+	panorama := NewServer(WithPanorama(), WithManagedDevice(firewall))
+	defer panorama.Close()
+
+	body := apiCall(t, panorama, url.Values{"type": {"op"}, "cmd": {"<show><devices><all></all></devices></show>"}})
+	if !strings.Contains(body, "<serial>007051000000001</serial>") || !strings.Contains(body, "<hostname>branch-fw</hostname>") {
+		t.Fatalf("show devices all: got %s", body)
+	}
+
+	body = apiCall(t, panorama, url.Values{"type": {"op"}, "cmd": {"<show><system><info></info></system></show>"}, "target": {"007051000000001"}})
+	if !strings.Contains(body, "<hostname>branch-fw</hostname>") {
+		t.Fatalf("the request was not forwarded to the firewall: %s", body)
+	}
+
+	body = apiCall(t, panorama, url.Values{"type": {"op"}, "cmd": {"<show><system><info></info></system></show>"}, "target": {"missing"}})
+	if !strings.Contains(body, `code="17"`) {
+		t.Fatalf("got %s for a device that is not connected", body)
+	}
+}
+
+func TestLogQuery(t *testing.T) {
+	srv := NewServer(WithLogs("traffic", "<src>10.0.0.3</src>", "<src>10.0.0.2</src>", "<src>10.0.0.1</src>"))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		params url.Values
+		want   []string
+	}{
+		{name: "all", params: url.Values{}, want: []string{"10.0.0.3", "10.0.0.2", "10.0.0.1"}},
+		{name: "nlogs", params: url.Values{"nlogs": {"2"}}, want: []string{"10.0.0.3", "10.0.0.2"}},
+		{name: "skip", params: url.Values{"skip": {"2"}}, want: []string{"10.0.0.1"}},
+		{name: "forward", params: url.Values{"dir": {"forward"}, "nlogs": {"2"}}, want: []string{"10.0.0.1", "10.0.0.2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params.Set("type", "log")
+			tt.params.Set("log-type", "traffic")
+
+			body := apiCall(t, srv, tt.params)
+			start := strings.Index(body, "<job>")
+			end := strings.Index(body, "</job>")
+			if start < 0 || end < 0 {
+				t.Fatalf("got %s", body)
+			}
+
+			body = apiCall(t, srv, url.Values{"type": {"log"}, "action": {"get"}, "job-id": {body[start+5 : end]}})
+
+			var got []string
+			for _, part := range strings.Split(body, "<src>")[1:] {
+				got = append(got, part[:strings.Index(part, "</src>")])
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,484 @@
+package panostest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// node is an element in the in-memory configuration tree.
+type node struct {
+	name     string
+	attrs    []xml.Attr
+	text     string
+	children []*node
+	parent   *node
+}
+
+// parseFragment parses one or more sibling XML elements, such as the element parameter of a set request, and
+// returns them as the children of an unnamed node.
+func parseFragment(s string) (*node, error) {
+	root := &node{}
+	current := root
+
+	decoder := xml.NewDecoder(strings.NewReader(s))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			n := &node{name: t.Name.Local, parent: current}
+			for _, a := range t.Attr {
+				n.attrs = append(n.attrs, xml.Attr{Name: xml.Name{Local: a.Name.Local}, Value: a.Value})
+			}
+
+			current.children = append(current.children, n)
+			current = n
+		case xml.EndElement:
+			current = current.parent
+		case xml.CharData:
+			if current != root {
+				current.text += string(t)
+			}
+		}
+	}
+
+	if current != root {
+		return nil, errors.New("unexpected end of XML")
+	}
+
+	trimText(root)
+
+	return root, nil
+}
+
+// trimText removes the whitespace around the text of every element, and the text of elements that have children.
+func trimText(n *node) {
+	n.text = strings.TrimSpace(n.text)
+	if len(n.children) > 0 {
+		n.text = ""
+	}
+
+	for _, c := range n.children {
+		trimText(c)
+	}
+}
+
+// attr returns the value of the given attribute, and whether it is set.
+func (n *node) attr(name string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// setAttr sets the value of the given attribute, adding it if needed.
+func (n *node) setAttr(name, value string) {
+	for i, a := range n.attrs {
+		if a.Name.Local == name {
+			n.attrs[i].Value = value
+			return
+		}
+	}
+
+	n.attrs = append(n.attrs, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+// clone returns a deep copy of n, with no parent.
+func (n *node) clone() *node {
+	c := &node{name: n.name, text: n.text, attrs: append([]xml.Attr(nil), n.attrs...)}
+	for _, child := range n.children {
+		cc := child.clone()
+		cc.parent = c
+		c.children = append(c.children, cc)
+	}
+
+	return c
+}
+
+// appendChild adds c as the last child of n.
+func (n *node) appendChild(c *node) {
+	c.parent = n
+	n.children = append(n.children, c)
+}
+
+// remove takes n out of its parent.
+func (n *node) remove() {
+	if n.parent == nil {
+		return
+	}
+
+	siblings := n.parent.children
+	for i, c := range siblings {
+		if c == n {
+			n.parent.children = append(siblings[:i:i], siblings[i+1:]...)
+			break
+		}
+	}
+
+	n.parent = nil
+}
+
+// index returns the position of n among its siblings.
+func (n *node) index() int {
+	for i, c := range n.parent.children {
+		if c == n {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// same reports whether c is the same configuration object as n: an entry with the same name, a member with the
+// same value, or any other element with the same tag.
+func (n *node) same(c *node) bool {
+	if n.name != c.name {
+		return false
+	}
+
+	if name, ok := c.attr("name"); ok {
+		existing, _ := n.attr("name")
+		return existing == name
+	}
+
+	if c.name == "member" {
+		return n.text == c.text
+	}
+
+	return true
+}
+
+// merge adds the children of src to n, the way a set request does. Children that are already there are merged
+// in turn, and the text of leaf elements is replaced.
+func (n *node) merge(src *node) {
+	for _, c := range src.children {
+		var existing *node
+		for _, e := range n.children {
+			if e.same(c) {
+				existing = e
+				break
+			}
+		}
+
+		if existing == nil {
+			n.appendChild(c.clone())
+			continue
+		}
+
+		for _, a := range c.attrs {
+			existing.setAttr(a.Name.Local, a.Value)
+		}
+
+		if len(c.children) == 0 {
+			existing.children = nil
+			existing.text = c.text
+			continue
+		}
+
+		existing.text = ""
+		existing.merge(c)
+	}
+}
+
+// String returns n as XML.
+func (n *node) String() string {
+	var buf bytes.Buffer
+	n.write(&buf)
+
+	return buf.String()
+}
+
+// write writes n as XML to buf.
+func (n *node) write(buf *bytes.Buffer) {
+	buf.WriteString("<" + n.name)
+	for _, a := range n.attrs {
+		buf.WriteString(" " + a.Name.Local + `="`)
+		xml.EscapeText(buf, []byte(a.Value))
+		buf.WriteString(`"`)
+	}
+
+	if len(n.children) == 0 && n.text == "" {
+		buf.WriteString("/>")
+		return
+	}
+
+	buf.WriteString(">")
+	xml.EscapeText(buf, []byte(n.text))
+
+	for _, c := range n.children {
+		c.write(buf)
+	}
+
+	buf.WriteString("</" + n.name + ">")
+}
+
+// step is one location step of an xpath, such as entry[@name='web-server'].
+type step struct {
+	descendant bool
+	name       string
+	predicates []predicate
+}
+
+// predicate is a test on an attribute, such as [@name='web-server'], or on the text of an element, such as
+// [text()='web-server'].
+type predicate struct {
+	attr  string
+	value string
+}
+
+// matches reports whether n passes every test in the step.
+func (s step) matches(n *node) bool {
+	if s.name != "*" && s.name != n.name {
+		return false
+	}
+
+	for _, p := range s.predicates {
+		if p.attr == "" {
+			if n.text != p.value {
+				return false
+			}
+
+			continue
+		}
+
+		if value, ok := n.attr(p.attr); !ok || value != p.value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// errBadXpath is returned for xpaths that use more than the subset this package understands: absolute paths, with
+// / and //, element names or *, and predicates comparing @attribute or text() to a string literal or concat().
+var errBadXpath = errors.New("bad xpath")
+
+// parseXpath splits an absolute xpath into its steps.
+func parseXpath(xpath string) ([]step, error) {
+	var steps []step
+
+	s := strings.TrimSpace(xpath)
+	for len(s) > 0 {
+		if s[0] != '/' {
+			return nil, errBadXpath
+		}
+
+		var st step
+		s = s[1:]
+
+		if strings.HasPrefix(s, "/") {
+			st.descendant = true
+			s = s[1:]
+		}
+
+		end := strings.IndexAny(s, "/[")
+		if end < 0 {
+			end = len(s)
+		}
+
+		st.name = strings.TrimSpace(s[:end])
+		if st.name == "" {
+			return nil, errBadXpath
+		}
+
+		s = s[end:]
+
+		for strings.HasPrefix(s, "[") {
+			end, err := closingBracket(s)
+			if err != nil {
+				return nil, err
+			}
+
+			p, err := parsePredicate(s[1:end])
+			if err != nil {
+				return nil, err
+			}
+
+			st.predicates = append(st.predicates, p)
+			s = s[end+1:]
+		}
+
+		steps = append(steps, st)
+	}
+
+	if len(steps) == 0 {
+		return nil, errBadXpath
+	}
+
+	return steps, nil
+}
+
+// closingBracket returns the index of the ] that closes the [ at the start of s, skipping over quoted strings.
+func closingBracket(s string) (int, error) {
+	var quote byte
+
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case s[i] == ']':
+			return i, nil
+		}
+	}
+
+	return 0, errBadXpath
+}
+
+// parsePredicate parses the inside of a predicate, such as @name='web-server'.
+func parsePredicate(s string) (predicate, error) {
+	var p predicate
+
+	eq := strings.Index(s, "=")
+	if eq < 0 {
+		return p, errBadXpath
+	}
+
+	lhs := strings.TrimSpace(s[:eq])
+	switch {
+	case lhs == "text()":
+	case strings.HasPrefix(lhs, "@") && len(lhs) > 1:
+		p.attr = lhs[1:]
+	default:
+		return p, errBadXpath
+	}
+
+	value, err := parseLiteral(strings.TrimSpace(s[eq+1:]))
+	if err != nil {
+		return p, err
+	}
+
+	p.value = value
+
+	return p, nil
+}
+
+// parseLiteral parses an xpath string literal, either quoted with ' or ", or built with concat().
+func parseLiteral(s string) (string, error) {
+	if strings.HasPrefix(s, "concat(") && strings.HasSuffix(s, ")") {
+		var value string
+
+		args := strings.TrimSpace(s[len("concat(") : len(s)-1])
+		for len(args) > 0 {
+			if args[0] != '\'' && args[0] != '"' {
+				return "", errBadXpath
+			}
+
+			end := strings.IndexByte(args[1:], args[0])
+			if end < 0 {
+				return "", errBadXpath
+			}
+
+			value += args[1 : end+1]
+			args = strings.TrimSpace(args[end+2:])
+
+			if strings.HasPrefix(args, ",") {
+				args = strings.TrimSpace(args[1:])
+			} else if args != "" {
+				return "", errBadXpath
+			}
+		}
+
+		return value, nil
+	}
+
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] && !strings.ContainsRune(s[1:len(s)-1], rune(s[0])) {
+		return s[1 : len(s)-1], nil
+	}
+
+	return "", errBadXpath
+}
+
+// find returns every node under root that the xpath selects, in document order.
+func find(root *node, steps []step) []*node {
+	current := []*node{root}
+
+	for _, st := range steps {
+		var next []*node
+		seen := map[*node]bool{}
+
+		for _, n := range current {
+			var candidates []*node
+			if st.descendant {
+				candidates = descendants(n)
+			} else {
+				candidates = n.children
+			}
+
+			for _, c := range candidates {
+				if !seen[c] && st.matches(c) {
+					seen[c] = true
+					next = append(next, c)
+				}
+			}
+		}
+
+		current = next
+	}
+
+	return current
+}
+
+// descendants returns every node below n, in document order.
+func descendants(n *node) []*node {
+	var all []*node
+	for _, c := range n.children {
+		all = append(all, c)
+		all = append(all, descendants(c)...)
+	}
+
+	return all
+}
+
+// ensure returns the node at the given xpath, creating it and any missing parents along the way. The xpath
+// must not use // or *.
+func ensure(root *node, steps []step) (*node, error) {
+	current := root
+
+	for _, st := range steps {
+		if st.descendant || st.name == "*" {
+			return nil, errBadXpath
+		}
+
+		var next *node
+		for _, c := range current.children {
+			if st.matches(c) {
+				next = c
+				break
+			}
+		}
+
+		if next == nil {
+			next = &node{name: st.name}
+			for _, p := range st.predicates {
+				if p.attr == "" {
+					next.text = p.value
+				} else {
+					next.setAttr(p.attr, p.value)
+				}
+			}
+
+			current.appendChild(next)
+		}
+
+		current = next
+	}
+
+	return current, nil
+}
@@ -0,0 +1,82 @@
+package panostest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseXpath(t *testing.T) {
+	tests := []struct {
+		xpath   string
+		want    []step
+		wantErr bool
+	}{
+		{
+			xpath: "/config/shared",
+			want:  []step{{name: "config"}, {name: "shared"}},
+		},
+		{
+			xpath: "/config//entry[@name='web-server']",
+			want: []step{
+				{name: "config"},
+				{descendant: true, name: "entry", predicates: []predicate{{attr: "name", value: "web-server"}}},
+			},
+		},
+		{
+			xpath: `/config/*/member[text()="a]b"]`,
+			want: []step{
+				{name: "config"},
+				{name: "*"},
+				{name: "member", predicates: []predicate{{value: "a]b"}}},
+			},
+		},
+		{xpath: "", wantErr: true},
+		{xpath: "config/shared", wantErr: true},
+		{xpath: "/config/entry[@name='unclosed", wantErr: true},
+		{xpath: "/config//", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.xpath, func(t *testing.T) {
+			got, err := parseXpath(tt.xpath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	root, err := parseFragment(`<config><shared><address><entry name="a"/><entry name="b"/></address><tag><entry name="a"/></tag></shared></config>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		xpath string
+		want  int
+	}{
+		{xpath: "/config/shared/address/entry", want: 2},
+		{xpath: "/config/shared/address/entry[@name='a']", want: 1},
+		{xpath: "/config//entry[@name='a']", want: 2},
+		{xpath: "/config/shared/*/entry", want: 3},
+		{xpath: "/config/shared/address/entry[@name='c']", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.xpath, func(t *testing.T) {
+			steps, err := parseXpath(tt.xpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := len(find(root, steps)); got != tt.want {
+				t.Fatalf("got %d nodes, want %d", got, tt.want)
+			}
+		})
+	}
+}
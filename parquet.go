@@ -0,0 +1,425 @@
+package panos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+const (
+	// parquetMagic starts and ends every Parquet file.
+	parquetMagic = "PAR1"
+
+	// parquetRowGroupSize is how many logs a ParquetEncoder buffers before it writes them out as a row group.
+	parquetRowGroupSize = 10000
+
+	// parquetCreatedBy is written to the footer of each Parquet file, as the application that created it.
+	parquetCreatedBy = "github.com/scottdware/go-panos"
+
+	// Values of the enums in the Parquet format that are used here.
+	parquetInt64        = 2
+	parquetByteArray    = 6
+	parquetUTF8         = 0
+	parquetTimestamp    = 9
+	parquetRequired     = 0
+	parquetOptional     = 1
+	parquetPlain        = 0
+	parquetRLE          = 3
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+
+	// Types in the Thrift compact protocol, which the Parquet format uses for its metadata.
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// parquetEncoder writes logs as a Parquet file.
+type parquetEncoder struct {
+	w       io.Writer
+	typ     reflect.Type
+	columns []logColumn
+	data    []bytes.Buffer
+	rows    int
+	total   int64
+	offset  int64
+	groups  []parquetRowGroup
+	closed  bool
+	err     error
+
+	// levels holds the definition level of each buffered value of the optional columns: whether it is there.
+	levels [][]bool
+}
+
+// parquetRowGroup describes a row group that has been written, for the footer.
+type parquetRowGroup struct {
+	rows   int
+	size   int64
+	chunks []parquetColumnChunk
+}
+
+// parquetColumnChunk describes where the data of a column in a row group was written, for the footer.
+type parquetColumnChunk struct {
+	offset int64
+	size   int64
+}
+
+// NewParquetEncoder returns a LogEncoder that writes logs of the same type as record (such as &panos.TrafficLog{}) to
+// w as an Apache Parquet file, with the given columns. The schema follows the fields of the type: numbers and
+// durations (in seconds) are INT64 columns, times are INT64 TIMESTAMP_MILLIS columns, and strings and addresses are
+// UTF-8 strings. Addresses and times are optional columns, which are null when they were not in a log, as are the
+// columns of an OtherLog; the rest are required.
+//
+// Logs are buffered and written out in row groups of 10000. The data is not compressed. Close must be called to write
+// the rest of the logs and the footer; the file can't be read without it.
+func NewParquetEncoder(w io.Writer, record LogRecord, columns ...string) (LogEncoder, error) {
+	c, err := selectLogColumns(record, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parquetEncoder{
+		w:       w,
+		typ:     reflect.TypeOf(record),
+		columns: c,
+		data:    make([]bytes.Buffer, len(c)),
+		levels:  make([][]bool, len(c)),
+	}, nil
+}
+
+// Encode buffers the given logs, writing out a row group each time enough of them have been buffered.
+func (e *parquetEncoder) Encode(records ...LogRecord) error {
+	if e.closed {
+		return errors.New("the encoder is closed")
+	}
+
+	for _, r := range records {
+		if e.err != nil {
+			return e.err
+		}
+
+		if reflect.TypeOf(r) != e.typ {
+			return fmt.Errorf("the encoder writes %s logs, not %T", e.typ.Elem().Name(), r)
+		}
+
+		for j, c := range e.columns {
+			v := c.value(r)
+
+			if parquetOptionalColumn(c) {
+				e.levels[j] = append(e.levels[j], !c.null(v))
+				if c.null(v) {
+					continue
+				}
+			}
+
+			if parquetType(c) == parquetInt64 {
+				binary.Write(&e.data[j], binary.LittleEndian, c.number(v))
+			} else {
+				s := c.text(v)
+				binary.Write(&e.data[j], binary.LittleEndian, uint32(len(s)))
+				e.data[j].WriteString(s)
+			}
+		}
+
+		e.rows++
+		if e.rows == parquetRowGroupSize {
+			e.writeRowGroup()
+		}
+	}
+
+	return e.err
+}
+
+// Close writes the logs that are still buffered, and the footer. Calling it again does nothing.
+func (e *parquetEncoder) Close() error {
+	if e.closed {
+		return e.err
+	}
+	e.closed = true
+
+	if e.rows > 0 || e.offset == 0 {
+		e.writeRowGroup()
+	}
+
+	footer := e.footer()
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(footer)))
+
+	e.write(footer)
+	e.write(size)
+	e.write([]byte(parquetMagic))
+
+	return e.err
+}
+
+// write writes b to the file, unless an earlier write failed.
+func (e *parquetEncoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+
+	n, err := e.w.Write(b)
+	e.offset += int64(n)
+	e.err = err
+}
+
+// writeRowGroup writes the buffered logs as a row group, with a single data page for each column. The page of an
+// optional column starts with the definition levels, which say which of its values are there.
+func (e *parquetEncoder) writeRowGroup() {
+	if e.offset == 0 {
+		e.write([]byte(parquetMagic))
+	}
+
+	if e.rows == 0 {
+		return
+	}
+
+	group := parquetRowGroup{rows: e.rows}
+	for i, c := range e.columns {
+		var levels []byte
+		if parquetOptionalColumn(c) {
+			levels = parquetLevels(e.levels[i])
+			e.levels[i] = e.levels[i][:0]
+		}
+
+		size := len(levels) + e.data[i].Len()
+		header := parquetPageHeader(size, e.rows)
+		chunk := parquetColumnChunk{offset: e.offset, size: int64(len(header) + size)}
+
+		e.write(header)
+		e.write(levels)
+		e.write(e.data[i].Bytes())
+		e.data[i].Reset()
+
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.size
+	}
+
+	e.groups = append(e.groups, group)
+	e.total += int64(e.rows)
+	e.rows = 0
+}
+
+// parquetPageHeader returns the header of a PLAIN encoded, uncompressed data page with the given size and number of
+// values, including null ones. No column is repeated, so pages have no repetition levels.
+func parquetPageHeader(size, values int) []byte {
+	var t thriftWriter
+
+	t.begin(0)
+	t.i32(1, parquetDataPage)
+	t.i32(2, int32(size))
+	t.i32(3, int32(size))
+	t.begin(5)
+	t.i32(1, int32(values))
+	t.i32(2, parquetPlain)
+	t.i32(3, parquetRLE)
+	t.i32(4, parquetRLE)
+	t.end()
+	t.end()
+
+	return t.buf.Bytes()
+}
+
+// footer returns the file metadata, which holds the schema and where each row group was written.
+func (e *parquetEncoder) footer() []byte {
+	var t thriftWriter
+
+	t.begin(0)
+	t.i32(1, 1)
+
+	t.list(2, thriftStruct, len(e.columns)+1)
+	t.begin(0)
+	t.binary(4, "schema")
+	t.i32(5, int32(len(e.columns)))
+	t.end()
+
+	for _, c := range e.columns {
+		t.begin(0)
+		t.i32(1, parquetType(c))
+		if parquetOptionalColumn(c) {
+			t.i32(3, parquetOptional)
+		} else {
+			t.i32(3, parquetRequired)
+		}
+		t.binary(4, c.name)
+		switch {
+		case c.typ == timeType:
+			t.i32(6, parquetTimestamp)
+		case parquetType(c) == parquetByteArray:
+			t.i32(6, parquetUTF8)
+		}
+		t.end()
+	}
+
+	t.i64(3, e.total)
+
+	t.list(4, thriftStruct, len(e.groups))
+	for _, g := range e.groups {
+		t.begin(0)
+		t.list(1, thriftStruct, len(g.chunks))
+
+		for i, chunk := range g.chunks {
+			t.begin(0)
+			t.i64(2, chunk.offset)
+			t.begin(3)
+			t.i32(1, parquetType(e.columns[i]))
+			t.list(2, thriftI32, 2)
+			t.zigzag(parquetPlain)
+			t.zigzag(parquetRLE)
+			t.list(3, thriftBinary, 1)
+			t.str(e.columns[i].name)
+			t.i32(4, parquetUncompressed)
+			t.i64(5, int64(g.rows))
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.end()
+			t.end()
+		}
+
+		t.i64(2, g.size)
+		t.i64(3, int64(g.rows))
+		t.end()
+	}
+
+	t.binary(6, parquetCreatedBy)
+	t.end()
+
+	return t.buf.Bytes()
+}
+
+// parquetType returns the Parquet type of a log column.
+func parquetType(c logColumn) int32 {
+	if c.numeric() || c.typ == timeType {
+		return parquetInt64
+	}
+
+	return parquetByteArray
+}
+
+// parquetOptionalColumn reports whether a log column can be null.
+func parquetOptionalColumn(c logColumn) bool {
+	return c.index == nil || c.typ == ipType || c.typ == timeType
+}
+
+// parquetLevels returns the definition levels of a page, in the RLE encoding, after the length that Parquet puts in
+// front of them. Each run of levels that are the same is written as its length, then the level in a single byte.
+func parquetLevels(levels []bool) []byte {
+	var t thriftWriter
+
+	for i := 0; i < len(levels); {
+		n := 1
+		for i+n < len(levels) && levels[i+n] == levels[i] {
+			n++
+		}
+
+		// RLE runs have the lowest bit of their length cleared, to tell them from bit-packed runs.
+		t.varint(uint64(n) << 1)
+		if levels[i] {
+			t.buf.WriteByte(1)
+		} else {
+			t.buf.WriteByte(0)
+		}
+
+		i += n
+	}
+
+	b := make([]byte, 4, 4+t.buf.Len())
+	binary.LittleEndian.PutUint32(b, uint32(t.buf.Len()))
+
+	return append(b, t.buf.Bytes()...)
+}
+
+// thriftWriter encodes Thrift structs with the compact protocol.
+type thriftWriter struct {
+	buf bytes.Buffer
+
+	// last holds the ID of the last field written in each struct that is open.
+	last []int
+}
+
+// begin starts a struct, as the field with the given ID of the current struct, or on its own (such as an element of a
+// list) if the ID is 0.
+func (t *thriftWriter) begin(id int) {
+	if id > 0 {
+		t.field(id, thriftStruct)
+	}
+
+	t.last = append(t.last, 0)
+}
+
+// end ends the current struct.
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+// field writes the header of a field, using the short form when the ID is close enough to the previous one.
+func (t *thriftWriter) field(id int, typ byte) {
+	last := &t.last[len(t.last)-1]
+
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta<<4) | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.zigzag(int64(id))
+	}
+
+	*last = id
+}
+
+// i32 writes an i32 (or enum) field.
+func (t *thriftWriter) i32(id int, v int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+// i64 writes an i64 field.
+func (t *thriftWriter) i64(id int, v int64) {
+	t.field(id, thriftI64)
+	t.zigzag(v)
+}
+
+// binary writes a string field.
+func (t *thriftWriter) binary(id int, s string) {
+	t.field(id, thriftBinary)
+	t.str(s)
+}
+
+// list starts a list field with n elements of the given type. The elements follow, without field headers.
+func (t *thriftWriter) list(id int, typ byte, n int) {
+	t.field(id, thriftList)
+
+	if n < 15 {
+		t.buf.WriteByte(byte(n<<4) | typ)
+	} else {
+		t.buf.WriteByte(0xf0 | typ)
+		t.varint(uint64(n))
+	}
+}
+
+// str writes a string, without a field header.
+func (t *thriftWriter) str(s string) {
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+// zigzag writes a signed integer, without a field header.
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+// varint writes an unsigned integer in as few bytes as it takes.
+func (t *thriftWriter) varint(v uint64) {
+	for v >= 0x80 {
+		t.buf.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+
+	t.buf.WriteByte(byte(v))
+}
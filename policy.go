@@ -1,6 +1,7 @@
 package panos
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -132,11 +133,17 @@ type NATRule struct {
 	BiDirectional                   string   `xml:"source-translation>static-ip>bi-directional"`
 }
 
-// Policy returns information about the security policies for the given device-group. If no device-group is specified
-// then the local rules are returned when run against a firewall. If you have pre and/or post rules,
+// Policy returns information about the security policies at the given location. If no location is specified
+// then the local rules are returned when run against a firewall. On a Panorama device, specify the location
+// (e.g. DeviceGroupLocation("name") or SharedLocation()). If you have pre and/or post rules,
 // then both of them will be returned. They are separated under a Pre and Post field in the returned Policy struct.
 // Local rules are returned in the Local field.
-func (p *PaloAlto) Policy(devicegroup ...string) (*Policy, error) {
+func (p *PaloAlto) Policy(location ...Location) (*Policy, error) {
+	return p.PolicyContext(context.Background(), location...)
+}
+
+// PolicyContext is the same as Policy, but uses ctx for all of its API requests.
+func (p *PaloAlto) PolicyContext(ctx context.Context, location ...Location) (*Policy, error) {
 	var policy Policy
 	var prePolicy policyRules
 	var postPolicy policyRules
@@ -144,25 +151,20 @@ func (p *PaloAlto) Policy(devicegroup ...string) (*Policy, error) {
 
 	switch p.DeviceType {
 	case "panos":
-		xpath := "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/rulebase/security/rules"
-
-		if len(devicegroup[0]) > 0 {
-			return nil, errors.New("you do not need to specify a device-group when connected to a fireawll")
+		rulebase, err := p.rulebaseXpath("local", location...)
+		if err != nil {
+			return nil, err
 		}
 
-		_, localPolicyData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-		if errs != nil {
-			return nil, errs[0]
+		localPolicyData, err := p.getConfig(ctx, rulebase+"/security/rules")
+		if err != nil {
+			return nil, err
 		}
 
 		if err := xml.Unmarshal([]byte(localPolicyData), &localPolicy); err != nil {
 			return nil, err
 		}
 
-		if localPolicy.Status != "success" {
-			return nil, fmt.Errorf("error code %s: %s", localPolicy.Code, errorCodes[localPolicy.Code])
-		}
-
 		if len(localPolicy.Rules) == 0 {
 			return nil, errors.New("there are no rules created")
 		}
@@ -170,25 +172,28 @@ func (p *PaloAlto) Policy(devicegroup ...string) (*Policy, error) {
 		policy.IncludedRules = "local"
 		policy.Local = localPolicy.Rules
 	case "panorama":
-		if len(devicegroup) == 0 {
-			return nil, errors.New("you must specify a device-group when viewing policies on a Panorama device")
+		preRulebase, err := p.rulebaseXpath("pre", location...)
+		if err != nil {
+			return nil, err
 		}
 
-		preXpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/pre-rulebase/security/rules", devicegroup[0])
-		postXpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/post-rulebase/security/rules", devicegroup[0])
+		postRulebase, err := p.rulebaseXpath("post", location...)
+		if err != nil {
+			return nil, err
+		}
 
-		_, prePolicyData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", preXpath, p.Key)).End()
-		if errs != nil {
-			return nil, errs[0]
+		prePolicyData, err := p.getConfig(ctx, preRulebase+"/security/rules")
+		if err != nil {
+			return nil, err
 		}
 
 		if err := xml.Unmarshal([]byte(prePolicyData), &prePolicy); err != nil {
 			return nil, err
 		}
 
-		_, postPolicyData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", postXpath, p.Key)).End()
-		if errs != nil {
-			return nil, errs[0]
+		postPolicyData, err := p.getConfig(ctx, postRulebase+"/security/rules")
+		if err != nil {
+			return nil, err
 		}
 
 		if err := xml.Unmarshal([]byte(postPolicyData), &postPolicy); err != nil {
@@ -196,7 +201,7 @@ func (p *PaloAlto) Policy(devicegroup ...string) (*Policy, error) {
 		}
 
 		if len(prePolicy.Rules) == 0 && len(postPolicy.Rules) == 0 {
-			return nil, fmt.Errorf("there are no rules created, or the device-group %s does not exist", devicegroup[0])
+			return nil, fmt.Errorf("there are no rules created, or the %s does not exist", location[0])
 		}
 
 		if len(prePolicy.Rules) > 0 && len(postPolicy.Rules) > 0 {
@@ -211,14 +216,6 @@ func (p *PaloAlto) Policy(devicegroup ...string) (*Policy, error) {
 			policy.IncludedRules = "post"
 		}
 
-		if prePolicy.Status != "success" {
-			return nil, fmt.Errorf("error code %s: %s", prePolicy.Code, errorCodes[prePolicy.Code])
-		}
-
-		if postPolicy.Status != "success" {
-			return nil, fmt.Errorf("error code %s: %s", postPolicy.Code, errorCodes[postPolicy.Code])
-		}
-
 		policy.Pre = prePolicy.Rules
 		policy.Post = postPolicy.Rules
 	}
@@ -228,27 +225,28 @@ func (p *PaloAlto) Policy(devicegroup ...string) (*Policy, error) {
 
 // NATPolicy returns information about the NAT policy on a device.
 func (p *PaloAlto) NATPolicy() (*NATPolicy, error) {
+	return p.NATPolicyContext(context.Background())
+}
+
+// NATPolicyContext is the same as NATPolicy, but uses ctx for all of its API requests.
+func (p *PaloAlto) NATPolicyContext(ctx context.Context) (*NATPolicy, error) {
 	var policy NATPolicy
 
 	if p.DeviceType != "panos" {
 		return nil, errors.New("you can only view NAT policies on a firewall")
 	}
 
-	xpath := "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/rulebase/nat/rules"
+	xpath := p.vsysXpath() + "/rulebase/nat/rules"
 
-	_, natPolicyData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	natPolicyData, err := p.getConfig(ctx, xpath)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(natPolicyData), &policy); err != nil {
 		return nil, err
 	}
 
-	if policy.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", policy.Code, errorCodes[policy.Code])
-	}
-
 	if len(policy.Rules) == 0 {
 		return nil, errors.New("there are no rules created")
 	}
@@ -258,26 +256,27 @@ func (p *PaloAlto) NATPolicy() (*NATPolicy, error) {
 
 // DeviceGroupNATPolicy returns information about the NAT policy on a given device group.
 func (p *PaloAlto) DeviceGroupNATPolicy(group string) (*NATPolicy, error) {
+	return p.DeviceGroupNATPolicyContext(context.Background(), group)
+}
+
+// DeviceGroupNATPolicyContext is the same as DeviceGroupNATPolicy, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeviceGroupNATPolicyContext(ctx context.Context, group string) (*NATPolicy, error) {
 	var policy NATPolicy
 
 	if p.DeviceType != "panorama" {
 		return nil, errors.New("you can only view device group NAT policies on a panorama")
 	}
 
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/*/nat/rules", group)
-	_, natPolicyData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name=%s]/*/nat/rules", xpathQuote(group))
+	natPolicyData, err := p.getConfig(ctx, xpath)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(natPolicyData), &policy); err != nil {
 		return nil, err
 	}
 
-	if policy.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", policy.Code, errorCodes[policy.Code])
-	}
-
 	if len(policy.Rules) == 0 {
 		return nil, errors.New("there are no rules created")
 	}
@@ -286,8 +285,8 @@ func (p *PaloAlto) DeviceGroupNATPolicy(group string) (*NATPolicy, error) {
 }
 
 // CreateRule will create a new rule on the device. If you are connected to a Panorama device, then
-// you must specify the device-group as the last parameter. You do not need this when connected to
-// a firewall.
+// you must specify its location (e.g. DeviceGroupLocation("name")) as the last parameter. You do not need this when
+// connected to a firewall.
 //
 // Ruletype must be one of:
 //
@@ -295,10 +294,13 @@ func (p *PaloAlto) DeviceGroupNATPolicy(group string) (*NATPolicy, error) {
 //
 // You will need to create the rules contents within the RuleContent struct. Please see the documentation
 // for the struct on how to structure it.
-func (p *PaloAlto) CreateRule(name, ruletype string, content *RuleContent, devicegroup ...string) error {
+func (p *PaloAlto) CreateRule(name, ruletype string, content *RuleContent, location ...Location) error {
+	return p.CreateRuleContext(context.Background(), name, ruletype, content, location...)
+}
+
+// CreateRuleContext is the same as CreateRule, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateRuleContext(ctx context.Context, name, ruletype string, content *RuleContent, location ...Location) error {
 	var xmlBody string
-	var reqError requestError
-	var xpath string
 
 	urlp := len(content.URLFilteringProfile)
 	fp := len(content.FileBlockingProfile)
@@ -307,153 +309,170 @@ func (p *PaloAlto) CreateRule(name, ruletype string, content *RuleContent, devic
 	asp := len(content.AntiSpywareProfile)
 	vp := len(content.VulnerabilityProfile)
 
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/rulebase/security/rules/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && len(devicegroup) == 0 {
-		return errors.New("you must specify a device-group when creating a rule on a Panorama device")
+	rulebase, err := p.rulebaseXpath(ruletype, location...)
+	if err != nil {
+		return err
 	}
 
-	if len(devicegroup) > 0 {
-		switch ruletype {
-		case "pre":
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/pre-rulebase/security/rules/entry[@name='%s']", devicegroup[0], name)
-		case "post":
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/post-rulebase/security/rules/entry[@name='%s']", devicegroup[0], name)
-		}
-	}
+	xpath := fmt.Sprintf("%s/security/rules/entry[@name=%s]", rulebase, xpathQuote(name))
 
 	if len(content.Tag) > 0 {
 		xmlBody += fmt.Sprintf("<tag>")
 		for _, tag := range content.Tag {
-			xmlBody += fmt.Sprintf("<member>%s</member>", tag)
+			xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(tag))
 		}
 		xmlBody += fmt.Sprintf("</tag>")
 	}
 
 	xmlBody += fmt.Sprintf("<to>")
 	for _, to := range content.To {
-		xmlBody += fmt.Sprintf("<member>%s</member>", to)
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(to))
 	}
 	xmlBody += fmt.Sprintf("</to>")
 
 	xmlBody += fmt.Sprintf("<from>")
 	for _, from := range content.From {
-		xmlBody += fmt.Sprintf("<member>%s</member>", from)
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(from))
 	}
 	xmlBody += fmt.Sprintf("</from>")
 
 	xmlBody += fmt.Sprintf("<source>")
 	for _, source := range content.Source {
-		xmlBody += fmt.Sprintf("<member>%s</member>", source)
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(source))
 	}
 	xmlBody += fmt.Sprintf("</source>")
 
 	xmlBody += fmt.Sprintf("<destination>")
 	for _, destination := range content.Destination {
-		xmlBody += fmt.Sprintf("<member>%s</member>", destination)
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(destination))
 	}
 	xmlBody += fmt.Sprintf("</destination>")
 
 	xmlBody += fmt.Sprintf("<source-user>")
 	for _, srcuser := range content.SourceUser {
-		xmlBody += fmt.Sprintf("<member>%s</member>", srcuser)
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(srcuser))
 	}
 	xmlBody += fmt.Sprintf("</source-user>")
 
 	xmlBody += fmt.Sprintf("<category>")
 	for _, category := range content.Category {
-		xmlBody += fmt.Sprintf("<member>%s</member>", category)
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(category))
 	}
 	xmlBody += fmt.Sprintf("</category>")
 
 	xmlBody += fmt.Sprintf("<application>")
 	for _, app := range content.Application {
-		xmlBody += fmt.Sprintf("<member>%s</member>", app)
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(app))
 	}
 	xmlBody += fmt.Sprintf("</application>")
 
 	xmlBody += fmt.Sprintf("<service>")
 	for _, service := range content.Service {
-		xmlBody += fmt.Sprintf("<member>%s</member>", service)
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(service))
 	}
 	xmlBody += fmt.Sprintf("</service>")
 
 	xmlBody += fmt.Sprintf("<hip-profiles>")
 	for _, hip := range content.HIPProfiles {
-		xmlBody += fmt.Sprintf("<member>%s</member>", hip)
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(hip))
 	}
 	xmlBody += fmt.Sprintf("</hip-profiles>")
 
-	xmlBody += fmt.Sprintf("<action>%s</action>", content.Action)
+	xmlBody += fmt.Sprintf("<action>%s</action>", xmlEscape(content.Action))
 
 	if len(content.LogStart) > 0 {
-		xmlBody += fmt.Sprintf("<log-start>%s</log-start>", content.LogStart)
+		xmlBody += fmt.Sprintf("<log-start>%s</log-start>", xmlEscape(content.LogStart))
 	}
 
 	if len(content.LogEnd) > 0 {
-		xmlBody += fmt.Sprintf("<log-end>%s</log-end>", content.LogEnd)
+		xmlBody += fmt.Sprintf("<log-end>%s</log-end>", xmlEscape(content.LogEnd))
 	}
 
 	if len(content.LogSetting) > 0 {
-		xmlBody += fmt.Sprintf("<log-setting>%s</log-setting>", content.LogSetting)
+		xmlBody += fmt.Sprintf("<log-setting>%s</log-setting>", xmlEscape(content.LogSetting))
 	}
 
 	if len(content.SecurityProfileGroup) > 0 {
-		xmlBody += fmt.Sprintf("<profile-setting><group><member>%s</member></group></profile-setting>", content.SecurityProfileGroup)
+		xmlBody += fmt.Sprintf("<profile-setting><group><member>%s</member></group></profile-setting>", xmlEscape(content.SecurityProfileGroup))
 	}
 
 	if len(content.Description) > 0 {
-		xmlBody += fmt.Sprintf("<description>%s</description>", content.Description)
+		xmlBody += fmt.Sprintf("<description>%s</description>", xmlEscape(content.Description))
 	}
 
 	if len(content.Disabled) > 0 {
-		xmlBody += fmt.Sprintf("<disabled>%s</disabled>", content.Disabled)
+		xmlBody += fmt.Sprintf("<disabled>%s</disabled>", xmlEscape(content.Disabled))
 	}
 
 	if urlp > 0 || fp > 0 || wfp > 0 || avp > 0 || asp > 0 || vp > 0 {
 		xmlBody += "<profile-setting><profiles>"
 
 		if urlp > 0 {
-			xmlBody += fmt.Sprintf("<url-filtering><member>%s</member></url-filtering>", content.URLFilteringProfile)
+			xmlBody += fmt.Sprintf("<url-filtering><member>%s</member></url-filtering>", xmlEscape(content.URLFilteringProfile))
 		}
 
 		if fp > 0 {
-			xmlBody += fmt.Sprintf("<file-blocking><member>%s</member></file-blocking>", content.FileBlockingProfile)
+			xmlBody += fmt.Sprintf("<file-blocking><member>%s</member></file-blocking>", xmlEscape(content.FileBlockingProfile))
 		}
 
 		if wfp > 0 {
-			xmlBody += fmt.Sprintf("<wildfire-analysis><member>%s</member></wildfire-analysis>", content.WildfireProfile)
+			xmlBody += fmt.Sprintf("<wildfire-analysis><member>%s</member></wildfire-analysis>", xmlEscape(content.WildfireProfile))
 		}
 
 		if avp > 0 {
-			xmlBody += fmt.Sprintf("<virus><member>%s</member></virus>", content.AntiVirusProfile)
+			xmlBody += fmt.Sprintf("<virus><member>%s</member></virus>", xmlEscape(content.AntiVirusProfile))
 		}
 
 		if asp > 0 {
-			xmlBody += fmt.Sprintf("<spyware><member>%s</member></spyware>", content.AntiSpywareProfile)
+			xmlBody += fmt.Sprintf("<spyware><member>%s</member></spyware>", xmlEscape(content.AntiSpywareProfile))
 		}
 
 		if vp > 0 {
-			xmlBody += fmt.Sprintf("<vulnerability><member>%s</member></vulnerability>", content.VulnerabilityProfile)
+			xmlBody += fmt.Sprintf("<vulnerability><member>%s</member></vulnerability>", xmlEscape(content.VulnerabilityProfile))
 		}
 
 		xmlBody += "</profiles></profile-setting>"
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
+		return err
 	}
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
-		return err
+	return nil
+}
+
+// ruleXpath returns the xpath of the security rule with the given name at the given location. On a Panorama device,
+// the pre-rulebase is searched first, followed by the post-rulebase.
+func (p *PaloAlto) ruleXpath(ctx context.Context, name string, location ...Location) (string, error) {
+	if p.DeviceType != "panorama" {
+		rulebase, err := p.rulebaseXpath("local", location...)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s/security/rules/entry[@name=%s]", rulebase, xpathQuote(name)), nil
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+	policy, err := p.PolicyContext(ctx, location...)
+	if err != nil {
+		return "", err
 	}
 
-	return nil
+	ruletypes := map[string][]Rule{"pre": policy.Pre, "post": policy.Post}
+	for _, ruletype := range []string{"pre", "post"} {
+		for _, r := range ruletypes[ruletype] {
+			if r.Name != name {
+				continue
+			}
+
+			rulebase, err := p.rulebaseXpath(ruletype, location...)
+			if err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("%s/security/rules/entry[@name=%s]", rulebase, xpathQuote(name)), nil
+		}
+	}
+
+	return "", fmt.Errorf("rule %s does not exist", name)
 }
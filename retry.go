@@ -0,0 +1,187 @@
+package panos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMinBackoff is the wait before the first retry, if the RetryPolicy does not set one.
+	defaultMinBackoff = 500 * time.Millisecond
+
+	// defaultMaxBackoff is the longest wait between retries, if the RetryPolicy does not set one.
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// transientCodes are the PAN-OS error codes for internal errors on the device, which are worth sending a request
+// again for.
+var transientCodes = []string{"2", "3", "4", "5", "9", "11", "21"}
+
+// RetryPolicy controls how a session retries failed API calls. Only requests that can't change anything on the
+// device, such as getting the configuration or running a show command, are ever retried; writes such as set, edit,
+// delete and commit are sent once. A read is retried when it failed with one of the PAN-OS error codes for a
+// transient internal error (2-5, 9, 11 and 21), because the connection dropped, or because the device's web server
+// returned an error status.
+//
+// The wait between attempts doubles each time, from MinBackoff up to MaxBackoff, and a random amount of jitter is
+// applied so many clients don't all retry at the same moment.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times a request is sent, including the first. A value of 0 or 1 turns
+	// off retries.
+	MaxAttempts int
+
+	// MinBackoff is the wait before the first retry. The default is 500ms.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the longest wait between any two attempts. The default is 30s.
+	MaxBackoff time.Duration
+}
+
+// backoff returns how long to wait before the given retry, where the first retry is 1. It uses "full jitter": a
+// random wait between zero and the exponential backoff for that retry.
+func (r RetryPolicy) backoff(retry int) time.Duration {
+	min, max := r.MinBackoff, r.MaxBackoff
+	if min <= 0 {
+		min = defaultMinBackoff
+	}
+
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+
+	wait := min
+	for i := 1; i < retry && wait < max; i++ {
+		wait *= 2
+	}
+
+	if wait > max {
+		wait = max
+	}
+
+	return time.Duration(rand.Int63n(int64(wait)) + 1)
+}
+
+// statusError is returned when the device's web server responds with something other than 200 OK, and the body is
+// not an API error.
+type statusError struct {
+	host       string
+	status     string
+	statusCode int
+}
+
+// Error returns the HTTP status that the device responded with.
+func (e *statusError) Error() string {
+	return "unexpected response from " + e.host + " - " + e.status
+}
+
+// retryable reports whether a request with the given parameters, that failed with err, should be sent again.
+func retryable(params url.Values, err error) bool {
+	if !readOnly(params) {
+		return false
+	}
+
+	if hasErrorCode(err, transientCodes...) {
+		return true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return false
+	}
+
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.statusCode < http.StatusInternalServerError && statusErr.statusCode != http.StatusTooManyRequests {
+		return false
+	}
+
+	return true
+}
+
+// readOnly reports whether a request with the given parameters only reads from the device, and so can be sent
+// more than once without changing the outcome.
+func readOnly(params url.Values) bool {
+	switch params.Get("type") {
+	case "config":
+		action := params.Get("action")
+		return action == "get" || action == "show"
+	case "op":
+		return strings.HasPrefix(strings.TrimSpace(params.Get("cmd")), "<show>")
+	case "log":
+		return params.Get("action") == "get"
+	case "export", "report":
+		return true
+	}
+
+	return false
+}
+
+// sleep waits for d, or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimiter is a token bucket. It holds up to burst tokens, and gains rate tokens every second. Each request takes
+// one token, and waits for one if the bucket is empty. It is shared by every copy of a session.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a full bucket that allows rate requests per second, with bursts of up to burst requests.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token from the bucket, waiting until one is available or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		l.last = now
+
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+
+			return nil
+		}
+
+		need := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		if err := sleep(ctx, need); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package panos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// roundTripFunc lets a function be used as an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// failingSession returns a session whose every request fails with the given PAN-OS error code, and a count of the
+// requests that were sent.
+func failingSession(code string, policy RetryPolicy) (*PaloAlto, *int32) {
+	var attempts int32
+
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&attempts, 1)
+
+		body := fmt.Sprintf(`<response status="error" code="%s"><msg>failed</msg></response>`, code)
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body)), Request: r}, nil
+	})
+
+	p := &PaloAlto{
+		Host:   "fw",
+		URI:    "https://fw/api/?",
+		client: &http.Client{Transport: transport},
+		retry:  policy,
+	}
+
+	return p, &attempts
+}
+
+func TestRetryable(t *testing.T) {
+	reads := map[string]url.Values{
+		"config get":  {"type": {"config"}, "action": {"get"}, "xpath": {"/config"}},
+		"config show": {"type": {"config"}, "action": {"show"}, "xpath": {"/config"}},
+		"show":        {"type": {"op"}, "cmd": {"<show><system><info/></system></show>"}},
+		"log get":     {"type": {"log"}, "action": {"get"}, "job-id": {"1"}},
+		"export":      {"type": {"export"}, "category": {"configuration"}},
+	}
+
+	writes := map[string]url.Values{
+		"config set":    {"type": {"config"}, "action": {"set"}, "xpath": {"/config"}},
+		"config edit":   {"type": {"config"}, "action": {"edit"}, "xpath": {"/config"}},
+		"config delete": {"type": {"config"}, "action": {"delete"}, "xpath": {"/config"}},
+		"commit":        {"type": {"commit"}, "cmd": {"<commit></commit>"}},
+		"request":       {"type": {"op"}, "cmd": {"<request><restart><system/></restart></request>"}},
+		"log query":     {"type": {"log"}, "log-type": {"traffic"}},
+		"import":        {"type": {"import"}, "category": {"configuration"}},
+	}
+
+	errs := map[string]error{
+		"connection":     errors.New("connection reset by peer"),
+		"server error":   &statusError{host: "fw", status: "503 Service Unavailable", statusCode: 503},
+		"rate limited":   &statusError{host: "fw", status: "429 Too Many Requests", statusCode: 429},
+		"wrapped code 2": fmt.Errorf("unable to get the configuration - %w", &APIError{Code: "2"}),
+	}
+
+	for _, code := range transientCodes {
+		errs["code "+code] = &APIError{Code: code}
+	}
+
+	permanent := map[string]error{
+		"object not present": &APIError{Code: "7"},
+		"invalid command":    &APIError{Code: "1"},
+		"unauthorized":       &APIError{Code: "403"},
+		"bad request":        &statusError{host: "fw", status: "400 Bad Request", statusCode: 400},
+	}
+
+	for name, params := range reads {
+		for errName, err := range errs {
+			if !retryable(params, err) {
+				t.Errorf("%s: %s was not retried", name, errName)
+			}
+		}
+
+		for errName, err := range permanent {
+			if retryable(params, err) {
+				t.Errorf("%s: %s was retried", name, errName)
+			}
+		}
+	}
+
+	for name, params := range writes {
+		for errName, err := range errs {
+			if retryable(params, err) {
+				t.Errorf("%s: %s was retried", name, errName)
+			}
+		}
+	}
+}
+
+func TestRetryAttempts(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+	get := url.Values{"type": {"config"}, "action": {"get"}, "xpath": {"/config"}}
+	set := url.Values{"type": {"config"}, "action": {"set"}, "xpath": {"/config"}, "element": {"<x/>"}}
+
+	tests := []struct {
+		name   string
+		code   string
+		policy RetryPolicy
+		params url.Values
+		want   int32
+	}{
+		{name: "transient read", code: "2", policy: policy, params: get, want: 3},
+		{name: "transient write", code: "2", policy: policy, params: set, want: 1},
+		{name: "permanent read", code: "7", policy: policy, params: get, want: 1},
+		{name: "no policy", code: "2", params: get, want: 1},
+		{name: "one attempt", code: "2", policy: RetryPolicy{MaxAttempts: 1}, params: get, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, attempts := failingSession(tt.code, tt.policy)
+
+			_, err := p.request(context.Background(), tt.params)
+			if !hasErrorCode(err, tt.code) {
+				t.Fatalf("got %v, want error code %s", err, tt.code)
+			}
+
+			if *attempts != tt.want {
+				t.Fatalf("got %d attempts, want %d", *attempts, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	p, attempts := failingSession("2", RetryPolicy{MaxAttempts: 5, MinBackoff: time.Hour, MaxBackoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := p.request(ctx, url.Values{"type": {"config"}, "action": {"get"}, "xpath": {"/config"}}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("the retry loop took %s to stop", elapsed)
+	}
+
+	if *attempts != 1 {
+		t.Fatalf("got %d attempts, want 1", *attempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{retry: 1, max: 100 * time.Millisecond},
+		{retry: 2, max: 200 * time.Millisecond},
+		{retry: 3, max: 400 * time.Millisecond},
+		{retry: 4, max: 800 * time.Millisecond},
+		{retry: 5, max: time.Second},
+		{retry: 50, max: time.Second},
+	}
+
+	for _, tt := range tests {
+		var longest time.Duration
+		for i := 0; i < 1000; i++ {
+			wait := policy.backoff(tt.retry)
+			if wait <= 0 || wait > tt.max {
+				t.Fatalf("retry %d: got a wait of %s, want one in (0, %s]", tt.retry, wait, tt.max)
+			}
+
+			if wait > longest {
+				longest = wait
+			}
+		}
+
+		// With full jitter, 1000 samples should come close to the upper bound.
+		if longest < tt.max/2 {
+			t.Errorf("retry %d: the longest wait was %s, want close to %s", tt.retry, longest, tt.max)
+		}
+	}
+
+	if wait := (RetryPolicy{}).backoff(1); wait > defaultMinBackoff {
+		t.Errorf("got a wait of %s with the default policy, want at most %s", wait, defaultMinBackoff)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	const rate, burst = 50, 5
+
+	limiter := newRateLimiter(rate, burst)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < burst; i++ {
+		if err := limiter.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > 15*time.Millisecond {
+		t.Fatalf("a burst of %d requests took %s, want no wait", burst, elapsed)
+	}
+
+	// After the burst, the next 10 requests are paced at 50 a second, so they take about 200ms.
+	start = time.Now()
+	for i := 0; i < 10; i++ {
+		if err := limiter.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond || elapsed > 400*time.Millisecond {
+		t.Fatalf("10 requests took %s, want about 200ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	slow := newRateLimiter(0.001, 1)
+	if err := slow.wait(ctx); err != nil {
+		t.Fatalf("the first request waited: %v", err)
+	}
+
+	if err := slow.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
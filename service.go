@@ -1,6 +1,7 @@
 package panos
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -40,88 +41,76 @@ type ServiceGroup struct {
 	Tag         []string `xml:"tag>member,omitempty"`
 }
 
-// Services returns information about all of the service objects. You can (optionally) specify a device-group
-// when ran against a Panorama device. If no device-group is specified, then all objects are returned, including
+// Services returns information about all of the service objects. You can (optionally) specify a location, such as
+// a device-group when ran against a Panorama device. If no location is specified, then all objects are returned, including
 // shared objects if ran against a Panorama device.
-func (p *PaloAlto) Services(devicegroup ...string) (*ServiceObjects, error) {
+func (p *PaloAlto) Services(location ...Location) (*ServiceObjects, error) {
+	return p.ServicesContext(context.Background(), location...)
+}
+
+// ServicesContext is the same as Services, but uses ctx for all of its API requests.
+func (p *PaloAlto) ServicesContext(ctx context.Context, location ...Location) (*ServiceObjects, error) {
 	var svcs ServiceObjects
 	xpath := "/config//service"
 
-	if p.DeviceType == "panos" {
-		if p.Panorama == true {
-			xpath = "/config//service"
-		}
-
-		if p.Panorama == false {
-			xpath = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service"
-		}
-
-		if len(devicegroup) > 0 && len(devicegroup[0]) > 0 {
-			return nil, errors.New("you must be connected to a Panorama device when specifying a device-group")
-		}
+	if p.DeviceType == "panos" && p.Panorama == false {
+		xpath = p.vsysXpath() + "/service"
 	}
 
-	if p.DeviceType == "panorama" {
-		if len(devicegroup) > 0 && len(devicegroup[0]) > 0 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service", devicegroup[0])
+	if len(location) > 0 {
+		base, err := p.locationXpath(location...)
+		if err != nil {
+			return nil, err
 		}
-	}
 
-	_, svcData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+		xpath = base + "/service"
 	}
 
-	if err := xml.Unmarshal([]byte(svcData), &svcs); err != nil {
+	svcData, err := p.getConfig(ctx, xpath)
+	if err != nil {
 		return nil, err
 	}
 
-	if svcs.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", svcs.Code, errorCodes[svcs.Code])
+	if err := xml.Unmarshal([]byte(svcData), &svcs); err != nil {
+		return nil, err
 	}
 
 	return &svcs, nil
 }
 
-// ServiceGroups returns information about all of the service groups. You can (optionally) specify a device-group
-// when ran against a Panorama device. If no device-group is specified, then all service groups are returned, including
+// ServiceGroups returns information about all of the service groups. You can (optionally) specify a location, such as
+// a device-group when ran against a Panorama device. If no location is specified, then all service groups are returned, including
 // shared objects if ran against a Panorama device.
-func (p *PaloAlto) ServiceGroups(devicegroup ...string) (*ServiceGroups, error) {
+func (p *PaloAlto) ServiceGroups(location ...Location) (*ServiceGroups, error) {
+	return p.ServiceGroupsContext(context.Background(), location...)
+}
+
+// ServiceGroupsContext is the same as ServiceGroups, but uses ctx for all of its API requests.
+func (p *PaloAlto) ServiceGroupsContext(ctx context.Context, location ...Location) (*ServiceGroups, error) {
 	var groups ServiceGroups
 	// xpath := "/config/devices/entry//service-group"
 	xpath := "/config//service-group"
 
-	if p.DeviceType == "panos" {
-		if p.Panorama == true {
-			xpath = "/config//service-group"
-		}
-
-		if p.Panorama == false {
-			xpath = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group"
-		}
-
-		if len(devicegroup) > 0 && len(devicegroup[0]) > 0 {
-			return nil, errors.New("you must be connected to a Panorama device when specifying a device-group")
-		}
+	if p.DeviceType == "panos" && p.Panorama == false {
+		xpath = p.vsysXpath() + "/service-group"
 	}
 
-	if p.DeviceType == "panorama" {
-		if len(devicegroup) > 0 && len(devicegroup[0]) > 0 {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group", devicegroup[0])
+	if len(location) > 0 {
+		base, err := p.locationXpath(location...)
+		if err != nil {
+			return nil, err
 		}
-	}
 
-	_, groupData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+		xpath = base + "/service-group"
 	}
 
-	if err := xml.Unmarshal([]byte(groupData), &groups); err != nil {
+	groupData, err := p.getConfig(ctx, xpath)
+	if err != nil {
 		return nil, err
 	}
 
-	if groups.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", groups.Code, errorCodes[groups.Code])
+	if err := xml.Unmarshal([]byte(groupData), &groups); err != nil {
+		return nil, err
 	}
 
 	return &groups, nil
@@ -129,68 +118,50 @@ func (p *PaloAlto) ServiceGroups(devicegroup ...string) (*ServiceGroups, error)
 
 // CreateService adds a new service object to the device. Port can be a single port number, range (1-65535),
 // or comma separated (80, 8080, 443).
-// If creating a service on a Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) CreateService(name, protocol, port, description string, devicegroup ...string) error {
+// If creating a service on a Panorama device, specify its location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) CreateService(name, protocol, port, description string, location ...Location) error {
+	return p.CreateServiceContext(context.Background(), name, protocol, port, description, location...)
+}
+
+// CreateServiceContext is the same as CreateService, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateServiceContext(ctx context.Context, name, protocol, port, description string, location ...Location) error {
 	var xmlBody string
-	var xpath string
-	var reqError requestError
 
 	switch protocol {
 	case "tcp":
-		xmlBody = fmt.Sprintf("<protocol><tcp><port>%s</port></tcp></protocol>", strings.TrimSpace(port))
+		xmlBody = fmt.Sprintf("<protocol><tcp><port>%s</port></tcp></protocol>", xmlEscape(strings.TrimSpace(port)))
 	case "udp":
-		xmlBody = fmt.Sprintf("<protocol><udp><port>%s</port></udp></protocol>", strings.TrimSpace(port))
+		xmlBody = fmt.Sprintf("<protocol><udp><port>%s</port></udp></protocol>", xmlEscape(strings.TrimSpace(port)))
 	}
 
 	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
-	}
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service/entry[@name='%s']", name)
+		xmlBody += fmt.Sprintf("<description>%s</description>", xmlEscape(description))
 	}
 
-	if p.DeviceType == "panorama" {
-		if p.Shared == true {
-			xpath = fmt.Sprintf("/config/shared/service/entry[@name='%s']", name)
-		}
-
-		if len(devicegroup) > 0 && devicegroup[0] == "shared" {
-			xpath = fmt.Sprintf("/config/shared/service/entry[@name='%s']", name)
-		}
-
-		if p.Shared == false && len(devicegroup) > 0 && devicegroup[0] != "shared" {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service/entry[@name='%s']", devicegroup[0], name)
-		}
-
-		if p.Shared == false && len(devicegroup) <= 0 {
-			return errors.New("you must specify a device-group when creating service objects on a Panorama device")
-		}
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("%s/service/entry[@name=%s]", base, xpathQuote(name))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // CreateServiceGroup will create a new service group on the device. You can specify members to add
 // by using a []string variable (e.g. members := []string{"tcp-service1", "udp-service1"}). If creating a service group on a
-// Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) CreateServiceGroup(name string, members []string, devicegroup ...string) error {
+// Panorama device, specify its location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) CreateServiceGroup(name string, members []string, location ...Location) error {
+	return p.CreateServiceGroupContext(context.Background(), name, members, location...)
+}
+
+// CreateServiceGroupContext is the same as CreateServiceGroup, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateServiceGroupContext(ctx context.Context, name string, members []string, location ...Location) error {
 	var xmlBody string
-	var xpath string
-	var reqError requestError
 
 	if len(members) <= 0 {
 		return errors.New("you cannot create a service group without any members")
@@ -198,120 +169,66 @@ func (p *PaloAlto) CreateServiceGroup(name string, members []string, devicegroup
 
 	xmlBody = "<members>"
 	for _, member := range members {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(member))
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(member)))
 	}
 	xmlBody += "</members>"
 
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" {
-		if p.Shared == true {
-			xpath = fmt.Sprintf("/config/shared/service-group/entry[@name='%s']", name)
-		}
-
-		if len(devicegroup) > 0 && devicegroup[0] == "shared" {
-			xpath = fmt.Sprintf("/config/shared/service-group/entry[@name='%s']", name)
-		}
-
-		if p.Shared == false && len(devicegroup) > 0 && devicegroup[0] != "shared" {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']", devicegroup[0], name)
-		}
-
-		if p.Shared == false && len(devicegroup) <= 0 {
-			return errors.New("you must specify a device-group when creating service groups on a Panorama device")
-		}
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("%s/service-group/entry[@name=%s]", base, xpathQuote(name))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // DeleteService will remove a service object from the device. If deleting a service on a
-// Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) DeleteService(name string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service/entry[@name='%s']", name)
-	}
+// Panorama device, specify its location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) DeleteService(name string, location ...Location) error {
+	return p.DeleteServiceContext(context.Background(), name, location...)
+}
 
-	if p.DeviceType == "panorama" && p.Shared == true {
-		xpath = fmt.Sprintf("/config/shared/service/entry[@name='%s']", name)
-	}
+// DeleteServiceContext is the same as DeleteService, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteServiceContext(ctx context.Context, name string, location ...Location) error {
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when deleting service objects on a Panorama device")
-	}
+	xpath := fmt.Sprintf("%s/service/entry[@name=%s]", base, xpathQuote(name))
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
 // DeleteServiceGroup will remove a service group from the device. If deleting a service group on a
-// Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) DeleteServiceGroup(name string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && p.Shared == true {
-		xpath = fmt.Sprintf("/config/shared/service-group/entry[@name='%s']", name)
-	}
+// Panorama device, specify its location (e.g. DeviceGroupLocation("name")) as the last parameter.
+func (p *PaloAlto) DeleteServiceGroup(name string, location ...Location) error {
+	return p.DeleteServiceGroupContext(context.Background(), name, location...)
+}
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']", devicegroup[0], name)
-	}
+// DeleteServiceGroupContext is the same as DeleteServiceGroup, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteServiceGroupContext(ctx context.Context, name string, location ...Location) error {
 
-	if p.DeviceType == "panorama" && p.Shared == false && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when deleting service groups on a Panorama device")
+	base, err := p.locationXpath(location...)
+	if err != nil {
+		return err
 	}
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
+	xpath := fmt.Sprintf("%s/service-group/entry[@name=%s]", base, xpathQuote(name))
 
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
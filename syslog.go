@@ -0,0 +1,416 @@
+package panos
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syslogFields is a run of fields in the CSV format of a log type, which were added in the given PAN-OS release.
+// Blank names are fields marked FUTURE_USE.
+type syslogFields struct {
+	since  Version
+	fields []string
+}
+
+var (
+	// syslogHeader holds the fields that start every log in the CSV format.
+	syslogHeader = []string{"", "receive_time", "serial", "type", "subtype", "", "time_generated"}
+
+	// dgHierarchy holds the device-group hierarchy fields, which most log types have.
+	dgHierarchy = []string{"dg_hier_level_1", "dg_hier_level_2", "dg_hier_level_3", "dg_hier_level_4", "vsys_name", "device_name"}
+
+	// deviceID holds the device identification fields that traffic and threat logs gained in PAN-OS 10.0.
+	deviceID = []string{
+		"xff_ip", "src_category", "src_profile", "src_model", "src_vendor", "src_osfamily", "src_osversion", "src_host",
+		"src_mac", "dst_category", "dst_profile", "dst_model", "dst_vendor", "dst_osfamily", "dst_osversion", "dst_host",
+		"dst_mac", "container_id", "pod_namespace", "pod_name", "src_edl", "dst_edl", "hostid", "serialnumber",
+	}
+
+	// appCharacteristics holds the application fields that traffic and threat logs gained in PAN-OS 10.2.
+	appCharacteristics = []string{
+		"subcategory_of_app", "category_of_app", "technology_of_app", "risk_of_app", "characteristic_of_app",
+		"container_of_app", "tunneled_app", "is_saas_of_app", "sanctioned_state_of_app",
+	}
+
+	// syslogFormats holds the CSV format of each log type, after the header, as documented by Palo Alto Networks.
+	// Fields added in later releases are mostly added at the end, but not always, which is why the format depends on
+	// the release.
+	syslogFormats = map[string][]syslogFields{
+		"TRAFFIC": {
+			{fields: []string{
+				"src", "dst", "natsrc", "natdst", "rule", "srcuser", "dstuser", "app", "vsys", "from", "to", "inbound_if",
+				"outbound_if", "logset", "", "sessionid", "repeatcnt", "sport", "dport", "natsport", "natdport", "flags",
+				"proto", "action", "bytes", "bytes_sent", "bytes_received", "packets", "start", "elapsed", "category", "",
+				"seqno", "actionflags", "srcloc", "dstloc", "", "pkts_sent", "pkts_received", "session_end_reason",
+			}},
+			{fields: dgHierarchy},
+			{fields: []string{
+				"action_source", "src_uuid", "dst_uuid", "tunnelid_imsi", "monitortag_imei", "parent_session_id",
+				"parent_start_time", "tunnel", "assoc_id", "chunks", "chunks_sent", "chunks_received",
+			}},
+			{since: Version{Major: 9}, fields: []string{"rule_uuid", "http2_connection"}},
+			{since: Version{Major: 9, Minor: 1}, fields: []string{
+				"link_change_count", "policy_id", "link_switches", "sdwan_cluster", "sdwan_device_type",
+				"sdwan_cluster_type", "sdwan_site", "dynusergroup_name",
+			}},
+			{since: Version{Major: 10}, fields: deviceID},
+			{since: Version{Major: 10}, fields: []string{"src_dag", "dst_dag"}},
+			{since: Version{Major: 10, Minor: 1}, fields: []string{"session_owner", "high_res_timestamp", "nssai_sst", "nssai_sd"}},
+			{since: Version{Major: 10, Minor: 2}, fields: appCharacteristics},
+			{since: Version{Major: 10, Minor: 2}, fields: []string{"offloaded"}},
+		},
+		"THREAT": {
+			{fields: []string{
+				"src", "dst", "natsrc", "natdst", "rule", "srcuser", "dstuser", "app", "vsys", "from", "to", "inbound_if",
+				"outbound_if", "logset", "", "sessionid", "repeatcnt", "sport", "dport", "natsport", "natdport", "flags",
+				"proto", "action", "misc", "threatid", "category", "severity", "direction", "seqno", "actionflags",
+				"srcloc", "dstloc", "", "contenttype", "pcap_id", "filedigest", "cloud", "url_idx", "user_agent",
+				"filetype", "xff", "referer", "sender", "subject", "recipient", "reportid",
+			}},
+			{fields: dgHierarchy},
+			{fields: []string{
+				"", "src_uuid", "dst_uuid", "http_method", "tunnelid_imsi", "monitortag_imei", "parent_session_id",
+				"parent_start_time", "tunnel", "thr_category", "contentver", "", "assoc_id", "ppid", "http_headers",
+			}},
+			{since: Version{Major: 9}, fields: []string{"url_category_list", "rule_uuid", "http2_connection"}},
+			{since: Version{Major: 9, Minor: 1}, fields: []string{"dynusergroup_name"}},
+			{since: Version{Major: 10}, fields: deviceID},
+			{since: Version{Major: 10}, fields: []string{"domain_edl", "src_dag", "dst_dag", "partial_hash"}},
+			{since: Version{Major: 10, Minor: 1}, fields: []string{"high_res_timestamp", "reason", "justification", "nssai_sst"}},
+			{since: Version{Major: 10, Minor: 2}, fields: appCharacteristics},
+		},
+		"SYSTEM": {
+			{fields: []string{"vsys", "eventid", "object", "", "", "module", "severity", "opaque", "seqno", "actionflags"}},
+			{fields: dgHierarchy},
+		},
+		"CONFIG": {
+			{fields: []string{"host", "vsys", "cmd", "admin", "client", "result", "path"}},
+			{since: Version{Major: 8}, fields: []string{"before-change-preview", "after-change-preview"}},
+			{fields: []string{"seqno", "actionflags"}},
+			{fields: dgHierarchy},
+		},
+		"HIPMATCH": {
+			{fields: []string{
+				"srcuser", "vsys", "machinename", "os", "src", "matchname", "repeatcnt", "matchtype", "", "", "seqno",
+				"actionflags",
+			}},
+			{fields: dgHierarchy},
+			{fields: []string{"vsys_id", "srcipv6", "hostid", "serialnumber"}},
+			{since: Version{Major: 10}, fields: []string{"mac"}},
+			{since: Version{Major: 10, Minor: 1}, fields: []string{"high_res_timestamp"}},
+		},
+		"GLOBALPROTECT": {
+			{fields: []string{
+				"vsys", "eventid", "stage", "auth_method", "tunnel_type", "srcuser", "srcregion", "machinename",
+				"public_ip", "public_ipv6", "private_ip", "private_ipv6", "hostid", "serialnumber", "client_ver",
+				"client_os", "client_os_ver", "repeatcnt", "reason", "error", "opaque", "status", "location",
+				"login_duration", "connect_method", "error_code", "portal", "seqno", "actionflags",
+			}},
+			{since: Version{Major: 10}, fields: []string{
+				"high_res_timestamp", "selection_type", "response_time", "priority", "attempted_gateways", "gateway",
+			}},
+			{since: Version{Major: 10}, fields: dgHierarchy},
+			{since: Version{Major: 10}, fields: []string{"vsys_id"}},
+		},
+		"USERID": {
+			{fields: []string{
+				"vsys", "ip", "user", "datasourcename", "eventid", "repeatcnt", "timeout", "beginport", "endport",
+				"datasource", "datasourcetype", "seqno", "actionflags",
+			}},
+			{fields: dgHierarchy},
+			{fields: []string{"vsys_id", "factortype", "factorcompletiontime", "factorno", "ugflags", "userbysource"}},
+			{since: Version{Major: 10}, fields: []string{"tag_name"}},
+			{since: Version{Major: 10, Minor: 1}, fields: []string{"high_res_timestamp"}},
+		},
+	}
+
+	// syslogCSVStart finds the start of a log in the CSV format, after any syslog header: the FUTURE_USE field,
+	// followed by the receive time.
+	syslogCSVStart = regexp.MustCompile(`(?:^|\s)(\d*,\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2},)`)
+
+	// cefKey finds each key in the extension of a CEF log.
+	cefKey = regexp.MustCompile(`(?:^|\s)(\w+)=`)
+
+	// cefTimeFormats holds the formats that PAN-OS uses for times in CEF logs, other than milliseconds since the
+	// epoch.
+	cefTimeFormats = []string{"Jan 02 2006 15:04:05 MST", "Jan 02 2006 15:04:05", "Jan _2 2006 15:04:05 MST", "Jan _2 2006 15:04:05"}
+
+	// cefFields maps the standard CEF keys that the recommended PAN-OS CEF formats use to log fields.
+	cefFields = map[string]string{
+		"rt":                           "receive_time",
+		"deviceExternalId":             "serial",
+		"dvchost":                      "device_name",
+		"src":                          "src",
+		"dst":                          "dst",
+		"sourceTranslatedAddress":      "natsrc",
+		"destinationTranslatedAddress": "natdst",
+		"spt":                          "sport",
+		"dpt":                          "dport",
+		"sourceTranslatedPort":         "natsport",
+		"destinationTranslatedPort":    "natdport",
+		"suser":                        "srcuser",
+		"duser":                        "dstuser",
+		"app":                          "app",
+		"proto":                        "proto",
+		"act":                          "action",
+		"in":                           "bytes_sent",
+		"out":                          "bytes_received",
+		"cnt":                          "repeatcnt",
+		"deviceInboundInterface":       "inbound_if",
+		"deviceOutboundInterface":      "outbound_if",
+		"externalId":                   "seqno",
+		"reason":                       "session_end_reason",
+		"request":                      "misc",
+		"fname":                        "misc",
+		"fileHash":                     "filedigest",
+		"fileType":                     "filetype",
+		"requestMethod":                "http_method",
+		"requestClientApplication":     "user_agent",
+		"requestContext":               "contenttype",
+		"msg":                          "opaque",
+		"fileId":                       "pcap_id",
+		"duid":                         "hostid",
+		"PanOSPacketsReceived":         "pkts_received",
+		"PanOSPacketsSent":             "pkts_sent",
+		"PanOSDGl1":                    "dg_hier_level_1",
+		"PanOSDGl2":                    "dg_hier_level_2",
+		"PanOSDGl3":                    "dg_hier_level_3",
+		"PanOSDGl4":                    "dg_hier_level_4",
+		"PanOSVsysName":                "vsys_name",
+		"PanOSSourceLocation":          "srcloc",
+		"PanOSDestinationLocation":     "dstloc",
+		"PanOSRuleUUID":                "rule_uuid",
+		"PanOSThreatCategory":          "thr_category",
+		"PanOSContentVersion":          "contentver",
+		"PanOSXForwardedFor":           "xff",
+		"PanOSReferer":                 "referer",
+		"PanOSSender":                  "sender",
+		"PanOSRecipient":               "recipient",
+		"PanOSSubject":                 "subject",
+	}
+
+	// cefLabels maps the labels of the custom CEF keys (cs1, cn1, flexString1 and so on) that the recommended PAN-OS
+	// CEF formats use to log fields.
+	cefLabels = map[string]string{
+		"Rule":                    "rule",
+		"Virtual System":          "vsys",
+		"Source Zone":             "from",
+		"Destination Zone":        "to",
+		"LogProfile":              "logset",
+		"SessionID":               "sessionid",
+		"Flags":                   "flags",
+		"Total bytes":             "bytes",
+		"Packets":                 "packets",
+		"Elapsed time in seconds": "elapsed",
+		"URL Category":            "category",
+		"Threat ID":               "threatid",
+		"Threat Content Name":     "threatid",
+		"Direction":               "direction",
+		"Event ID":                "eventid",
+		"Object":                  "object",
+		"Module":                  "module",
+		"Command":                 "cmd",
+		"Admin":                   "admin",
+		"Client":                  "client",
+		"Result":                  "result",
+		"Configuration Path":      "path",
+		"Before Change Detail":    "before-change-preview",
+		"After Change Detail":     "after-change-preview",
+		"Host":                    "host",
+	}
+)
+
+// syslogFormat returns the names of the fields of the given log type in the CSV format of the given release, or nil
+// if the log type is not known.
+func syslogFormat(logtype string, version Version) []string {
+	format, ok := syslogFormats[logtype]
+	if !ok {
+		return nil
+	}
+
+	fields := append([]string{}, syslogHeader...)
+	for _, f := range format {
+		if version.Compare(f.since) >= 0 {
+			fields = append(fields, f.fields...)
+		}
+	}
+
+	return fields
+}
+
+// ParseSyslog parses a log that a device sent over syslog, in either the default CSV format or the CEF format, into
+// the same LogRecord that RetrieveLogs returns for it. Any syslog header in front of the log is skipped.
+//
+// The fields in the CSV format change from one PAN-OS release to the next, so you can (optionally) specify the
+// release of the device that sent the log, e.g. ParseSyslog(line, pan.Version). Otherwise, the newest format that is
+// known is used, which reads logs from older releases correctly as long as no fields were added to the middle of
+// them since. Fields are found by name in the CEF format, so the release doesn't matter for it.
+//
+// An error is returned if the line is not a log, or if the log was cut short, such as a CSV log with fewer fields
+// than its type has or a CEF log without its whole header.
+func ParseSyslog(line string, version ...Version) (LogRecord, error) {
+	if i := strings.Index(line, "CEF:"); i >= 0 {
+		fields, err := cefLogFields(line[i:])
+		if err != nil {
+			return nil, err
+		}
+
+		return fields.record(), nil
+	}
+
+	match := syslogCSVStart.FindStringSubmatchIndex(line)
+	if match == nil {
+		return nil, errors.New("the log is not in the PAN-OS CSV or CEF format")
+	}
+
+	r := csv.NewReader(strings.NewReader(line[match[2]:]))
+	r.LazyQuotes = true
+	r.FieldsPerRecord = -1
+
+	values, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the log - %w", err)
+	}
+
+	v := Version{Major: 1 << 16}
+	if len(version) > 0 && !version[0].IsZero() {
+		v = version[0]
+	}
+
+	// Each log starts with the same header, which holds the log type. Syslog over UDP can cut long logs short, which
+	// leaves them without the fields that every log of their type has, even in the oldest release.
+	if len(values) < len(syslogHeader) {
+		return nil, fmt.Errorf("the log has %d fields, but every log starts with %d", len(values), len(syslogHeader))
+	}
+
+	logtype := strings.ToUpper(values[3])
+	names := syslogFormat(logtype, v)
+	if names == nil {
+		names = syslogHeader
+	} else if min := len(syslogFormat(logtype, Version{})); len(values) < min {
+		return nil, fmt.Errorf("the log has %d fields, but %s logs have at least %d", len(values), logtype, min)
+	}
+
+	fields := logFields{}
+	for i, value := range values {
+		name := fmt.Sprintf("field%d", i+1)
+		if i < len(names) {
+			name = names[i]
+		}
+
+		if name != "" {
+			fields[name] = value
+		}
+	}
+
+	return fields.record(), nil
+}
+
+// cefLogFields returns the fields of a log in the CEF format, starting with "CEF:".
+func cefLogFields(log string) (logFields, error) {
+	header, err := splitCEFHeader(log)
+	if err != nil {
+		return nil, err
+	}
+
+	// The recommended PAN-OS formats put the subtype in the signature ID, and the log type in the name.
+	if header[5] == "" {
+		return nil, errors.New("the CEF log does not have a log type in its header")
+	}
+
+	fields := logFields{"subtype": header[4], "type": header[5]}
+
+	extension := header[7]
+	keys := cefKey.FindAllStringSubmatchIndex(extension, -1)
+
+	raw := map[string]string{}
+	for i, k := range keys {
+		end := len(extension)
+		if i+1 < len(keys) {
+			end = keys[i+1][0]
+		}
+
+		raw[extension[k[2]:k[3]]] = unescapeCEF(strings.TrimSpace(extension[k[1]:end]))
+	}
+
+	for key, value := range raw {
+		if strings.HasSuffix(key, "Label") {
+			continue
+		}
+
+		name, ok := cefFields[key]
+		if label, labeled := raw[key+"Label"]; labeled {
+			name, ok = cefLabels[label]
+		}
+
+		if !ok {
+			name = key
+		}
+
+		if key == "rt" || key == "start" || key == "end" {
+			value = cefTime(value)
+		}
+
+		fields[name] = value
+	}
+
+	// PAN-OS puts the time the log was generated in start, for traffic logs, and leaves it out of the others.
+	if t, ok := raw["start"]; ok {
+		fields["time_generated"] = cefTime(t)
+	}
+
+	return fields, nil
+}
+
+// splitCEFHeader splits a CEF log into the seven fields of its header, followed by the extension.
+func splitCEFHeader(log string) ([]string, error) {
+	var parts []string
+	var part strings.Builder
+
+	for i := 0; i < len(log); i++ {
+		switch c := log[i]; {
+		case c == '\\' && i+1 < len(log) && len(parts) < 7:
+			i++
+			part.WriteByte(log[i])
+		case c == '|' && len(parts) < 7:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(c)
+		}
+	}
+
+	parts = append(parts, part.String())
+
+	if len(parts) != 8 {
+		return nil, errors.New("the CEF log does not have a complete header")
+	}
+
+	return parts, nil
+}
+
+// unescapeCEF returns value without the escaping that CEF uses in the values of the extension.
+func unescapeCEF(value string) string {
+	return strings.NewReplacer(`\=`, "=", `\\`, `\`, `\n`, "\n", `\r`, "\r").Replace(value)
+}
+
+// cefTime returns a time from a CEF log, in the format that logs use elsewhere. Times that can't be parsed are
+// returned unchanged.
+func cefTime(value string) string {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)).UTC().Format(logTimeFormat)
+	}
+
+	for _, layout := range cefTimeFormats {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format(logTimeFormat)
+		}
+	}
+
+	return value
+}
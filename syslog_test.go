@@ -0,0 +1,119 @@
+package panos
+
+import (
+	"strings"
+	"testing"
+)
+
+// syslogLine returns a log of the given type in the CSV format of version, with the given fields set, behind a syslog
+// header.
+func syslogLine(logtype string, version Version, fields map[string]string) string {
+	names := syslogFormat(logtype, version)
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = fields[name]
+	}
+
+	values[3] = logtype
+
+	return "<14>Jan  1 00:00:00 fw1 " + strings.Join(values, ",")
+}
+
+func TestParseSyslog(t *testing.T) {
+	traffic := map[string]string{
+		"receive_time": "2026/01/01 00:00:01",
+		"serial":       "000000000001",
+		"subtype":      "end",
+		"src":          "10.1.1.10",
+		"dst":          "192.0.2.1",
+		"dport":        "443",
+		"app":          "ssl",
+		"rule":         "allow-web",
+		"bytes":        "1024",
+	}
+
+	newest := Version{Major: 1 << 16}
+	line := syslogLine("TRAFFIC", newest, traffic)
+
+	tests := []struct {
+		name    string
+		line    string
+		version []Version
+	}{
+		{name: "newest format", line: line},
+		{name: "oldest format", line: syslogLine("TRAFFIC", Version{}, traffic), version: []Version{{Major: 8}}},
+		{name: "without a syslog header", line: line[strings.Index(line, " fw1 ")+5:]},
+		{
+			name: "CEF",
+			line: "<14>Jan  1 00:00:00 fw1 CEF:0|Palo Alto Networks|PAN-OS|10.1.0|end|TRAFFIC|1|rt=Jan 01 2026 00:00:01 GMT " +
+				"deviceExternalId=000000000001 src=10.1.1.10 dst=192.0.2.1 dpt=443 app=ssl cs1Label=Rule cs1=allow-web " +
+				"cn1Label=Total bytes cn1=1024",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, err := ParseSyslog(tt.line, tt.version...)
+			if err != nil {
+				t.Fatalf("ParseSyslog: %v", err)
+			}
+
+			l, ok := record.(*TrafficLog)
+			if !ok {
+				t.Fatalf("ParseSyslog returned a %T, want a *TrafficLog", record)
+			}
+
+			if l.Serial != "000000000001" || l.Subtype != "end" || l.Source.String() != "10.1.1.10" ||
+				l.DestinationPort != 443 || l.Application != "ssl" || l.Rule != "allow-web" || l.Bytes != 1024 {
+				t.Errorf("ParseSyslog returned %+v", l)
+			}
+
+			if got := l.ReceiveTime.Format(logTimeFormat); got != "2026/01/01 00:00:01" {
+				t.Errorf("ReceiveTime = %s", got)
+			}
+		})
+	}
+}
+
+func TestParseSyslogOther(t *testing.T) {
+	record, err := ParseSyslog("1,2026/01/01 00:00:01,000000000001,DECRYPTION,,,2026/01/01 00:00:00,10.1.1.10")
+	if err != nil {
+		t.Fatalf("ParseSyslog: %v", err)
+	}
+
+	l, ok := record.(*OtherLog)
+	if !ok {
+		t.Fatalf("ParseSyslog returned a %T, want an *OtherLog", record)
+	}
+
+	if l.Fields["field8"] != "10.1.1.10" {
+		t.Errorf("field8 = %q", l.Fields["field8"])
+	}
+}
+
+func TestParseSyslogMalformed(t *testing.T) {
+	traffic := syslogLine("TRAFFIC", Version{}, map[string]string{"receive_time": "2026/01/01 00:00:01"})
+
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "empty", line: ""},
+		{name: "not a log", line: "<14>Jan  1 00:00:00 fw1 sshd[42]: session opened"},
+		{name: "receive time only", line: "1,2024/01/01 00:00:00,"},
+		{name: "cut short in the header", line: "1,2024/01/01 00:00:00,000000000001,TRAFFIC"},
+		{name: "header only", line: "1,2024/01/01 00:00:00,000000000001,TRAFFIC,end,,2024/01/01 00:00:00"},
+		{name: "cut short traffic", line: traffic[:len(traffic)/2]},
+		{name: "CEF version only", line: "CEF:0"},
+		{name: "CEF cut short in the header", line: "CEF:0|Palo Alto Networks|PAN-OS|10.1.0|end"},
+		{name: "CEF without a log type", line: "CEF:0|Palo Alto Networks|PAN-OS|10.1.0|end||1|src=10.1.1.10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if record, err := ParseSyslog(tt.line); err == nil {
+				t.Errorf("ParseSyslog(%q) = %+v, want an error", tt.line, record)
+			}
+		})
+	}
+}
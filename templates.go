@@ -1,6 +1,7 @@
 package panos
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -41,6 +42,11 @@ type TemplateStack struct {
 // Templates returns information about all of the templates in Panorama, and what devices they are
 // applied to.
 func (p *PaloAlto) Templates() (*Templates, error) {
+	return p.TemplatesContext(context.Background())
+}
+
+// TemplatesContext is the same as Templates, but uses ctx for all of its API requests.
+func (p *PaloAlto) TemplatesContext(ctx context.Context) (*Templates, error) {
 	var temps Templates
 	xpath := "/config/devices/entry//template"
 
@@ -48,58 +54,58 @@ func (p *PaloAlto) Templates() (*Templates, error) {
 		return nil, errors.New("templates can only be listed on a Panorama device")
 	}
 
-	_, tData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	tData, err := p.getConfig(ctx, xpath)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(tData), &temps); err != nil {
 		return nil, err
 	}
 
-	if temps.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", temps.Code, errorCodes[temps.Code])
-	}
-
 	return &temps, nil
 }
 
 // TemplateStacks returns information about all of the template stacks in Panorama, and what templates, devices
 // are assigned to them. This is ONLY available on Panorama version 7.0.0 and higher.
 func (p *PaloAlto) TemplateStacks() (*TemplateStacks, error) {
+	return p.TemplateStacksContext(context.Background())
+}
+
+// TemplateStacksContext is the same as TemplateStacks, but uses ctx for all of its API requests.
+func (p *PaloAlto) TemplateStacksContext(ctx context.Context) (*TemplateStacks, error) {
 	var temps TemplateStacks
-	ver := splitSWVersion(p.SoftwareVersion)
 	xpath := "/config/devices/entry//template-stack"
 
 	if p.DeviceType != "panorama" {
 		return nil, errors.New("template stacks can only be listed on a Panorama device")
 	}
 
-	if ver[0] < 7 {
+	if p.olderThan(7, 0) {
 		return nil, errors.New("you must be running version 7.0.0 or higher to use template stacks")
 	}
 
-	_, tData, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=get&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return nil, errs[0]
+	tData, err := p.getConfig(ctx, xpath)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := xml.Unmarshal([]byte(tData), &temps); err != nil {
 		return nil, err
 	}
 
-	if temps.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", temps.Code, errorCodes[temps.Code])
-	}
-
 	return &temps, nil
 }
 
 // CreateTemplate adds a new template to Panorama. If you wish to associate devices, then
 // separate their serial numbers with a comma (e.g. "0101010101, 0202020202").
 func (p *PaloAlto) CreateTemplate(name, description string, devices ...string) error {
-	var reqError requestError
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']", name)
+	return p.CreateTemplateContext(context.Background(), name, description, devices...)
+}
+
+// CreateTemplateContext is the same as CreateTemplate, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateTemplateContext(ctx context.Context, name, description string, devices ...string) error {
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name=%s]", xpathQuote(name))
 	xmlBody := "<settings><default-vsys>vsys1</default-vsys></settings><config><devices><entry name=\"localhost.localdomain\"><vsys><entry name=\"vsys1\"/></vsys></entry></devices></config>"
 
 	if p.DeviceType != "panorama" {
@@ -107,30 +113,21 @@ func (p *PaloAlto) CreateTemplate(name, description string, devices ...string) e
 	}
 
 	if len(description) > 0 {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
+		xmlBody += fmt.Sprintf("<description>%s</description>", xmlEscape(description))
 	}
 
 	if len(devices) > 0 {
 		xmlBody += "<devices>"
 		for _, d := range strings.Split(devices[0], ",") {
-			xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", strings.TrimSpace(d))
+			xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", xmlEscape(strings.TrimSpace(d)))
 		}
 		xmlBody += "</devices>"
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -139,12 +136,15 @@ func (p *PaloAlto) CreateTemplate(name, description string, devices ...string) e
 // If you wish to associate devices, then separate their serial numbers with a comma, just like you would template names.
 // This is ONLY available on Panorama version 7.0.0 and higher.
 func (p *PaloAlto) CreateTemplateStack(name, description, templates string, devices ...string) error {
-	var reqError requestError
-	ver := splitSWVersion(p.SoftwareVersion)
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name='%s']", name)
+	return p.CreateTemplateStackContext(context.Background(), name, description, templates, devices...)
+}
+
+// CreateTemplateStackContext is the same as CreateTemplateStack, but uses ctx for all of its API requests.
+func (p *PaloAlto) CreateTemplateStackContext(ctx context.Context, name, description, templates string, devices ...string) error {
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name=%s]", xpathQuote(name))
 	xmlBody := "<templates>"
 	for _, t := range strings.Split(templates, ",") {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(t))
+		xmlBody += fmt.Sprintf("<member>%s</member>", xmlEscape(strings.TrimSpace(t)))
 	}
 	xmlBody += "</templates>"
 
@@ -152,35 +152,26 @@ func (p *PaloAlto) CreateTemplateStack(name, description, templates string, devi
 		return errors.New("template stacks can only be created on a Panorama device")
 	}
 
-	if ver[0] < 7 {
+	if p.olderThan(7, 0) {
 		return errors.New("you must be running version 7.0.0 or higher to use template stacks")
 	}
 
 	if len(description) > 0 {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
+		xmlBody += fmt.Sprintf("<description>%s</description>", xmlEscape(description))
 	}
 
 	if len(devices) > 0 {
 		xmlBody += "<devices>"
 		for _, d := range strings.Split(devices[0], ",") {
-			xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", strings.TrimSpace(d))
+			xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", xmlEscape(strings.TrimSpace(d)))
 		}
 		xmlBody += "</devices>"
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -190,40 +181,34 @@ func (p *PaloAlto) CreateTemplateStack(name, description, templates string, devi
 // will only assign devices to a single template. Template stacks are ONLY
 // available on Panorama version 7.0.0 and higher.
 func (p *PaloAlto) AssignTemplate(name, devices string, stack bool) error {
-	var reqError requestError
-	ver := splitSWVersion(p.SoftwareVersion)
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']", name)
+	return p.AssignTemplateContext(context.Background(), name, devices, stack)
+}
+
+// AssignTemplateContext is the same as AssignTemplate, but uses ctx for all of its API requests.
+func (p *PaloAlto) AssignTemplateContext(ctx context.Context, name, devices string, stack bool) error {
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name=%s]", xpathQuote(name))
 	xmlBody := "<devices>"
 	for _, d := range strings.Split(devices, ",") {
-		xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", strings.TrimSpace(d))
+		xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", xmlEscape(strings.TrimSpace(d)))
 	}
 	xmlBody += "</devices>"
 
 	if stack {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name='%s']", name)
+		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name=%s]", xpathQuote(name))
 	}
 
 	if p.DeviceType != "panorama" {
 		return errors.New("templates can only be assigned on a Panorama device")
 	}
 
-	if ver[0] < 7 && stack {
+	if p.olderThan(7, 0) && stack {
 		return errors.New("you must be running version 7.0.0 or higher to use template stacks")
 	}
 
-	_, resp, errs := r.Post(p.URI).Query(fmt.Sprintf("type=config&action=set&xpath=%s&element=%s&key=%s", xpath, xmlBody, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.setConfig(ctx, xpath, xmlBody); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
 
@@ -232,34 +217,28 @@ func (p *PaloAlto) AssignTemplate(name, devices string, stack bool) error {
 // will only delete single templates. Template stacks are ONLY
 // available on Panorama version 7.0.0 and higher.
 func (p *PaloAlto) DeleteTemplate(name string, stack bool) error {
-	var reqError requestError
-	ver := splitSWVersion(p.SoftwareVersion)
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']", name)
+	return p.DeleteTemplateContext(context.Background(), name, stack)
+}
+
+// DeleteTemplateContext is the same as DeleteTemplate, but uses ctx for all of its API requests.
+func (p *PaloAlto) DeleteTemplateContext(ctx context.Context, name string, stack bool) error {
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name=%s]", xpathQuote(name))
 
 	if stack {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name='%s']", name)
+		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name=%s]", xpathQuote(name))
 	}
 
 	if p.DeviceType != "panorama" {
 		return errors.New("templates can only be deleted on a Panorama device")
 	}
 
-	if ver[0] < 7 && stack {
+	if p.olderThan(7, 0) && stack {
 		return errors.New("you must be running version 7.0.0 or higher to use template stacks")
 	}
 
-	_, resp, errs := r.Get(p.URI).Query(fmt.Sprintf("type=config&action=delete&xpath=%s&key=%s", xpath, p.Key)).End()
-	if errs != nil {
-		return errs[0]
-	}
-
-	if err := xml.Unmarshal([]byte(resp), &reqError); err != nil {
+	if err := p.deleteConfig(ctx, xpath); err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
 	return nil
 }
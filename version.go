@@ -0,0 +1,146 @@
+package panos
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// versionPattern matches a PAN-OS software version, such as 10.1.6, 10.1.6-h3 or 11.0.0-b1.
+	versionPattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)(?:-(?:h(\d+)|(\w+)))?$`)
+
+	// contentPattern matches a content version, such as 8500-7000.
+	contentPattern = regexp.MustCompile(`^(\d+)-(\d+)$`)
+)
+
+// Version is a PAN-OS software version, such as 10.1.6-h3. The zero value means the version is not known. Versions
+// can be compared with Compare or AtLeast, e.g. pan.Version.AtLeast(10, 1, 0).
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+
+	// Hotfix is the number of the hotfix release (the 3 in 10.1.6-h3), or 0 if it isn't one.
+	Hotfix int
+
+	// Suffix holds anything else after the patch number, such as "b1" for a beta release.
+	Suffix string
+}
+
+// ParseVersion parses a PAN-OS software version, such as "10.1.6" or "10.1.6-h3".
+func ParseVersion(version string) (Version, error) {
+	match := versionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return Version{}, fmt.Errorf("%q is not a PAN-OS version", version)
+	}
+
+	var v Version
+	v.Major, _ = strconv.Atoi(match[1])
+	v.Minor, _ = strconv.Atoi(match[2])
+	v.Patch, _ = strconv.Atoi(match[3])
+	v.Hotfix, _ = strconv.Atoi(match[4])
+	v.Suffix = match[5]
+
+	return v, nil
+}
+
+// String returns the version the way PAN-OS writes it, or a blank string if the version is not known.
+func (v Version) String() string {
+	if v.IsZero() {
+		return ""
+	}
+
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+
+	switch {
+	case v.Hotfix > 0:
+		s += fmt.Sprintf("-h%d", v.Hotfix)
+	case v.Suffix != "":
+		s += "-" + v.Suffix
+	}
+
+	return s
+}
+
+// IsZero reports whether the version is not known.
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// Compare returns -1 if v is older than other, 1 if it is newer, and 0 if they are the same release. Hotfixes are
+// newer than the release they are based on. The Suffix is not compared.
+func (v Version) Compare(other Version) int {
+	for _, pair := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}, {v.Hotfix, other.Hotfix}} {
+		switch {
+		case pair[0] < pair[1]:
+			return -1
+		case pair[0] > pair[1]:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// AtLeast reports whether v is the given release, or a newer one.
+func (v Version) AtLeast(major, minor, patch int) bool {
+	return v.Compare(Version{Major: major, Minor: minor, Patch: patch}) >= 0
+}
+
+// ContentVersion is the version of a content update, such as the applications and threats package 8500-7000. The
+// zero value means the version is not known.
+type ContentVersion struct {
+	// Release is the first number, which goes up with each content release.
+	Release int
+
+	// Build is the second number.
+	Build int
+}
+
+// ParseContentVersion parses the version of a content update, such as "8500-7000".
+func ParseContentVersion(version string) (ContentVersion, error) {
+	match := contentPattern.FindStringSubmatch(version)
+	if match == nil {
+		return ContentVersion{}, fmt.Errorf("%q is not a content version", version)
+	}
+
+	var v ContentVersion
+	v.Release, _ = strconv.Atoi(match[1])
+	v.Build, _ = strconv.Atoi(match[2])
+
+	return v, nil
+}
+
+// String returns the version the way PAN-OS writes it, or a blank string if the version is not known.
+func (v ContentVersion) String() string {
+	if v == (ContentVersion{}) {
+		return ""
+	}
+
+	return fmt.Sprintf("%d-%d", v.Release, v.Build)
+}
+
+// Compare returns -1 if v is older than other, 1 if it is newer, and 0 if they are the same.
+func (v ContentVersion) Compare(other ContentVersion) int {
+	switch {
+	case v.Release != other.Release:
+		if v.Release < other.Release {
+			return -1
+		}
+
+		return 1
+	case v.Build < other.Build:
+		return -1
+	case v.Build > other.Build:
+		return 1
+	}
+
+	return 0
+}
+
+// olderThan reports whether the device is known to run a PAN-OS release older than major.minor. If the version is
+// not known, such as for a lazy session that hasn't been refreshed, it returns false, so the newer behavior is used.
+func (p *PaloAlto) olderThan(major, minor int) bool {
+	return !p.Version.IsZero() && !p.Version.AtLeast(major, minor, 0)
+}
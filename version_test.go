@@ -0,0 +1,155 @@
+package panos
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    Version
+		wantErr bool
+	}{
+		{version: "10.1.6", want: Version{Major: 10, Minor: 1, Patch: 6}},
+		{version: "10.1.6-h3", want: Version{Major: 10, Minor: 1, Patch: 6, Hotfix: 3}},
+		{version: "9.0.0-b12", want: Version{Major: 9, Suffix: "b12"}},
+		{version: "8.1.0-c100", want: Version{Major: 8, Minor: 1, Suffix: "c100"}},
+		{version: "11.0.2-h12", want: Version{Major: 11, Patch: 2, Hotfix: 12}},
+		{version: "", wantErr: true},
+		{version: "10.1", wantErr: true},
+		{version: "10.1.6-", wantErr: true},
+		{version: "v10.1.6", wantErr: true},
+		{version: "10.1.6 ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.version)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVersion(%q): got error %v, want error %v", tt.version, err, tt.wantErr)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("ParseVersion(%q) = %+v, want %+v", tt.version, got, tt.want)
+		}
+
+		if !tt.wantErr && got.String() != tt.version {
+			t.Errorf("ParseVersion(%q).String() = %q", tt.version, got.String())
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "10.1.6", b: "10.1.6", want: 0},
+		{a: "10.1.6", b: "10.1.7", want: -1},
+		{a: "10.2.0", b: "10.1.9", want: 1},
+		{a: "9.1.0", b: "10.0.0", want: -1},
+		{a: "10.1.6-h3", b: "10.1.6", want: 1},
+		{a: "10.1.6-h3", b: "10.1.6-h12", want: -1},
+		{a: "10.1.6-h3", b: "10.1.7", want: -1},
+		{a: "9.0.0-b12", b: "9.0.0", want: 0},
+		{a: "9.0.0-b12", b: "9.0.0-h1", want: -1},
+		{a: "9.0.0-b12", b: "8.1.20", want: 1},
+	}
+
+	for _, tt := range tests {
+		a, err := ParseVersion(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ParseVersion(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+
+		if got := b.Compare(a); got != -tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version             string
+		major, minor, patch int
+		want                bool
+	}{
+		{version: "10.1.6", major: 10, minor: 1, patch: 6, want: true},
+		{version: "10.1.6-h3", major: 10, minor: 1, patch: 6, want: true},
+		{version: "10.1.6-h3", major: 10, minor: 1, patch: 7},
+		{version: "10.1.6", major: 9, want: true},
+		{version: "9.0.0-b12", major: 9, want: true},
+		{version: "8.1.25-h1", major: 9},
+	}
+
+	for _, tt := range tests {
+		v, err := ParseVersion(tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := v.AtLeast(tt.major, tt.minor, tt.patch); got != tt.want {
+			t.Errorf("%s.AtLeast(%d, %d, %d) = %v, want %v", tt.version, tt.major, tt.minor, tt.patch, got, tt.want)
+		}
+	}
+
+	if (Version{}).String() != "" || !(Version{}).IsZero() {
+		t.Error("the zero version is not blank")
+	}
+}
+
+func TestParseContentVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    ContentVersion
+		wantErr bool
+	}{
+		{version: "8500-7000", want: ContentVersion{Release: 8500, Build: 7000}},
+		{version: "4222-4735", want: ContentVersion{Release: 4222, Build: 4735}},
+		{version: "0", wantErr: true},
+		{version: "", wantErr: true},
+		{version: "8500", wantErr: true},
+		{version: "8500-7000-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseContentVersion(tt.version)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseContentVersion(%q): got error %v, want error %v", tt.version, err, tt.wantErr)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("ParseContentVersion(%q) = %+v, want %+v", tt.version, got, tt.want)
+		}
+
+		if !tt.wantErr && got.String() != tt.version {
+			t.Errorf("ParseContentVersion(%q).String() = %q", tt.version, got.String())
+		}
+	}
+}
+
+func TestContentVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b ContentVersion
+		want int
+	}{
+		{a: ContentVersion{8500, 7000}, b: ContentVersion{8500, 7000}, want: 0},
+		{a: ContentVersion{8500, 7000}, b: ContentVersion{8501, 6000}, want: -1},
+		{a: ContentVersion{8500, 7001}, b: ContentVersion{8500, 7000}, want: 1},
+		{a: ContentVersion{}, b: ContentVersion{1, 1}, want: -1},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package panos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+)
+
+// defaultVsys is the virtual system that is used when none has been chosen with WithVsys.
+const defaultVsys = "vsys1"
+
+// VirtualSystems contains information about all of the virtual systems on a firewall.
+type VirtualSystems struct {
+	XMLName xml.Name        `xml:"response"`
+	Status  string          `xml:"status,attr"`
+	Code    string          `xml:"code,attr"`
+	Vsys    []VirtualSystem `xml:"result>vsys>entry"`
+}
+
+// VirtualSystem contains information about each individual virtual system.
+type VirtualSystem struct {
+	Name        string `xml:"name,attr"`
+	DisplayName string `xml:"display-name"`
+}
+
+// WithVsys returns a copy of the session that works against the given virtual system (e.g. "vsys3"), instead
+// of vsys1. Every object, policy, zone and tag function called on the returned session uses that vsys. The copy
+// shares the original session's API key and HTTP client, and the original session is left unchanged.
+func (p *PaloAlto) WithVsys(name string) *PaloAlto {
+	s := *p
+	s.vsys = name
+
+	return &s
+}
+
+// vsysXpath returns the xpath of the virtual system that the session works against.
+func (p *PaloAlto) vsysXpath() string {
+	name := p.vsys
+	if name == "" {
+		name = defaultVsys
+	}
+
+	return fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name=%s]", xpathQuote(name))
+}
+
+// Vsys returns all of the virtual systems configured on a firewall. If the firewall is not in multi-vsys mode,
+// then only vsys1 is returned.
+func (p *PaloAlto) Vsys() (*VirtualSystems, error) {
+	return p.VsysContext(context.Background())
+}
+
+// VsysContext is the same as Vsys, but uses ctx for all of its API requests.
+func (p *PaloAlto) VsysContext(ctx context.Context) (*VirtualSystems, error) {
+	var vsys VirtualSystems
+
+	if p.DeviceType != "panos" {
+		return nil, errors.New("you can only list virtual systems on a firewall")
+	}
+
+	// A lazy session doesn't know whether the firewall is in multi-vsys mode until it asks.
+	info, err := p.discovered(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.MultiVsys != "on" {
+		vsys.Vsys = []VirtualSystem{{Name: defaultVsys}}
+
+		return &vsys, nil
+	}
+
+	resp, err := p.getConfig(ctx, "/config/devices/entry[@name='localhost.localdomain']/vsys")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := xml.Unmarshal([]byte(resp), &vsys); err != nil {
+		return nil, err
+	}
+
+	return &vsys, nil
+}
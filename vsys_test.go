@@ -0,0 +1,151 @@
+package panos
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// multiVsys is the vsys section of the configuration of a fake firewall with three virtual systems.
+const multiVsys = `<vsys>` +
+	`<entry name="vsys1"><display-name>Corporate</display-name></entry>` +
+	`<entry name="vsys2"><display-name>Guest</display-name></entry>` +
+	`<entry name="vsys3"><display-name>Lab</display-name></entry>` +
+	`</vsys>`
+
+func TestWithVsys(t *testing.T) {
+	srv := newFakeDevice(nil)
+	defer srv.Close()
+
+	srv.info["multi-vsys"] = "on"
+
+	pan := srv.session(t)
+	lab := pan.WithVsys("vsys3")
+
+	if err := lab.CreateAddress("web-1", "ip", "10.1.1.1/32", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lab.CreateZone("lab", "layer3", false); err != nil {
+		t.Fatal(err)
+	}
+
+	rule := &RuleContent{
+		From:        []string{"lab"},
+		To:          []string{"any"},
+		Source:      []string{"web-1"},
+		Destination: []string{"any"},
+		SourceUser:  []string{"any"},
+		Application: []string{"any"},
+		Service:     []string{"application-default"},
+		HIPProfiles: []string{"any"},
+		Category:    []string{"any"},
+		Action:      "allow",
+	}
+
+	if err := lab.CreateRule("allow-lab", "", rule); err != nil {
+		t.Fatal(err)
+	}
+
+	vsys3 := "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys3']"
+	vsys1 := "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']"
+
+	requests := srv.Requests()
+	if len(requests) < 3 {
+		t.Fatalf("got %d requests, want at least 3", len(requests))
+	}
+
+	for _, want := range []string{"/address/entry[@name='web-1']", "/zone/entry[@name='lab']", "/rulebase/security/rules/entry[@name='allow-lab']"} {
+		found := false
+		for _, r := range requests {
+			found = found || strings.HasPrefix(r.Get("xpath"), vsys3+want)
+		}
+
+		if !found {
+			t.Errorf("no request was made for %s", vsys3+want)
+		}
+	}
+
+	for i, r := range requests {
+		if xpath := r.Get("xpath"); strings.Contains(xpath, "/vsys/") && !strings.HasPrefix(xpath, vsys3) {
+			t.Errorf("request %d of the vsys3 session was for %s", i+1, xpath)
+		}
+	}
+
+	if got := lab.vsysXpath(); got != vsys3 {
+		t.Errorf("got vsys xpath %s, want %s", got, vsys3)
+	}
+
+	if got := pan.vsysXpath(); got != vsys1 {
+		t.Errorf("the parent session's vsys xpath changed to %s", got)
+	}
+
+	if _, err := pan.Addresses(); err != nil {
+		t.Fatal(err)
+	}
+
+	requests = srv.Requests()
+	if got := requests[len(requests)-1].Get("xpath"); got != vsys1+"/address" {
+		t.Errorf("the parent session got its addresses from %s, want %s/address", got, vsys1)
+	}
+}
+
+func TestVsys(t *testing.T) {
+	tests := []struct {
+		name      string
+		multiVsys string
+		want      []VirtualSystem
+	}{
+		{
+			name:      "single vsys",
+			multiVsys: "off",
+			want:      []VirtualSystem{{Name: "vsys1"}},
+		},
+		{
+			name:      "multi-vsys",
+			multiVsys: "on",
+			want: []VirtualSystem{
+				{Name: "vsys1", DisplayName: "Corporate"},
+				{Name: "vsys2", DisplayName: "Guest"},
+				{Name: "vsys3", DisplayName: "Lab"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeDevice(func(w http.ResponseWriter, r *http.Request) {
+				if r.Form.Get("xpath") != "/config/devices/entry[@name='localhost.localdomain']/vsys" {
+					t.Errorf("got a request for %s", r.Form.Get("xpath"))
+				}
+
+				fmt.Fprintf(w, `<response status="success"><result>%s</result></response>`, multiVsys)
+			})
+			defer srv.Close()
+
+			srv.info["multi-vsys"] = tt.multiVsys
+
+			vsys, err := srv.session(t).Vsys()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(vsys.Vsys, tt.want) {
+				t.Fatalf("got %+v, want %+v", vsys.Vsys, tt.want)
+			}
+		})
+	}
+}
+
+func TestVsysPanorama(t *testing.T) {
+	srv := newFakeDevice(nil)
+	defer srv.Close()
+
+	srv.info["model"] = "Panorama"
+
+	if _, err := srv.session(t).Vsys(); err == nil {
+		t.Fatal("listed the virtual systems of a Panorama device")
+	}
+}